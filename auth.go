@@ -0,0 +1,56 @@
+package impala
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/sclgo/impala-go/internal/isql"
+	"github.com/sclgo/impala-go/internal/sasl"
+)
+
+// Values returned by AuthMechanism
+const (
+	// AuthMechanismPlain is the SASL PLAIN mechanism, used for LDAP authentication with the binary transport
+	AuthMechanismPlain = sasl.MechPlain
+	// AuthMechanismNone means that the connection didn't negotiate SASL.
+	// This includes the http transport, which sends LDAP credentials as a basic authorization header.
+	AuthMechanismNone = "NONE"
+)
+
+// AuthMechanism returns the SASL mechanism negotiated when the connection was opened e.g. for audit logging.
+// It returns AuthMechanismNone for connections without SASL. *sql.Conn implements ConnRawAccess.
+func AuthMechanism(conn ConnRawAccess) (string, error) {
+	var res string
+	err := conn.Raw(func(driverConn any) error {
+		impalaConn, ok := driverConn.(*isql.Conn)
+		if !ok {
+			return errors.New("auth mechanism can be reported only for Impala drivers")
+		}
+		res = impalaConn.AuthMechanism()
+		return nil
+	})
+	return res, err
+}
+
+// interpretFirstEOF returns the isql.Options.InterpretFirstEOF hook for opts
+func interpretFirstEOF(opts *Options) func(transportError error) error {
+	if opts.UseLDAP || opts.Transport == TransportHTTP {
+		// with SASL, the negotiation already interprets EOF.
+		// the http transport reports authentication failures with status codes.
+		return nil
+	}
+	return func(transportError error) error {
+		// Impala servers, which require SASL, close the connection when they get a Thrift message
+		// instead of the start of the negotiation.
+		return fmt.Errorf("%w: %w", ErrSASLRequired, transportError)
+	}
+}
+
+// authMechanism returns the SASL mechanism negotiated on transport
+func authMechanism(transport thrift.TTransport) string {
+	if saslTransport, ok := transport.(*sasl.TSaslTransport); ok && saslTransport.Mechanism() != "" {
+		return saslTransport.Mechanism()
+	}
+	return AuthMechanismNone
+}
@@ -0,0 +1,70 @@
+package impala
+
+import (
+	"context"
+	"database/sql/driver"
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/murfffi/gorich/fi"
+	"github.com/sclgo/impala-go/internal/sasl"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthMechanism(t *testing.T) {
+	t.Run("no sasl", func(t *testing.T) {
+		require.Equal(t, AuthMechanismNone, authMechanism(thrift.NewTMemoryBuffer()))
+	})
+
+	t.Run("plain", func(t *testing.T) {
+		buf := thrift.NewTMemoryBuffer()
+		// the server response: negotiation complete with an empty payload
+		_, err := buf.Write([]byte{byte(sasl.StatusComplete), 0, 0, 0, 0})
+		require.NoError(t, err)
+		transport, err := sasl.NewTSaslTransport(buf, &sasl.Options{Username: "fry", Password: "pass"})
+		require.NoError(t, err)
+		require.Equal(t, AuthMechanismNone, authMechanism(transport), "not negotiated yet")
+		require.NoError(t, transport.Open())
+		require.Equal(t, AuthMechanismPlain, authMechanism(transport))
+
+		client := NewHiveClient(transport, &Options{})
+		mech, err := AuthMechanism(client)
+		require.NoError(t, err)
+		require.Equal(t, AuthMechanismPlain, mech)
+	})
+}
+
+func TestConnect_SASLRequired(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	fi.CleanupF(t, listener.Close)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			// like a server that expects SASL negotiation, close the connection on an unexpected first frame
+			_, _ = conn.Read(make([]byte, 1))
+			_ = conn.Close()
+		}
+	}()
+	port := strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)
+
+	t.Run("nosasl", func(t *testing.T) {
+		conn, err := connect(context.Background(), &Options{Host: "127.0.0.1", Port: port})
+		require.NoError(t, err)
+		_, err = conn.OpenSession(context.Background())
+		require.ErrorIs(t, err, ErrSASLRequired)
+		require.ErrorIs(t, err, driver.ErrBadConn)
+		require.ErrorContains(t, err, "auth=ldap")
+	})
+
+	t.Run("ldap", func(t *testing.T) {
+		_, err := connect(context.Background(), &Options{Host: "127.0.0.1", Port: port, UseLDAP: true, Username: "fry"})
+		require.Error(t, err)
+		require.NotErrorIs(t, err, ErrSASLRequired)
+	})
+}
@@ -0,0 +1,14 @@
+package impala
+
+import (
+	"context"
+
+	"github.com/sclgo/impala-go/internal/hive"
+)
+
+// WithBatchSize returns a context that overrides Options.BatchSize and Options.BatchBytes for the queries executed
+// with it, e.g. to fetch fewer rows at a time for a query with huge rows, without changing the connection.
+// n is the max number of rows in each fetch from the server. If n is 0 or negative, the context is returned as is.
+func WithBatchSize(ctx context.Context, n int) context.Context {
+	return hive.WithBatchSize(ctx, n)
+}
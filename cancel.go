@@ -0,0 +1,37 @@
+package impala
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sclgo/impala-go/internal/isql"
+)
+
+// Canceler cancels the query running on a connection from another goroutine, e.g. in response to
+// a "Stop" button, without closing the connection.
+type Canceler struct {
+	conn *isql.Conn
+}
+
+// NewCanceler returns a Canceler for the given connection, usually *sql.Conn.
+// Call NewCanceler before starting the query to cancel, since sql.Conn.Raw blocks while
+// the connection is in use. The Canceler can be used as long as the connection is open.
+func NewCanceler(conn ConnRawAccess) (*Canceler, error) {
+	var res *Canceler
+	err := conn.Raw(func(driverConn any) error {
+		impalaConn, ok := driverConn.(*isql.Conn)
+		if !ok {
+			return errors.New("canceler can operate only on Impala drivers")
+		}
+		res = &Canceler{conn: impalaConn}
+		return nil
+	})
+	return res, err
+}
+
+// Cancel cancels the query currently running on the connection, if any.
+// The cancelled query returns an error to its caller. Cancel is safe for concurrent use.
+// Cancel opens a short-lived separate connection to the server to send the cancel request.
+func (c *Canceler) Cancel(ctx context.Context) error {
+	return c.conn.Cancel(ctx)
+}
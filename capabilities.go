@@ -0,0 +1,33 @@
+package impala
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sclgo/impala-go/internal/hive"
+	"github.com/sclgo/impala-go/internal/isql"
+)
+
+// Capabilities describes optional features of the server, detected when the first session of a connection is opened
+type Capabilities = hive.Capabilities
+
+// ServerCapabilities returns the capabilities of the server, to which conn is connected. If the server doesn't
+// implement the Impala extension of HiveServer2, e.g. because it is Hive, the driver falls back to the base
+// protocol, and the number of rows modified by DML statements is not available.
+// *sql.Conn implements ConnRawAccess.
+func ServerCapabilities(ctx context.Context, conn ConnRawAccess) (Capabilities, error) {
+	var res Capabilities
+	err := conn.Raw(func(driverConn any) error {
+		impalaConn, ok := driverConn.(*isql.Conn)
+		if !ok {
+			return errors.New("server capabilities can be reported only for Impala drivers")
+		}
+		session, err := impalaConn.OpenSession(ctx) // also validates transport; err has driver.ErrBadConn in chain
+		if err != nil {
+			return err
+		}
+		res, err = session.Capabilities(ctx)
+		return err
+	})
+	return res, err
+}
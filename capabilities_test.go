@@ -0,0 +1,25 @@
+package impala
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sclgo/impala-go/impalatest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerCapabilities(t *testing.T) {
+	srv := impalatest.Start(t)
+	db := srv.OpenDB(t)
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, conn.Close())
+	}()
+
+	caps, err := ServerCapabilities(ctx, conn)
+	require.NoError(t, err)
+	require.True(t, caps.ImpalaExtension)
+	require.Contains(t, srv.Calls(), "PingImpalaHS2Service")
+}
@@ -0,0 +1,28 @@
+package impala
+
+import (
+	"errors"
+
+	"github.com/sclgo/impala-go/internal/isql"
+)
+
+// LastResultChecksum returns the checksum of all values, which the rows of the last query on conn returned.
+// The rows must be closed first - database/sql closes them when Next returns false. The checksum is a
+// 64-bit FNV-1a hash of the values in order, including their Go types, so it depends on the options that
+// change how values are returned, e.g. decimal-as. Two runs of a query with the same results and options have the
+// same checksum. It fails if Options.ResultChecksum is not enabled or the last statement had no result set.
+// *sql.Conn implements ConnRawAccess.
+func LastResultChecksum(conn ConnRawAccess) (uint64, error) {
+	var res uint64
+	err := conn.Raw(func(driverConn any) error {
+		impalaConn, ok := driverConn.(*isql.Conn)
+		if !ok {
+			return errors.New("result checksum can be retrieved only for Impala drivers")
+		}
+		if res, ok = impalaConn.LastResultChecksum(); !ok {
+			return errors.New("no result checksum: enable the result-checksum parameter and close the rows of a query first")
+		}
+		return nil
+	})
+	return res, err
+}
@@ -0,0 +1,46 @@
+package impala
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/sclgo/impala-go/impalatest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLastResultChecksum(t *testing.T) {
+	srv := impalatest.Start(t)
+	columns := []impalatest.Column{{Name: "id", Type: "BIGINT"}, {Name: "name", Type: "STRING"}}
+	require.NoError(t, srv.AddResult("SELECT run1", impalatest.Result{Columns: columns, Rows: [][]any{{1, "a"}, {2, nil}}}))
+	require.NoError(t, srv.AddResult("SELECT run2", impalatest.Result{Columns: columns, Rows: [][]any{{1, "a"}, {2, nil}}}))
+	require.NoError(t, srv.AddResult("SELECT changed", impalatest.Result{Columns: columns, Rows: [][]any{{1, "a"}, {2, ""}}}))
+
+	checksum := func(t *testing.T, db *sql.DB, query string) (uint64, error) {
+		ctx := context.Background()
+		conn, err := db.Conn(ctx)
+		require.NoError(t, err)
+		defer func() {
+			require.NoError(t, conn.Close())
+		}()
+		rows, err := conn.QueryContext(ctx, query)
+		require.NoError(t, err)
+		for rows.Next() {
+		}
+		require.NoError(t, rows.Err())
+		return LastResultChecksum(conn)
+	}
+
+	db := srv.OpenDB(t, "result-checksum=true")
+	sum1, err := checksum(t, db, "SELECT run1")
+	require.NoError(t, err)
+	sum2, err := checksum(t, db, "SELECT run2")
+	require.NoError(t, err)
+	require.Equal(t, sum1, sum2)
+	changed, err := checksum(t, db, "SELECT changed")
+	require.NoError(t, err)
+	require.NotEqual(t, sum1, changed, "NULL and empty string differ")
+
+	_, err = checksum(t, srv.OpenDB(t), "SELECT run1")
+	require.ErrorContains(t, err, "enable the result-checksum parameter")
+}
@@ -0,0 +1,95 @@
+package impala
+
+import (
+	"reflect"
+
+	"github.com/sclgo/impala-go/internal/hive"
+)
+
+// Supported values of Options.DecimalAs
+const (
+	// DecimalAsString returns DECIMAL values as strings, which preserves their exact value. This is the default.
+	DecimalAsString = hive.DecimalAsString
+	// DecimalAsFloat64 returns DECIMAL values as float64, which may lose precision
+	DecimalAsFloat64 = hive.DecimalAsFloat64
+	// DecimalAsRat returns DECIMAL values as *big.Rat
+	DecimalAsRat = hive.DecimalAsRat
+)
+
+// Supported values of Options.DateAs
+const (
+	// DateAsString returns DATE values as strings, as sent by Impala e.g. 2024-01-02. This is the default.
+	DateAsString = hive.DateAsString
+	// DateAsTime returns DATE values as time.Time at midnight UTC
+	DateAsTime = hive.DateAsTime
+)
+
+// Supported values of Options.TypeNames
+const (
+	// TypeNamesThrift reports base type names e.g. DECIMAL or VARCHAR, derived from the server API. This is the default.
+	TypeNamesThrift = hive.TypeNamesThrift
+	// TypeNamesSQL reports type names as written in Impala DDL e.g. DECIMAL(10,2) or VARCHAR(20)
+	TypeNamesSQL = hive.TypeNamesSQL
+)
+
+// Supported values of Options.ComplexJSON
+const (
+	// ComplexJSONCompact returns ARRAY, MAP, and STRUCT values as single-line JSON without insignificant whitespace
+	ComplexJSONCompact = hive.ComplexJSONCompact
+	// ComplexJSONIndent returns ARRAY, MAP, and STRUCT values as JSON indented with two spaces
+	ComplexJSONIndent = hive.ComplexJSONIndent
+)
+
+// ValueConverter customizes the values of result columns, matched by name or type, see Options.ValueConverters.
+// Convert runs after the driver decodes a non-NULL value to the default Go type of the column, e.g. int64 for
+// BIGINT, and its result is returned to database/sql instead. For example, to scan a BIGINT column with
+// durations in milliseconds as time.Duration:
+//
+//	impala.ValueConverter{
+//		Column:   "elapsed_ms",
+//		Convert:  func(v any) (any, error) { return time.Duration(v.(int64)) * time.Millisecond, nil },
+//		ScanType: reflect.TypeFor[time.Duration](),
+//	}
+//
+// ConvertRaw runs before the built-in decoding, which depends on options like Options.DecimalAs. It receives
+// the non-NULL value as sent by the server: a string for DECIMAL, TIMESTAMP and the string types, an int8, int16,
+// int32 or int64 for the integer types, a float64 for FLOAT and DOUBLE, a bool for BOOLEAN, and a []byte for
+// BINARY. If it returns handled true, its result is final. Otherwise, the value is decoded as usual and passed
+// to Convert, if set. For example, to parse DECIMAL values with an arbitrary-precision decimal library:
+//
+//	impala.ValueConverter{
+//		DatabaseTypeName: "DECIMAL",
+//		ConvertRaw: func(raw any) (any, bool, error) {
+//			d, _, err := apd.NewFromString(raw.(string))
+//			return d, true, err
+//		},
+//	}
+//
+// Convert and ConvertRaw may return any type. database/sql assigns it as is to Scan destinations of the same type.
+// Errors are returned by Rows.Next.
+type ValueConverter = hive.ValueConverter
+
+// ColumnDesc describes a column in a query result, including the Go type used for scanning its values.
+// It mirrors sql.ColumnType, which can't be created outside database/sql.
+type ColumnDesc = hive.ColDesc
+
+// NewColumnDesc creates a ColumnDesc for a column with the given Impala type, using the same type mapping
+// as the driver uses for query results. typeName can be either an Impala type name like DECIMAL or
+// a type id from the HiveServer2 Thrift API like DECIMAL_TYPE. Type qualifiers - length, precision, and scale -
+// are not set by this function. Returns an error if typeName is not recognized.
+//
+// NewColumnDesc is intended for tools that call the HiveServer2 API directly and want to stay consistent
+// with this driver.
+func NewColumnDesc(name string, typeName string) (*ColumnDesc, error) {
+	return hive.NewColDesc(name, typeName)
+}
+
+// ScanType returns the Go type used for scanning values of the given Impala type.
+// See NewColumnDesc about typeName. Returns nil if the type is not recognized.
+func ScanType(typeName string) reflect.Type {
+	cd, err := hive.NewColDesc("", typeName)
+	if err != nil {
+		return nil
+	}
+	return cd.ScanType
+}
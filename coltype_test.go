@@ -0,0 +1,44 @@
+package impala_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sclgo/impala-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewColumnDesc(t *testing.T) {
+	tests := []struct {
+		typeName string
+		dbType   string
+		scanType reflect.Type
+	}{
+		{"INT", "INT", reflect.TypeFor[int32]()},
+		{"BIGINT_TYPE", "BIGINT", reflect.TypeFor[int64]()},
+		{"decimal", "DECIMAL", reflect.TypeFor[string]()},
+		{"DATE", "DATE", reflect.TypeFor[string]()},
+		{"TIMESTAMP", "TIMESTAMP", reflect.TypeFor[time.Time]()},
+		{"BINARY", "BINARY", reflect.TypeFor[[]byte]()},
+		{"ARRAY", "ARRAY", reflect.TypeFor[string]()},
+		{"NULL_TYPE", "NULL", reflect.TypeFor[any]()},
+		{"VARCHAR", "VARCHAR", reflect.TypeFor[string]()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.typeName, func(t *testing.T) {
+			cd, err := impala.NewColumnDesc("col", tt.typeName)
+			require.NoError(t, err)
+			require.Equal(t, "col", cd.Name)
+			require.Equal(t, tt.dbType, cd.DatabaseTypeName)
+			require.Equal(t, tt.scanType, cd.ScanType)
+			require.Equal(t, tt.scanType, impala.ScanType(tt.typeName))
+		})
+	}
+
+	t.Run("unknown", func(t *testing.T) {
+		_, err := impala.NewColumnDesc("col", "FOO")
+		require.ErrorContains(t, err, "FOO")
+		require.Nil(t, impala.ScanType("FOO"))
+	})
+}
@@ -0,0 +1,47 @@
+package impala
+
+import (
+	"errors"
+	"sync/atomic"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/sclgo/impala-go/internal/isql"
+)
+
+// countingTransport counts the bytes read from the wrapped transport
+type countingTransport struct {
+	thrift.TTransport
+	bytesRead atomic.Int64
+}
+
+func (t *countingTransport) Read(p []byte) (int, error) {
+	n, err := t.TTransport.Read(p)
+	t.bytesRead.Add(int64(n))
+	return n, err
+}
+
+func (t *countingTransport) SetTConfiguration(conf *thrift.TConfiguration) {
+	thrift.PropagateTConfiguration(t.TTransport, conf)
+}
+
+var _ interface {
+	thrift.TTransport
+	thrift.TConfigurationSetter
+} = &countingTransport{}
+
+// LastStatementBytesRead returns the number of bytes, which the last statement executed on conn read from
+// the server, including result rows and protocol overhead. If the statement is still in progress, e.g. its rows
+// are not closed yet, it returns the bytes read so far. This helps account for network egress per query.
+// *sql.Conn implements ConnRawAccess.
+func LastStatementBytesRead(conn ConnRawAccess) (int64, error) {
+	var res int64
+	err := conn.Raw(func(driverConn any) error {
+		impalaConn, ok := driverConn.(*isql.Conn)
+		if !ok {
+			return errors.New("bytes read can be retrieved only for Impala drivers")
+		}
+		res = impalaConn.LastBytesRead()
+		return nil
+	})
+	return res, err
+}
@@ -0,0 +1,50 @@
+package impala
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/sclgo/impala-go/impalatest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLastStatementBytesRead(t *testing.T) {
+	srv := impalatest.Start(t)
+	rows := func(n int) [][]any {
+		var res [][]any
+		for i := range n {
+			res = append(res, []any{fmt.Sprintf("row %d with some padding", i)})
+		}
+		return res
+	}
+	columns := []impalatest.Column{{Name: "s", Type: "STRING"}}
+	require.NoError(t, srv.AddResult("SELECT small", impalatest.Result{Columns: columns, Rows: rows(1)}))
+	require.NoError(t, srv.AddResult("SELECT large", impalatest.Result{Columns: columns, Rows: rows(1000)}))
+
+	db := srv.OpenDB(t)
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, conn.Close())
+	}()
+
+	query := func(stmt string) int64 {
+		rows, err := conn.QueryContext(ctx, stmt)
+		require.NoError(t, err)
+		for rows.Next() {
+		}
+		require.NoError(t, rows.Err())
+		require.NoError(t, rows.Close())
+		n, err := LastStatementBytesRead(conn)
+		require.NoError(t, err)
+		return n
+	}
+
+	small := query("SELECT small")
+	require.Positive(t, small)
+	large := query("SELECT large")
+	require.Greater(t, large, small+20_000, "1000 rows of ~25 bytes each")
+	require.Equal(t, small, query("SELECT small"), "the count is per statement")
+}
@@ -0,0 +1,53 @@
+package impala
+
+import (
+	"context"
+)
+
+type credentials struct {
+	username string
+	password string
+}
+
+type credentialsKey struct{}
+
+// WithCredentials returns a context that carries end-user credentials for LDAP authentication.
+// When database/sql opens a new connection for a statement executed with this context, e.g. with QueryContext,
+// the connection authenticates with these credentials instead of the ones in Options or the DSN.
+//
+// database/sql reuses pooled connections across statements. A connection runs statements only for contexts
+// with the same credentials as the context that opened it - contexts without credentials included.
+// Otherwise, the connection reports driver.ErrBadConn, so database/sql closes it and retries the statement
+// on a new connection. Within sql.Conn, which holds a single connection, such statements fail with driver.ErrBadConn.
+func WithCredentials(ctx context.Context, username, password string) context.Context {
+	return context.WithValue(ctx, credentialsKey{}, credentials{username: username, password: password})
+}
+
+func credentialsFromContext(ctx context.Context) (credentials, bool) {
+	creds, ok := ctx.Value(credentialsKey{}).(credentials)
+	return creds, ok
+}
+
+// withContextCredentials returns a copy of opts with the credentials from ctx, if any
+func withContextCredentials(ctx context.Context, opts *Options) *Options {
+	creds, ok := credentialsFromContext(ctx)
+	if !ok {
+		return opts
+	}
+	res := *opts
+	res.Username = creds.username
+	res.Password = creds.password
+	// PasswordFile and PasswordEnv must not apply if the context password is empty
+	res.PasswordFile = ""
+	res.PasswordEnv = ""
+	return &res
+}
+
+// acceptsCredentials returns a function that accepts only contexts with the same credentials as connectCtx
+func acceptsCredentials(connectCtx context.Context) func(ctx context.Context) bool {
+	connectCreds, connectOk := credentialsFromContext(connectCtx)
+	return func(ctx context.Context) bool {
+		creds, ok := credentialsFromContext(ctx)
+		return ok == connectOk && creds == connectCreds
+	}
+}
@@ -0,0 +1,61 @@
+package impala
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithContextCredentials(t *testing.T) {
+	opts := &Options{Username: "dsn", Password: "dsn-pass", PasswordEnv: "IMPALA_PASSWORD"}
+	require.Same(t, opts, withContextCredentials(context.Background(), opts))
+
+	ctx := WithCredentials(context.Background(), "fry", "")
+	actual := withContextCredentials(ctx, opts)
+	require.Equal(t, &Options{Username: "fry"}, actual)
+	require.Equal(t, "dsn", opts.Username, "the original options must not change")
+}
+
+func TestAcceptsCredentials(t *testing.T) {
+	background := context.Background()
+	fry := WithCredentials(background, "fry", "pass")
+	require.True(t, acceptsCredentials(fry)(WithCredentials(background, "fry", "pass")))
+	require.False(t, acceptsCredentials(fry)(WithCredentials(background, "fry", "other")))
+	require.False(t, acceptsCredentials(fry)(background))
+	require.False(t, acceptsCredentials(background)(fry))
+	require.True(t, acceptsCredentials(background)(background))
+}
+
+func TestConnect_ContextCredentials(t *testing.T) {
+	requests := make(chan *http.Request, 1)
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case requests <- r:
+		default:
+		}
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer proxy.Close()
+
+	opts := &Options{
+		Host:      "impala.internal",
+		Transport: TransportHTTP,
+		HTTPProxy: proxy.URL,
+		UseLDAP:   true,
+		Username:  "service",
+		Password:  "service-pass",
+	}
+	ctx := WithCredentials(context.Background(), "fry", "fry-pass")
+	conn, err := connect(ctx, opts)
+	require.NoError(t, err)
+	_, err = conn.OpenSession(ctx)
+	require.ErrorContains(t, err, "502")
+
+	user, pass, ok := (<-requests).BasicAuth()
+	require.True(t, ok)
+	require.Equal(t, "fry", user)
+	require.Equal(t, "fry-pass", pass)
+}
@@ -0,0 +1,122 @@
+package impala
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/sclgo/impala-go/internal/hive"
+	"github.com/sclgo/impala-go/internal/isql"
+)
+
+// CSVOptions configures WriteCSV
+type CSVOptions struct {
+	// Comma is the field delimiter. 0 means ','. Use '\t' for TSV.
+	Comma rune
+	// Null is written for NULL values. The default is an empty field, same as an empty string.
+	Null string
+	// Header enables writing the column names as the first record
+	Header bool
+}
+
+// WriteCSV runs query and writes its result to w as CSV, with encoding/csv, one row at a time, so
+// the result is never held in memory. Fields that contain the delimiter, quotes, or line breaks are quoted.
+// TIMESTAMP and DATE values are written in the format Impala uses, e.g. "2024-01-02 03:04:05.123" and
+// "2024-01-02", and DECIMAL values with the scale of their column. If ctx is cancelled, writing stops and the query is closed.
+// *sql.Conn implements ConnRawAccess.
+func WriteCSV(ctx context.Context, conn ConnRawAccess, query string, w io.Writer, opts CSVOptions) error {
+	return conn.Raw(func(driverConn any) error {
+		impalaConn, ok := driverConn.(*isql.Conn)
+		if !ok {
+			return errors.New("CSV can be written only with Impala drivers")
+		}
+		rows, err := impalaConn.QueryWithOptions(ctx, query, nil)
+		if err != nil {
+			return err
+		}
+		err = writeCSV(ctx, rows, w, opts)
+		closeErr := rows.Close()
+		return errors.Join(err, closeErr)
+	})
+}
+
+func writeCSV(ctx context.Context, rows driver.Rows, w io.Writer, opts CSVOptions) error {
+	cw := csv.NewWriter(w)
+	if opts.Comma != 0 {
+		cw.Comma = opts.Comma
+	}
+	columns := rows.Columns()
+	if opts.Header {
+		if err := cw.Write(columns); err != nil {
+			return err
+		}
+	}
+	// DECIMAL values are formatted with the scale of their column
+	scales := make([]int, len(columns))
+	if pr, ok := rows.(driver.RowsColumnTypePrecisionScale); ok {
+		for i := range columns {
+			_, scale, _ := pr.ColumnTypePrecisionScale(i)
+			scales[i] = int(scale)
+		}
+	}
+	// time.Time values are DATE values with date-as=time, or TIMESTAMP values
+	layouts := make([]string, len(columns))
+	for i := range columns {
+		layouts[i] = hive.TimestampFormat
+	}
+	if tn, ok := rows.(driver.RowsColumnTypeDatabaseTypeName); ok {
+		for i := range columns {
+			if tn.ColumnTypeDatabaseTypeName(i) == "DATE" {
+				layouts[i] = hive.DateFormat
+			}
+		}
+	}
+	dest := make([]driver.Value, len(columns))
+	record := make([]string, len(columns))
+	var err error
+	for err = rows.Next(dest); err == nil; err = rows.Next(dest) {
+		// rows.Next checks ctx only when it fetches the next batch
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		for i, v := range dest {
+			record[i] = csvField(v, scales[i], layouts[i], opts.Null)
+		}
+		if err = cw.Write(record); err != nil {
+			return err
+		}
+	}
+	if !errors.Is(err, io.EOF) {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvField formats v. scale applies only to DECIMAL values and layout only to time.Time values.
+func csvField(v driver.Value, scale int, layout string, null string) string {
+	switch v := v.(type) {
+	case nil:
+		return null
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(layout)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case *big.Rat:
+		return v.FloatString(scale)
+	default:
+		return fmt.Sprint(v)
+	}
+}
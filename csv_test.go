@@ -0,0 +1,93 @@
+package impala
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/sclgo/impala-go/impalatest"
+	"github.com/sclgo/impala-go/internal/hive"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteCSV(t *testing.T) {
+	srv := impalatest.Start(t)
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 123000000, time.UTC)
+	require.NoError(t, srv.AddResult("SELECT * FROM t", impalatest.Result{
+		Columns: []impalatest.Column{
+			{Name: "id", Type: "BIGINT"},
+			{Name: "name", Type: "STRING"},
+			{Name: "score", Type: "DOUBLE"},
+			{Name: "ok", Type: "BOOLEAN"},
+			{Name: "ts", Type: "TIMESTAMP"},
+			{Name: "day", Type: "DATE"},
+		},
+		Rows: [][]any{
+			{1, "plain", 1.5, true, ts, ts},
+			{2, "a,b \"quoted\"", nil, false, nil, nil},
+		},
+	}))
+
+	db := srv.OpenDB(t, "batch-size=1")
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, conn.Close())
+	}()
+
+	t.Run("csv", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, WriteCSV(ctx, conn, "SELECT * FROM t", &buf, CSVOptions{Header: true, Null: `\N`}))
+		require.Equal(t, "id,name,score,ok,ts,day\n"+
+			"1,plain,1.5,true,2024-01-02 03:04:05.123,2024-01-02\n"+
+			"2,\"a,b \"\"quoted\"\"\",\\N,false,\\N,\\N\n", buf.String())
+	})
+
+	t.Run("tsv", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, WriteCSV(ctx, conn, "SELECT * FROM t", &buf, CSVOptions{Comma: '\t'}))
+		require.Equal(t, "1\tplain\t1.5\ttrue\t2024-01-02 03:04:05.123\t2024-01-02\n"+
+			"2\t\"a,b \"\"quoted\"\"\"\t\tfalse\t\t\n", buf.String())
+	})
+
+	t.Run("date as time", func(t *testing.T) {
+		timeConn, err := srv.OpenDB(t, "date-as=time").Conn(ctx)
+		require.NoError(t, err)
+		defer func() {
+			require.NoError(t, timeConn.Close())
+		}()
+		var buf bytes.Buffer
+		require.NoError(t, WriteCSV(ctx, timeConn, "SELECT * FROM t", &buf, CSVOptions{}))
+		require.Equal(t, "1,plain,1.5,true,2024-01-02 03:04:05.123,2024-01-02\n"+
+			"2,\"a,b \"\"quoted\"\"\",,false,,\n", buf.String())
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		cancelledCtx, cancel := context.WithCancel(ctx)
+		cancel()
+		var buf bytes.Buffer
+		err := WriteCSV(cancelledCtx, conn, "SELECT * FROM t", &buf, CSVOptions{})
+		require.ErrorIs(t, err, context.Canceled)
+		require.Empty(t, buf.String())
+	})
+
+	t.Run("query error", func(t *testing.T) {
+		require.NoError(t, srv.AddResult("SELECT * FROM missing", impalatest.Result{Err: "AnalysisException: Could not resolve table reference: 'missing'"}))
+		var buf bytes.Buffer
+		require.ErrorIs(t, WriteCSV(ctx, conn, "SELECT * FROM missing", &buf, CSVOptions{}), ErrObjectNotFound)
+	})
+}
+
+func TestCSVField(t *testing.T) {
+	require.Equal(t, "1.50", csvField(big.NewRat(3, 2), 2, "", ""))
+	require.Equal(t, "2", csvField(big.NewRat(2, 1), 0, "", ""))
+	require.Equal(t, "0.1", csvField(float32(0.1), 0, "", ""))
+	require.Equal(t, "NULL", csvField(nil, 0, "", "NULL"))
+	require.Equal(t, "abc", csvField([]byte("abc"), 0, "", ""))
+	day := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	require.Equal(t, "2024-01-02", csvField(day, 0, hive.DateFormat, ""))
+	require.Equal(t, "2024-01-02 00:00:00", csvField(day, 0, hive.TimestampFormat, ""))
+}
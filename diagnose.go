@@ -0,0 +1,128 @@
+package impala
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Stages of opening a connection, reported by Diagnose
+const (
+	// DiagnoseStageDSN parses the DSN
+	DiagnoseStageDSN = "dsn"
+	// DiagnoseStageDial opens the network connection to the server
+	DiagnoseStageDial = "dial"
+	// DiagnoseStageTLS does the TLS handshake, if TLS is enabled
+	DiagnoseStageTLS = "tls"
+	// DiagnoseStageAuth authenticates, with SASL for the binary transport or with HTTP basic auth
+	DiagnoseStageAuth = "auth"
+	// DiagnoseStageSession opens an Impala session
+	DiagnoseStageSession = "session"
+)
+
+// DiagnoseResult reports which stage of opening a connection failed, see Diagnose
+type DiagnoseResult struct {
+	// Stage is the stage that failed e.g. DiagnoseStageTLS. It is empty if all stages succeeded.
+	Stage string
+	// Err is the error of the failed stage, in the same error tree as AuthError,
+	// *tls.CertificateVerificationError, ErrCertPinMismatch, ErrSASLRequired, or *net.OpError, when applicable
+	Err error
+}
+
+// OK reports if all stages succeeded
+func (r DiagnoseResult) OK() bool {
+	return r.Err == nil
+}
+
+// String describes the result e.g. for a log message
+func (r DiagnoseResult) String() string {
+	if r.OK() {
+		return "ok"
+	}
+	return fmt.Sprintf("%s failed: %v", r.Stage, r.Err)
+}
+
+// Diagnose opens a connection and a session with the given DSN, one stage at a time - see DiagnoseStageDial and
+// the other stages - and reports the first stage that failed, e.g. for readiness probes that need to tell network
+// issues from expired credentials. The dial and TLS stages connect to the server directly, even if
+// an HTTP proxy is configured. The connection is closed before Diagnose returns.
+func Diagnose(ctx context.Context, dsn string) DiagnoseResult {
+	opts, err := parseURI(dsn)
+	if err != nil {
+		return DiagnoseResult{Stage: DiagnoseStageDSN, Err: fmt.Errorf("%w: %w", ErrBadDSN, err)}
+	}
+	if res := diagnoseNetwork(ctx, opts); !res.OK() {
+		return res
+	}
+
+	// connect repeats the stages above, which are not expected to fail again
+	conn, err := connect(ctx, opts)
+	if err != nil {
+		stage := DiagnoseStageDial
+		if opts.UseLDAP {
+			stage = DiagnoseStageAuth
+		}
+		return DiagnoseResult{Stage: diagnoseStage(err, stage), Err: err}
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	// the http transport connects and authenticates lazily, and Impala servers, which require SASL,
+	// reject the first request when the driver doesn't use SASL, so auth failures may surface here
+	if _, err = conn.OpenSession(ctx); err != nil {
+		return DiagnoseResult{Stage: diagnoseStage(err, DiagnoseStageSession), Err: err}
+	}
+	return DiagnoseResult{}
+}
+
+// diagnoseNetwork runs the dial and TLS stages on a separate connection, which is closed afterward
+func diagnoseNetwork(ctx context.Context, opts *Options) DiagnoseResult {
+	network, addr := dialAddr(opts)
+	dialer := &net.Dialer{Timeout: opts.ConnectTimeout}
+	conn, err := dialer.DialContext(ctx, network, addr)
+	if err != nil {
+		return DiagnoseResult{Stage: DiagnoseStageDial, Err: wrapConnectErr(ctx, err, "")}
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	if !opts.UseTLS {
+		return DiagnoseResult{}
+	}
+
+	tlsConfig, err := getTLSConfig(opts)
+	if err != nil {
+		return DiagnoseResult{Stage: DiagnoseStageDSN, Err: err}
+	}
+	if tlsConfig.ServerName == "" {
+		// tls.Dialer does the same
+		tlsConfig.ServerName = opts.Host
+	}
+	handshakeCtx, cancel := withConnectTimeout(ctx, opts)
+	defer cancel()
+	if err = tls.Client(conn, tlsConfig).HandshakeContext(handshakeCtx); err != nil {
+		return DiagnoseResult{Stage: DiagnoseStageTLS, Err: wrapConnectErr(ctx, err, "")}
+	}
+	return DiagnoseResult{}
+}
+
+// diagnoseStage returns the stage, which err indicates, or stage if err doesn't indicate a specific one
+func diagnoseStage(err error, stage string) string {
+	var authErr *AuthError
+	switch {
+	case errors.Is(err, ErrBadDSN):
+		return DiagnoseStageDSN
+	case errors.As(err, &authErr), errors.Is(err, ErrSASLRequired), isHTTPAuthFailure(err):
+		return DiagnoseStageAuth
+	default:
+		return stage
+	}
+}
+
+// isHTTPAuthFailure reports if err is the error of the Thrift HTTP client for a 401 Unauthorized response
+func isHTTPAuthFailure(err error) bool {
+	return strings.Contains(err.Error(), "HTTP Response code: 401")
+}
@@ -0,0 +1,63 @@
+package impala
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"testing"
+
+	"github.com/sclgo/impala-go/impalatest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiagnose(t *testing.T) {
+	srv := impalatest.Start(t)
+	host, port, err := net.SplitHostPort(srv.Addr())
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	t.Run("ok", func(t *testing.T) {
+		res := Diagnose(ctx, srv.DSN())
+		require.True(t, res.OK(), res.String())
+		require.Empty(t, res.Stage)
+	})
+	t.Run("dsn", func(t *testing.T) {
+		res := Diagnose(ctx, srv.DSN()+"?batch-size=aa")
+		require.Equal(t, DiagnoseStageDSN, res.Stage)
+		require.ErrorIs(t, res.Err, ErrBadDSN)
+	})
+	t.Run("dial", func(t *testing.T) {
+		res := Diagnose(ctx, fmt.Sprintf("impala://127.0.0.1:%d", closedPort(t)))
+		require.Equal(t, DiagnoseStageDial, res.Stage)
+		var opErr *net.OpError
+		require.ErrorAs(t, res.Err, &opErr)
+	})
+	t.Run("tls", func(t *testing.T) {
+		// the test server doesn't support TLS
+		res := Diagnose(ctx, srv.DSN()+"?tls=true&connect-timeout=1s")
+		require.Equal(t, DiagnoseStageTLS, res.Stage)
+		require.ErrorIs(t, res.Err, ErrOpenFailed)
+	})
+	t.Run("auth", func(t *testing.T) {
+		// the test server doesn't support SASL
+		dsn := DSN{Host: host, Port: port, Username: "admin", Password: "s3cret", Auth: "ldap",
+			Params: url.Values{"sasl-timeout": {"1s"}}}
+		res := Diagnose(ctx, dsn.String())
+		require.Equal(t, DiagnoseStageAuth, res.Stage)
+		require.Error(t, res.Err)
+	})
+	t.Run("session", func(t *testing.T) {
+		srv.FailNext("OpenSession", "too many sessions")
+		res := Diagnose(ctx, srv.DSN())
+		require.Equal(t, DiagnoseStageSession, res.Stage)
+		require.ErrorContains(t, res.Err, "too many sessions")
+		require.Contains(t, res.String(), "session failed")
+	})
+}
+
+func TestDiagnoseStage(t *testing.T) {
+	require.Equal(t, DiagnoseStageAuth, diagnoseStage(fmt.Errorf("open: %w", ErrSASLRequired), DiagnoseStageSession))
+	require.Equal(t, DiagnoseStageAuth, diagnoseStage(fmt.Errorf("HTTP Response code: 401"), DiagnoseStageSession))
+	require.Equal(t, DiagnoseStageSession, diagnoseStage(fmt.Errorf("HTTP Response code: 503"), DiagnoseStageSession))
+}
@@ -2,19 +2,20 @@ package impala
 
 import (
 	"context"
+	"crypto/sha256"
+	"crypto/subtle"
 	"crypto/tls"
 	"crypto/x509"
 	"database/sql/driver"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net"
-	"net/url"
 	"os"
-	"strconv"
 	"strings"
-	"time"
+	"sync"
 
 	"github.com/apache/thrift/lib/go/thrift"
 	"github.com/samber/lo"
@@ -28,6 +29,11 @@ import (
 var (
 	// ErrNotSupported means the driver does not support this operation
 	ErrNotSupported = isql.ErrNotSupported
+	// ErrConnBusy means a statement was started on a connection while another one was still in progress on it,
+	// for example, while the sql.Rows of a previous query on the same sql.Conn were not closed yet.
+	ErrConnBusy = isql.ErrConnBusy
+	// ErrReadOnly means a statement was rejected because Options.ReadOnly is enabled
+	ErrReadOnly = isql.ErrReadOnly
 
 	// ErrOpenFailed means the driver failed to open a connection.
 	// Following database/sql docs, this is a separate error from driver.ErrBadConn.
@@ -38,6 +44,60 @@ var (
 	// ErrBadDSN means the driver failed to parse the DSN or contained incorrect values.
 	// Another error in the tree will describe the specific issue.
 	ErrBadDSN = errors.New("impala: bad DSN")
+
+	// ErrSASLRequired means that the server closed the connection during the first request, when the driver
+	// didn't use SASL - with auth=noauth or auth=nosasl. This usually means the server requires authentication.
+	ErrSASLRequired = errors.New("impala: server closed the connection during the first request; " +
+		"it likely requires SASL authentication - set auth=ldap and provide credentials")
+
+	// ErrCertPinMismatch means that the fingerprint of the server certificate didn't match Options.TLSCertPin.
+	// It is returned in the same error tree as ErrOpenFailed.
+	ErrCertPinMismatch = errors.New("impala: server certificate does not match cert-pin")
+
+	// ErrResultSizeExceeded means that a query result exceeded Options.MaxResultBytes
+	ErrResultSizeExceeded = hive.ErrResultSizeExceeded
+
+	// ErrMissingSchema means that the server returned rows for a query without describing their columns
+	ErrMissingSchema = hive.ErrMissingSchema
+
+	// ErrRowLimitExceeded means that a query result had more rows than Options.MaxRowsReturned
+	ErrRowLimitExceeded = hive.ErrRowLimitExceeded
+
+	// ErrCellTooLarge means that a query result had a string value larger than Options.MaxCellBytes
+	ErrCellTooLarge = hive.ErrCellTooLarge
+
+	// ErrRewindNotSupported means that a Rewinder can't restart the rows from the first row, because
+	// Options.ResultCacheSize is not set, or the server rejected it e.g. because the result exceeded the cache
+	ErrRewindNotSupported = hive.ErrRewindNotSupported
+
+	// ErrRowBasedResults means that the server returned results in the row-based encoding of HiveServer2
+	// protocols before V6. The driver requires the column-based encoding, which Impala 2.x and later use.
+	ErrRowBasedResults = hive.ErrRowBasedResults
+
+	// ErrInvalidHandle means that the handle passed to OpenOperation or AttachOperation was not returned by Detacher
+	ErrInvalidHandle = hive.ErrInvalidHandle
+
+	// ErrOperationNotFound means that OpenOperation or AttachOperation failed because the server doesn't have
+	// the query anymore, e.g. because it was closed, it expired, or its session was closed
+	ErrOperationNotFound = hive.ErrOperationNotFound
+
+	// ErrResultsExpired means that the server discarded a query while its results were still being fetched.
+	// This happens when the client fetches rows slower than the IDLE_QUERY_TIMEOUT query option allows:
+	// https://impala.apache.org/docs/build/html/topics/impala_idle_query_timeout.html
+	ErrResultsExpired = hive.ErrResultsExpired
+
+	// ErrFetchTimeout means that the server kept reporting the results of a query as not ready for longer
+	// than Options.FetchMaxWait, so the query was cancelled
+	ErrFetchTimeout = hive.ErrFetchTimeout
+
+	// ErrSessionLimit means that the server rejected a new session because a limit on the number of sessions
+	// was reached, e.g. the max_hs2_sessions_per_user Impala flag. Callers can back off and retry later.
+	// It is in the same error tree as driver.ErrBadConn so database/sql discards the connection.
+	ErrSessionLimit = hive.ErrSessionLimit
+
+	// ErrObjectNotFound means that a statement referenced a table, view or database that doesn't exist.
+	// The error tree also contains the original error from the server, which names the object.
+	ErrObjectNotFound = hive.ErrObjectNotFound
 )
 
 // Custom error types returned by the driver
@@ -82,158 +142,32 @@ func (*Driver) Open(dsn string) (driver.Conn, error) {
 	return conn, nil
 }
 
-func parseURI(uri string) (*Options, error) {
-	u, err := url.Parse(uri)
-	if err != nil {
-		return nil, err
-	}
-
-	// #21, https and http will be supported in the future for http transport
-	// transport=http(s) will also be supported for usql/dburl compatibility
-
-	if u.Scheme != "impala" {
-		return nil, fmt.Errorf("scheme %s not recognized", u.Scheme)
-	}
-
-	opts := DefaultOptions
-
-	if u.User != nil {
-		opts.Username = u.User.Username()
-		password, ok := u.User.Password()
-		if ok {
-			opts.Password = password
-		}
-	}
-
-	opts.Host = u.Hostname()
-	opts.Port = u.Port()
-
-	if opts.Port == "" {
-		opts.Port = DefaultOptions.Port
-	}
-
-	query := u.Query()
-
-	err = parseBoolKey(query, "reuse-session", &opts.ReuseSession)
-	if err != nil {
-		return nil, err
-	}
-
-	auth := query.Get("auth")
-	if auth == "ldap" {
-		opts.UseLDAP = true
-	}
-
-	err = parseBoolKey(query, "tls", &opts.UseTLS)
-	if err != nil {
-		return nil, err
-	}
-
-	if opts.UseTLS {
-		caCert, ok := query["ca-cert"]
-		if ok {
-			opts.CACertPath = caCert[0]
-		}
-
-		err = parseBoolKey(query, "tls-insecure-skip-verify", &opts.TLSInsecureSkipVerify)
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	err = parseIntKey(query, "batch-size", &opts.BatchSize)
-	if err != nil {
-		return nil, err
-	}
-
-	err = parseIntKey(query, "buffer-size", &opts.BufferSize)
-	if err != nil {
-		return nil, err
-	}
-
-	memLimit, ok := query["mem-limit"]
-	if ok {
-		opts.MemoryLimit = memLimit[0]
-	}
-
-	err = parseIntKey(query, "query-timeout", &opts.QueryTimeout)
-	if err != nil {
-		return nil, err
-	}
-
-	err = parseDurationKey(query, "socket-timeout", &opts.SocketTimeout)
-	if err != nil {
-		return nil, err
-	}
-
-	err = parseDurationKey(query, "connect-timeout", &opts.ConnectTimeout)
-	if err != nil {
-		return nil, err
-	}
-
-	logDest, ok := query["log"]
-	if ok {
-		if strings.ToLower(logDest[0]) == "stderr" {
-			opts.LogOut = os.Stderr
-		}
-	}
-
-	return &opts, nil
-}
-
-func parseBoolKey(query url.Values, key string, dest *bool) error {
-	values, ok := query[key]
-	if ok {
-		val := values[0]
-		v, err := strconv.ParseBool(val)
-		if err != nil {
-			return fmt.Errorf("invalid %s value: %s - %w", key, val, err)
-		}
-		*dest = v
-	}
-	return nil
-}
-
-func parseDurationKey(query url.Values, key string, target *time.Duration) (err error) {
-	values, ok := query[key]
-	if ok {
-		*target, err = time.ParseDuration(values[0])
-		if err != nil && strings.Contains(err.Error(), "missing unit in duration") {
-			*target, err = time.ParseDuration(values[0] + "ms")
-		}
-		if err != nil {
-			err = fmt.Errorf("invalid %s: %w", key, err)
-		}
-	}
-	return
-}
-
-func parseIntKey(query url.Values, key string, target *int) (err error) {
-	values, ok := query[key]
-	if ok {
-		*target, err = strconv.Atoi(values[0])
-		if err != nil {
-			err = fmt.Errorf("invalid %s: %w", key, err)
-		}
-	}
-	return
-}
-
-// OpenConnector parses name as a DSN (data source name) and returns connector with fixed options
+// OpenConnector parses name as a DSN (data source name) and returns connector with fixed options.
+// Parse failures, which sql.Open returns, contain ErrBadDSN in the tree, like with Open.
 //
 // Implements driver.DriverContext. See also NewConnector.
 func (*Driver) OpenConnector(name string) (driver.Connector, error) {
 
 	opts, err := parseURI(name)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %w", ErrBadDSN, err)
 	}
 
-	return &connector{opts: opts}, nil
+	return newConnector(opts), nil
 }
 
 type connector struct {
 	opts *Options
+	// opens is a semaphore for Options.MaxConcurrentOpens; nil means no limit
+	opens chan struct{}
+}
+
+func newConnector(opts *Options) *connector {
+	c := &connector{opts: opts}
+	if opts.MaxConcurrentOpens > 0 {
+		c.opens = make(chan struct{}, opts.MaxConcurrentOpens)
+	}
+	return c
 }
 
 // NewConnector creates a connector with specified options.
@@ -243,13 +177,21 @@ type connector struct {
 // If needed, users can wrap the connector to implement custom
 // features e.g., statements to initialize connections.
 func NewConnector(opts *Options) driver.Connector {
-	return &connector{opts: opts}
+	return newConnector(opts)
 }
 
 // Connect implements driver.Connector
 //
 // See Driver.Open for details about error results.
 func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	if c.opens != nil {
+		select {
+		case c.opens <- struct{}{}:
+			defer func() { <-c.opens }()
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%w: waiting for other connections to open: %w", ErrOpenFailed, ctx.Err())
+		}
+	}
 	return connect(ctx, c.opts)
 }
 
@@ -262,103 +204,332 @@ func connect(ctx context.Context, opts *Options) (*isql.Conn, error) {
 	if opts.LogOut == nil {
 		opts.LogOut = io.Discard
 	}
-	transport, tclient, err := connectThrift(ctx, opts)
+	logger := newLogger(opts)
+	if opts.UseTLS && opts.TLSInsecureSkipVerify && opts.LogOut != io.Discard {
+		skipVerifyWarning.Do(func() {
+			logger.Errorf("WARNING: TLS certificate verification is disabled. " +
+				"The connection is vulnerable to man-in-the-middle attacks. Never use this setting in production.")
+		})
+	}
+
+	opts = withContextCredentials(ctx, opts)
+	transport, tclient, serverAddr, err := connectThrift(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	logger := log.New(opts.LogOut, "impala: ", log.LstdFlags)
-	client := hive.NewClient(tclient, logger, &hive.Options{
-		MaxRows:      int64(opts.BatchSize),
-		MemLimit:     opts.MemoryLimit,
-		QueryTimeout: opts.QueryTimeout,
-	})
+	hiveOpts := hiveOptions(opts)
+	client := hive.NewClient(tclient, logger, hiveOpts)
 
 	return isql.NewConn(client, transport, logger, isql.Options{
 		ReuseSession: opts.ReuseSession,
+		CancelClient: func(ctx context.Context) (*hive.Client, io.Closer, error) {
+			cancelTransport, cancelClient, _, err := connectThrift(ctx, opts)
+			if err != nil {
+				return nil, nil, err
+			}
+			return hive.NewClient(cancelClient, logger, hiveOpts), cancelTransport, nil
+		},
+		AcceptsContext:    acceptsCredentials(ctx),
+		AuthMechanism:     authMechanism(transport.TTransport),
+		ServerAddr:        serverAddr,
+		InterpretFirstEOF: interpretFirstEOF(opts),
+		OnSession:         onSession(opts),
+		BytesRead:         transport.bytesRead.Load,
+		ReadOnly:          opts.ReadOnly,
+		StatementLog:      opts.StatementLog,
 	}), nil
 }
 
-func openTransport(ctx context.Context, opts *Options) (thrift.TTransport, *thrift.TConfiguration, error) {
-	var err error
+func onSession(opts *Options) func(ctx context.Context, conn *isql.Conn) error {
+	if len(opts.InitSQL) == 0 && opts.OnConnect == nil {
+		return nil
+	}
+	return func(ctx context.Context, conn *isql.Conn) error {
+		for i, stmt := range opts.InitSQL {
+			if _, err := conn.ExecContext(ctx, stmt, nil); err != nil {
+				return fmt.Errorf("init-sql statement %d failed: %w", i+1, err)
+			}
+		}
+		if opts.OnConnect == nil {
+			return nil
+		}
+		return opts.OnConnect(ctx, conn)
+	}
+}
+
+// skipVerifyWarning ensures the warning about disabled certificate verification is logged once per process
+var skipVerifyWarning sync.Once
+
+func newLogger(opts *Options) *hive.Logger {
+	return hive.NewLogger(log.New(opts.LogOut, "impala: ", log.LstdFlags), opts.LogLevel)
+}
+
+func hiveOptions(opts *Options) *hive.Options {
+	return &hive.Options{
+		MaxRows:      int64(opts.BatchSize),
+		BatchBytes:   opts.BatchBytes,
+		MemLimit:     opts.MemoryLimit,
+		QueryTimeout: opts.QueryTimeout,
+		FetchMaxWait: opts.FetchMaxWait,
+
+		MaxResultBytes:   opts.MaxResultBytes,
+		MaxRowsReturned:  opts.MaxRowsReturned,
+		MaxCellBytes:     opts.MaxCellBytes,
+		TruncateCells:    opts.TruncateCells,
+		ClientIdentifier: opts.ClientIdentifier,
+		DecimalAs:        opts.DecimalAs,
+		DateAs:           opts.DateAs,
+		CharTrim:         opts.CharTrim,
+		NullAsZero:       opts.NullAsZero,
+		ResultChecksum:   opts.ResultChecksum,
+		ComplexJSON:      opts.ComplexJSON,
+		TypeNames:        opts.TypeNames,
+		RequestPool:      opts.RequestPool,
+		SessionConfig:    opts.SessionConfig,
+		Timezone:         opts.Timezone,
+		SpoolResults:     opts.SpoolResults,
+		ResultCacheSize:  opts.ResultCacheSize,
+		ValueConverters:  opts.ValueConverters,
+		OnEvent:          opts.OnQueryEvent,
+	}
+}
+
+// openTransport opens the transport to the server. serverAddr is the address of the server that accepted the
+// connection, see ServerAddr.
+func openTransport(ctx context.Context, opts *Options) (
+	transport thrift.TTransport, conf *thrift.TConfiguration, serverAddr string, err error,
+) {
+	opts, err = withResolvedPassword(opts)
+	if err != nil {
+		return nil, nil, "", err
+	}
 	hostPort := net.JoinHostPort(opts.Host, opts.Port)
+	network, addr := dialAddr(opts)
 
-	conf := &thrift.TConfiguration{
+	conf = &thrift.TConfiguration{
 		TBinaryStrictRead:  lo.ToPtr(false),
 		TBinaryStrictWrite: lo.ToPtr(true),
 		SocketTimeout:      opts.SocketTimeout,
 		ConnectTimeout:     opts.ConnectTimeout,
 	}
 
-	var transport thrift.TTransport
+	switch opts.Transport {
+	case "", TransportBinary:
+	case TransportUnix:
+		if opts.SocketPath == "" {
+			return nil, nil, "", fmt.Errorf("%w: provide socket-path for unix transport", ErrBadDSN)
+		}
+		opts = withUnixDialer(opts)
+	case TransportHTTP:
+		transport, err = openHTTPTransport(opts, hostPort)
+		return transport, conf, hostPort, err
+	default:
+		return nil, nil, "", fmt.Errorf("%w: invalid transport: %s", ErrBadDSN, opts.Transport)
+	}
+
 	if opts.UseTLS {
 
 		conf.TLSConfig, err = getTLSConfig(opts)
 		if err != nil {
-			return nil, nil, err
-		}
-
-		dialer := tls.Dialer{
-			NetDialer: &net.Dialer{
-				Timeout: conf.GetConnectTimeout(),
-			},
-			Config: conf.TLSConfig,
+			return nil, nil, "", err
 		}
 
-		conn, err := dialer.DialContext(ctx, "tcp", hostPort)
+		conn, err := dialTLS(ctx, opts, conf, network, addr)
 		if err != nil {
 			var addInfo string
 			if opts.systemCAStoreSelected() {
 				addInfo = " (using system root CAs)"
 			}
-			return nil, nil, wrapConnectErr(ctx, err, addInfo)
+			return nil, nil, "", wrapConnectErr(ctx, err, addInfo)
 		}
+		serverAddr = remoteAddr(conn, addr)
 		transport = thrift.NewTSSLSocketFromConnConf(conn, conf)
 		transport = checkedTransport{
-			conn:       conn.(*tls.Conn), // type guaranteed by DialContext doc
+			conn:       conn,
 			TTransport: transport,
 		}
+	} else if opts.DialContext != nil {
+		conn, err := dialCustom(ctx, opts, network, addr)
+		if err != nil {
+			return nil, nil, "", wrapConnectErr(ctx, err, "")
+		}
+		serverAddr = remoteAddr(conn, addr)
+		transport = thrift.NewTSocketFromConnConf(conn, conf)
 	} else {
-		transport = thrift.NewTSocketConf(hostPort, conf)
-		if err := transport.Open(); err != nil {
-			return nil, nil, wrapConnectErr(ctx, err, "")
+		socket := thrift.NewTSocketConf(hostPort, conf)
+		if err := socket.Open(); err != nil {
+			return nil, nil, "", wrapConnectErr(ctx, err, "")
 		}
+		serverAddr = remoteAddr(socket.Conn(), addr)
+		transport = socket
 	}
 
 	if opts.UseLDAP {
 
 		if opts.Username == "" {
-			return nil, nil, fmt.Errorf("%w: provide username for LDAP auth", ErrBadDSN)
+			return nil, nil, "", fmt.Errorf("%w: provide username for LDAP auth", ErrBadDSN)
 		}
 
 		// Empty password will be used if not provided.
 
-		transport, err = sasl.NewTSaslTransport(transport, &sasl.Options{
-			Host:     opts.Host,
-			Username: opts.Username,
-			Password: opts.Password,
-		})
+		transport, err = negotiateSASL(ctx, opts, transport)
+		if err != nil {
+			return nil, nil, "", err
+		}
+	} else {
+		transport = thrift.NewTBufferedTransport(transport, opts.BufferSize)
+	}
+
+	return transport, conf, serverAddr, nil
+}
+
+// negotiateSASL wraps socket in SASL and authenticates. The negotiation is bounded by ctx and SASLTimeout,
+// independently of SocketTimeout, which applies only to individual reads and writes.
+func negotiateSASL(ctx context.Context, opts *Options, socket thrift.TTransport) (thrift.TTransport, error) {
+	transport, err := sasl.NewTSaslTransport(socket, &sasl.Options{
+		Host:     opts.Host,
+		Username: opts.Username,
+		Password: opts.Password,
+	})
+	if err != nil {
+		// This never happens in the current version of thrift.
+		// NewTSaslTransport always returns nil error
+		return nil, err
+	}
+
+	negotiationCtx := ctx
+	if opts.SASLTimeout > 0 {
+		var cancel context.CancelFunc
+		negotiationCtx, cancel = context.WithTimeout(ctx, opts.SASLTimeout)
+		defer cancel()
+	}
+	// Thrift sockets reset the deadlines of the underlying connection on every read, so a stalled negotiation
+	// is interrupted by closing the connection.
+	stop := context.AfterFunc(negotiationCtx, func() {
+		_ = socket.Close()
+	})
+	err = transport.Open()
+	if !stop() {
+		var addInfo string
+		if ctx.Err() == nil {
+			addInfo = " sasl-timeout exceeded:"
+		}
+		return nil, fmt.Errorf("%w: SASL negotiation did not complete:%s %w", ErrOpenFailed, addInfo, negotiationCtx.Err())
+	}
+	if err != nil {
+		_ = socket.Close()
+		return nil, fmt.Errorf("%w: authentication failed: %w", ErrOpenFailed, err)
+	}
+	return transport, nil
+}
 
+// withResolvedPassword returns a copy of opts with Password read from PasswordFile or PasswordEnv, if needed.
+// The password is resolved on every connect so the secret is not kept in the connector.
+func withResolvedPassword(opts *Options) (*Options, error) {
+	if !opts.UseLDAP || opts.Password != "" {
+		return opts, nil
+	}
+	var password string
+	switch {
+	case opts.PasswordFile != "":
+		content, err := os.ReadFile(opts.PasswordFile)
 		if err != nil {
-			// This never happens in the current version of thrift.
-			// NewTSaslTransport always returns nil error
-			return nil, nil, err
+			return nil, fmt.Errorf("%w: failed to read password-file: %w", ErrBadDSN, err)
+		}
+		password = strings.TrimRight(string(content), "\r\n")
+	case opts.PasswordEnv != "":
+		var ok bool
+		password, ok = os.LookupEnv(opts.PasswordEnv)
+		if !ok {
+			return nil, fmt.Errorf("%w: password-env variable %s is not set", ErrBadDSN, opts.PasswordEnv)
+		}
+	default:
+		return opts, nil
+	}
+	resolved := *opts
+	resolved.Password = password
+	return &resolved, nil
+}
+
+// dialAddr returns the network and the address, which the binary and unix transports dial
+func dialAddr(opts *Options) (string, string) {
+	if opts.Transport == TransportUnix {
+		return "unix", opts.SocketPath
+	}
+	return "tcp", net.JoinHostPort(opts.Host, opts.Port)
+}
+
+// withUnixDialer returns a copy of opts, which dials with a net.Dialer if DialContext is not set, so that
+// unix sockets are opened like connections from a custom dialer
+func withUnixDialer(opts *Options) *Options {
+	if opts.DialContext != nil {
+		return opts
+	}
+	resolved := *opts
+	resolved.DialContext = (&net.Dialer{}).DialContext
+	return &resolved
+}
+
+func dialTLS(ctx context.Context, opts *Options, conf *thrift.TConfiguration, network, addr string) (*tls.Conn, error) {
+	if opts.DialContext == nil {
+		dialer := tls.Dialer{
+			NetDialer: &net.Dialer{
+				Timeout: conf.GetConnectTimeout(),
+			},
+			Config: conf.TLSConfig,
 		}
 
-		err = transport.Open()
+		conn, err := dialer.DialContext(ctx, network, addr)
 		if err != nil {
-			return nil, nil, fmt.Errorf("%w: authentication failed: %w", ErrOpenFailed, err)
+			return nil, err
 		}
-	} else {
-		transport = thrift.NewTBufferedTransport(transport, opts.BufferSize)
+		return conn.(*tls.Conn), nil // type guaranteed by DialContext doc
+	}
+
+	ctx, cancel := withConnectTimeout(ctx, opts)
+	defer cancel()
+	rawConn, err := opts.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := conf.TLSConfig
+	if tlsConfig.ServerName == "" {
+		// tls.Dialer does the same
+		tlsConfig = tlsConfig.Clone()
+		tlsConfig.ServerName = opts.Host
+	}
+	conn := tls.Client(rawConn, tlsConfig)
+	if err = conn.HandshakeContext(ctx); err != nil {
+		_ = rawConn.Close()
+		return nil, err
 	}
+	return conn, nil
+}
 
-	return transport, conf, nil
+func dialCustom(ctx context.Context, opts *Options, network, addr string) (net.Conn, error) {
+	ctx, cancel := withConnectTimeout(ctx, opts)
+	defer cancel()
+	return opts.DialContext(ctx, network, addr)
+}
+
+// withConnectTimeout applies ConnectTimeout to ctx, like net.Dialer does
+func withConnectTimeout(ctx context.Context, opts *Options) (context.Context, context.CancelFunc) {
+	if opts.ConnectTimeout > 0 {
+		return context.WithTimeout(ctx, opts.ConnectTimeout)
+	}
+	return ctx, func() {}
 }
 
 func getTLSConfig(opts *Options) (*tls.Config, error) {
 	tlsConfig := &tls.Config{
 		InsecureSkipVerify: opts.TLSInsecureSkipVerify,
+		ServerName:         opts.TLSServerName,
+		MinVersion:         opts.TLSMinVersion,
+		CipherSuites:       opts.TLSCipherSuites,
+	}
+	if tlsConfig.MinVersion == 0 {
+		tlsConfig.MinVersion = tls.VersionTLS12
 	}
 	if certPath := opts.CACertPath; !opts.TLSInsecureSkipVerify && certPath != "" {
 		caCertPool, err := readCert(certPath)
@@ -367,8 +538,78 @@ func getTLSConfig(opts *Options) (*tls.Config, error) {
 		}
 		tlsConfig.RootCAs = caCertPool
 	}
+	if opts.TLSCertPin != "" {
+		pin, err := parseCertPin(opts.TLSCertPin)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid TLSCertPin: %w", ErrBadDSN, err)
+		}
+		if opts.CACertPath == "" {
+			// The pin replaces CA verification, so self-signed certificates can be pinned.
+			// With a CA certificate, both the chain and the pin are verified.
+			tlsConfig.InsecureSkipVerify = true
+		}
+		tlsConfig.VerifyPeerCertificate = verifyCertPin(pin)
+	}
 	return tlsConfig, nil
 }
+
+// parseTLSVersion parses versions like 1.2 into tls.VersionTLS12 and the like
+func parseTLSVersion(s string) (uint16, error) {
+	switch s {
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, errors.New("supported values are 1.0, 1.1, 1.2, and 1.3")
+	}
+}
+
+// parseCipherSuites parses a comma-separated list of cipher suite names, as returned by tls.CipherSuiteName
+func parseCipherSuites(s string) ([]uint16, error) {
+	known := make(map[string]uint16)
+	for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		known[suite.Name] = suite.ID
+	}
+	var ids []uint16
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		id, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown cipher suite %q", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// parseCertPin decodes a hex SHA-256 fingerprint. Bytes may be separated by colons,
+// as in the output of openssl x509 -fingerprint -sha256.
+func parseCertPin(s string) ([]byte, error) {
+	pin, err := hex.DecodeString(strings.ReplaceAll(s, ":", ""))
+	if err != nil || len(pin) != sha256.Size {
+		return nil, errors.New("expected hex SHA-256 fingerprint")
+	}
+	return pin, nil
+}
+
+// verifyCertPin returns a tls.Config.VerifyPeerCertificate callback that checks the fingerprint of the leaf certificate
+func verifyCertPin(pin []byte) func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return ErrCertPinMismatch
+		}
+		sum := sha256.Sum256(rawCerts[0])
+		if subtle.ConstantTimeCompare(sum[:], pin) != 1 {
+			return fmt.Errorf("%w: fingerprint %X", ErrCertPinMismatch, sum)
+		}
+		return nil
+	}
+}
 func wrapConnectErr(ctx context.Context, err error, addInfo string) error {
 	// Add information so the user can tell if "context deadline exceeded" means that
 	// the ConnectTimeout was exceeded or the deadline was from the given context.
@@ -394,14 +635,18 @@ func readCert(certPath string) (*x509.CertPool, error) {
 	return caCertPool, nil
 }
 
-func connectThrift(ctx context.Context, opts *Options) (thrift.TTransport, thrift.TClient, error) {
-	transport, conf, err := openTransport(ctx, opts)
+// connectThrift opens a Thrift client to the server. serverAddr is returned as by openTransport.
+func connectThrift(ctx context.Context, opts *Options) (
+	transport *countingTransport, tclient thrift.TClient, serverAddr string, err error,
+) {
+	rawTransport, conf, serverAddr, err := openTransport(ctx, opts)
 
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, "", err
 	}
-	protocol := thrift.NewTBinaryProtocolConf(transport, conf)
+	counting := &countingTransport{TTransport: rawTransport}
+	protocol := thrift.NewTBinaryProtocolConf(counting, conf)
 
-	tclient := thrift.NewTStandardClient(protocol, protocol)
-	return transport, tclient, nil
+	tclient = thrift.NewTStandardClient(protocol, protocol)
+	return counting, tclient, serverAddr, nil
 }
@@ -0,0 +1,382 @@
+package impala
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DSNError describes a DSN parameter with an invalid value.
+// It is reported in the same error tree as ErrBadDSN.
+type DSNError struct {
+	Key   string
+	Value string
+	Err   error
+}
+
+func (e *DSNError) Error() string {
+	return fmt.Sprintf("invalid %s value %q: %v", e.Key, e.Value, e.Err)
+}
+
+func (e *DSNError) Unwrap() error {
+	return e.Err
+}
+
+// DSN builds a data source name for the impala driver. It takes care of escaping, which is easy to get wrong
+// when a DSN is concatenated by hand e.g. for passwords or certificate paths with special characters.
+// See README.md for the supported parameters.
+type DSN struct {
+	Host string
+	// Port is optional. Empty means the default port for the transport.
+	Port     string
+	Username string
+	Password string
+	// UseTLS adds tls=true
+	UseTLS bool
+	// Auth is the authentication mode e.g. "ldap". Empty means the default - noauth.
+	Auth string
+	// Params holds any other DSN parameters, like "ca-cert" or "batch-size".
+	Params url.Values
+}
+
+// String returns the DSN as an impala:// URL
+func (d DSN) String() string {
+	return d.url().String()
+}
+
+// Redacted is like String but replaces the password with "xxxxx", so the result can be logged
+func (d DSN) Redacted() string {
+	return d.url().Redacted()
+}
+
+func (d DSN) url() *url.URL {
+	u := &url.URL{
+		Scheme: "impala",
+		Host:   d.Host,
+	}
+	if d.Port != "" {
+		u.Host = net.JoinHostPort(d.Host, d.Port)
+	} else if strings.Contains(d.Host, ":") {
+		u.Host = "[" + d.Host + "]" // IPv6 literal
+	}
+	if d.Password != "" {
+		u.User = url.UserPassword(d.Username, d.Password)
+	} else if d.Username != "" {
+		u.User = url.User(d.Username)
+	}
+
+	query := url.Values{}
+	for key, values := range d.Params {
+		query[key] = slices.Clone(values)
+	}
+	if d.UseTLS {
+		query.Set("tls", "true")
+	}
+	if d.Auth != "" {
+		query.Set("auth", d.Auth)
+	}
+	u.RawQuery = query.Encode()
+	return u
+}
+
+// ParseDSN parses and validates dsn. The String method of the result returns an equivalent DSN,
+// though the order of query parameters may differ. The default port is not filled in.
+func ParseDSN(dsn string) (DSN, error) {
+	opts, err := parseURI(dsn)
+	if err != nil {
+		return DSN{}, fmt.Errorf("%w: %w", ErrBadDSN, err)
+	}
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return DSN{}, err // never happens - parseURI would have failed
+	}
+	query := u.Query()
+	res := DSN{
+		Host:     opts.Host,
+		Port:     u.Port(),
+		Username: opts.Username,
+		Password: opts.Password,
+		UseTLS:   opts.UseTLS,
+		Auth:     query.Get("auth"),
+	}
+	query.Del("tls")
+	query.Del("auth")
+	if len(query) > 0 {
+		res.Params = query
+	}
+	return res, nil
+}
+
+// dsnParam maps a DSN query parameter to Options
+type dsnParam struct {
+	key string
+	set func(opts *Options, value string) error
+	// tlsOnly parameters are ignored unless TLS is enabled
+	tlsOnly bool
+	// multi parameters may be repeated. set is called for each value. Otherwise, only the first value is used.
+	multi bool
+	// secret parameter values may contain credentials so they are not included in errors
+	secret bool
+}
+
+// dsnParams lists the supported DSN query parameters, in the order they are applied.
+// Document new parameters in README.md.
+var dsnParams = []dsnParam{
+	{key: "reuse-session", set: boolParam(func(o *Options) *bool { return &o.ReuseSession })},
+	{key: "readonly", set: boolParam(func(o *Options) *bool { return &o.ReadOnly })},
+	{key: "auth", set: func(opts *Options, value string) error {
+		switch value {
+		case "ldap":
+			opts.UseLDAP = true
+		case "noauth", "nosasl":
+			// the transport is not wrapped in SASL
+			opts.UseLDAP = false
+		default:
+			return errors.New("supported values are noauth, nosasl, ldap")
+		}
+		return nil
+	}},
+	{key: "password-file", set: stringParam(func(o *Options) *string { return &o.PasswordFile })},
+	{key: "password-env", set: stringParam(func(o *Options) *string { return &o.PasswordEnv })},
+	{key: "tls", set: boolParam(func(o *Options) *bool { return &o.UseTLS })},
+	{key: "ca-cert", tlsOnly: true, set: stringParam(func(o *Options) *string { return &o.CACertPath })},
+	{key: "tls-insecure-skip-verify", tlsOnly: true, set: boolParam(func(o *Options) *bool { return &o.TLSInsecureSkipVerify })},
+	// shorter alias of tls-insecure-skip-verify
+	{key: "tls-skip-verify", tlsOnly: true, set: boolParam(func(o *Options) *bool { return &o.TLSInsecureSkipVerify })},
+	{key: "cert-pin", tlsOnly: true, set: func(opts *Options, value string) error {
+		if _, err := parseCertPin(value); err != nil {
+			return err
+		}
+		opts.TLSCertPin = value
+		return nil
+	}},
+	{key: "tls-server-name", tlsOnly: true, set: stringParam(func(o *Options) *string { return &o.TLSServerName })},
+	{key: "tls-min-version", tlsOnly: true, set: func(opts *Options, value string) (err error) {
+		opts.TLSMinVersion, err = parseTLSVersion(value)
+		return err
+	}},
+	{key: "tls-cipher-suites", tlsOnly: true, set: func(opts *Options, value string) (err error) {
+		opts.TLSCipherSuites, err = parseCipherSuites(value)
+		return err
+	}},
+	{key: "batch-size", set: intParam(func(o *Options) *int { return &o.BatchSize })},
+	{key: "batch-bytes", set: int64Param(func(o *Options) *int64 { return &o.BatchBytes })},
+	{key: "buffer-size", set: intParam(func(o *Options) *int { return &o.BufferSize })},
+	{key: "mem-limit", set: stringParam(func(o *Options) *string { return &o.MemoryLimit })},
+	{key: "pool", set: func(opts *Options, value string) error {
+		if value == "" {
+			return errors.New("must not be empty")
+		}
+		opts.RequestPool = value
+		return nil
+	}},
+	{key: "timezone", set: func(opts *Options, value string) error {
+		if value == "" {
+			return errors.New("must not be empty")
+		}
+		opts.Timezone = value
+		return nil
+	}},
+	{key: "spool-results", set: boolParam(func(o *Options) *bool { return &o.SpoolResults })},
+	{key: "result-cache-size", set: int64Param(func(o *Options) *int64 { return &o.ResultCacheSize })},
+	{key: "query-timeout", set: intParam(func(o *Options) *int { return &o.QueryTimeout })},
+	{key: "fetch-max-wait", set: durationParam(func(o *Options) *time.Duration { return &o.FetchMaxWait })},
+	{key: "max-result-bytes", set: int64Param(func(o *Options) *int64 { return &o.MaxResultBytes })},
+	{key: "max-rows-returned", set: int64Param(func(o *Options) *int64 { return &o.MaxRowsReturned })},
+	{key: "max-cell-bytes", set: intParam(func(o *Options) *int { return &o.MaxCellBytes })},
+	{key: "truncate-cells", set: boolParam(func(o *Options) *bool { return &o.TruncateCells })},
+	{key: "char-trim", set: boolParam(func(o *Options) *bool { return &o.CharTrim })},
+	{key: "null-as-zero", set: boolParam(func(o *Options) *bool { return &o.NullAsZero })},
+	{key: "result-checksum", set: boolParam(func(o *Options) *bool { return &o.ResultChecksum })},
+	{key: "statement-log", set: boolParam(func(o *Options) *bool { return &o.StatementLog })},
+	{key: "complex-json", set: oneOfParam(func(o *Options) *string { return &o.ComplexJSON },
+		ComplexJSONCompact, ComplexJSONIndent)},
+	{key: "type-names", set: oneOfParam(func(o *Options) *string { return &o.TypeNames },
+		TypeNamesThrift, TypeNamesSQL)},
+	{key: "decimal-as", set: oneOfParam(func(o *Options) *string { return &o.DecimalAs },
+		DecimalAsString, DecimalAsFloat64, DecimalAsRat)},
+	{key: "date-as", set: oneOfParam(func(o *Options) *string { return &o.DateAs },
+		DateAsString, DateAsTime)},
+	{key: "socket-timeout", set: durationParam(func(o *Options) *time.Duration { return &o.SocketTimeout })},
+	{key: "connect-timeout", set: durationParam(func(o *Options) *time.Duration { return &o.ConnectTimeout })},
+	{key: "max-concurrent-opens", set: intParam(func(o *Options) *int { return &o.MaxConcurrentOpens })},
+	{key: "sasl-timeout", set: durationParam(func(o *Options) *time.Duration { return &o.SASLTimeout })},
+	{key: "transport", set: oneOfParam(func(o *Options) *string { return &o.Transport },
+		TransportBinary, TransportHTTP, TransportUnix)},
+	{key: "socket-path", set: stringParam(func(o *Options) *string { return &o.SocketPath })},
+	{key: "client-identifier", set: stringParam(func(o *Options) *string { return &o.ClientIdentifier })},
+	{key: "http-path", set: stringParam(func(o *Options) *string { return &o.HTTPPath })},
+	{key: "http-proxy", set: stringParam(func(o *Options) *string { return &o.HTTPProxy })},
+	{key: "header", multi: true, secret: true, set: func(opts *Options, value string) error {
+		name, headerValue, found := strings.Cut(value, ":")
+		name = strings.TrimSpace(name)
+		if !found || name == "" {
+			return errors.New("expected format is Name:Value")
+		}
+		if opts.HTTPHeaders == nil {
+			opts.HTTPHeaders = map[string]string{}
+		}
+		opts.HTTPHeaders[name] = strings.TrimSpace(headerValue)
+		return nil
+	}},
+	{key: "init-sql", multi: true, set: func(opts *Options, value string) error {
+		if strings.TrimSpace(value) == "" {
+			return errors.New("expected a statement")
+		}
+		opts.InitSQL = append(opts.InitSQL, value)
+		return nil
+	}},
+	{key: "log", set: func(opts *Options, value string) error {
+		if strings.ToLower(value) == "stderr" {
+			opts.LogOut = os.Stderr
+		}
+		return nil
+	}},
+	{key: "log-level", set: oneOfParam(func(o *Options) *string { return &o.LogLevel },
+		LogLevelError, LogLevelInfo, LogLevelDebug)},
+}
+
+func parseURI(uri string) (*Options, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		// url.Error includes the full DSN. The underlying error may also quote a part of the password.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) && redactDSN(urlErr.URL) != urlErr.URL {
+			urlErr.URL = redactDSN(urlErr.URL)
+			urlErr.Err = errors.New("invalid URL syntax (details are hidden because the DSN contains a password)")
+		}
+		return nil, err
+	}
+
+	// #21, https and http schemes may be supported in the future for http transport
+	// for usql/dburl compatibility. For now, we support transport=http
+
+	if u.Scheme != "impala" {
+		return nil, fmt.Errorf("scheme %s not recognized", u.Scheme)
+	}
+
+	opts := DefaultOptions
+
+	if u.User != nil {
+		opts.Username = u.User.Username()
+		password, ok := u.User.Password()
+		if ok {
+			opts.Password = password
+		}
+	}
+
+	opts.Host = u.Hostname()
+	opts.Port = u.Port()
+
+	if opts.Port == "" {
+		opts.Port = DefaultOptions.Port
+	}
+
+	query := u.Query()
+	for _, param := range dsnParams {
+		values, ok := query[param.key]
+		if !ok || (param.tlsOnly && !opts.UseTLS) {
+			continue
+		}
+		if !param.multi {
+			values = values[:1]
+		}
+		for _, value := range values {
+			if err = param.set(&opts, value); err != nil {
+				if param.secret {
+					value = redacted
+				}
+				return nil, &DSNError{Key: param.key, Value: value, Err: err}
+			}
+		}
+	}
+
+	if opts.Transport == TransportHTTP && u.Port() == "" {
+		opts.Port = defaultHTTPPort
+	}
+
+	return &opts, nil
+}
+
+// redacted replaces secrets, like url.URL.Redacted does
+const redacted = "xxxxx"
+
+// redactDSN replaces the password in dsn, which may be an invalid URL, so it can be included in errors.
+// When in doubt, it replaces more than the password.
+func redactDSN(dsn string) string {
+	scheme, rest, ok := strings.Cut(dsn, "://")
+	if !ok {
+		return dsn
+	}
+	// url.Parse also considers the last @ as the end of userinfo
+	at := strings.LastIndex(rest, "@")
+	if at < 0 {
+		return dsn
+	}
+	username, _, hasPassword := strings.Cut(rest[:at], ":")
+	if !hasPassword {
+		return dsn
+	}
+	return scheme + "://" + username + ":" + redacted + rest[at:]
+}
+
+func stringParam(field func(*Options) *string) func(*Options, string) error {
+	return func(opts *Options, value string) error {
+		*field(opts) = value
+		return nil
+	}
+}
+
+func oneOfParam(field func(*Options) *string, supported ...string) func(*Options, string) error {
+	return func(opts *Options, value string) error {
+		for _, s := range supported {
+			if value == s {
+				*field(opts) = value
+				return nil
+			}
+		}
+		return fmt.Errorf("supported values are %s", strings.Join(supported, ", "))
+	}
+}
+
+func boolParam(field func(*Options) *bool) func(*Options, string) error {
+	return func(opts *Options, value string) (err error) {
+		*field(opts), err = strconv.ParseBool(value)
+		return err
+	}
+}
+
+func intParam(field func(*Options) *int) func(*Options, string) error {
+	return func(opts *Options, value string) (err error) {
+		*field(opts), err = strconv.Atoi(value)
+		return err
+	}
+}
+
+func int64Param(field func(*Options) *int64) func(*Options, string) error {
+	return func(opts *Options, value string) (err error) {
+		*field(opts), err = strconv.ParseInt(value, 10, 64)
+		return err
+	}
+}
+
+// durationParam accepts time.ParseDuration syntax. Integers without a unit are interpreted as milliseconds.
+func durationParam(field func(*Options) *time.Duration) func(*Options, string) error {
+	return func(opts *Options, value string) error {
+		d, err := time.ParseDuration(value)
+		if err != nil && strings.Contains(err.Error(), "missing unit in duration") {
+			d, err = time.ParseDuration(value + "ms")
+		}
+		if err != nil {
+			return err
+		}
+		*field(opts) = d
+		return nil
+	}
+}
@@ -0,0 +1,46 @@
+package impala
+
+import "github.com/sclgo/impala-go/internal/hive"
+
+// QueryEvent describes a step in the lifecycle of a session or a query, reported to Options.OnQueryEvent.
+// It carries the same information as the driver log in a structured form, e.g. for shipping to a log pipeline.
+type QueryEvent = hive.Event
+
+// Values of QueryEvent.Phase
+const (
+	// QueryPhaseOpen is reported when a session is opened
+	QueryPhaseOpen = hive.PhaseOpen
+	// QueryPhaseExecute is reported when a statement starts and each time the driver checks its state
+	QueryPhaseExecute = hive.PhaseExecute
+	// QueryPhaseQueued is reported instead of QueryPhaseExecute while the query waits in the admission control
+	// queue for resources. UIs can show it as "waiting for resources" instead of a generic progress indicator.
+	QueryPhaseQueued = hive.PhaseQueued
+	// QueryPhaseFetch is reported for each batch of rows fetched from the server
+	QueryPhaseFetch = hive.PhaseFetch
+	// QueryPhaseClose is reported when the query is closed, with the number of rows affected
+	QueryPhaseClose = hive.PhaseClose
+)
+
+// QueryState is the state of a query on the server, reported in QueryEvent.State. The values match the names of
+// the states in the HiveServer2 API e.g. FINISHED_STATE, so they don't change with the generated Thrift code.
+type QueryState = hive.QueryState
+
+// Values of QueryState
+const (
+	// QueryStateInitialized means the server accepted the query but didn't start it yet
+	QueryStateInitialized = hive.QueryStateInitialized
+	// QueryStatePending means the query waits in the admission control queue, see QueryPhaseQueued
+	QueryStatePending = hive.QueryStatePending
+	// QueryStateRunning means the query is running
+	QueryStateRunning = hive.QueryStateRunning
+	// QueryStateFinished means the query completed and its results, if any, are ready to be fetched
+	QueryStateFinished = hive.QueryStateFinished
+	// QueryStateCanceled means the query was cancelled
+	QueryStateCanceled = hive.QueryStateCanceled
+	// QueryStateClosed means the query was closed
+	QueryStateClosed = hive.QueryStateClosed
+	// QueryStateError means the query failed
+	QueryStateError = hive.QueryStateError
+	// QueryStateUnknown is reported for states that the driver doesn't recognize
+	QueryStateUnknown = hive.QueryStateUnknown
+)
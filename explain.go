@@ -0,0 +1,85 @@
+package impala
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/sclgo/impala-go/internal/isql"
+)
+
+// ExplainLevel controls the amount of detail in the output of Explain,
+// like the EXPLAIN_LEVEL query option
+type ExplainLevel int
+
+// Explain levels as documented in https://impala.apache.org/docs/build/html/topics/impala_explain_level.html
+const (
+	ExplainMinimal  ExplainLevel = 0
+	ExplainStandard ExplainLevel = 1
+	ExplainExtended ExplainLevel = 2
+	ExplainVerbose  ExplainLevel = 3
+)
+
+// Explain returns the plan of the given query, as reported by EXPLAIN, with the explain level
+// configured in the session. *sql.Conn implements ConnRawAccess.
+func Explain(ctx context.Context, conn ConnRawAccess, query string) (string, error) {
+	return explain(ctx, conn, query, nil)
+}
+
+// ExplainWithLevel is like Explain but uses the given explain level.
+// The level applies only to this call and doesn't change the EXPLAIN_LEVEL option of the session.
+func ExplainWithLevel(ctx context.Context, conn ConnRawAccess, query string, level ExplainLevel) (string, error) {
+	return explain(ctx, conn, query, map[string]string{
+		"EXPLAIN_LEVEL": strconv.Itoa(int(level)),
+	})
+}
+
+// Validate checks that the given query is valid, e.g. for a SQL linter, without running it. It fails with the
+// error reported by Impala if the query has a syntax or analysis error, e.g. an error matching ErrObjectNotFound
+// if it references a table that doesn't exist. The query is analyzed and planned, like with EXPLAIN, so Validate
+// supports the statements that EXPLAIN supports - queries, INSERT, CREATE TABLE AS SELECT, and UPDATE, DELETE and
+// UPSERT for Kudu tables - but not e.g. other DDL. *sql.Conn implements ConnRawAccess.
+func Validate(ctx context.Context, conn ConnRawAccess, query string) error {
+	return conn.Raw(func(driverConn any) error {
+		impalaConn, ok := driverConn.(*isql.Conn)
+		if !ok {
+			return errors.New("validate can operate only on Impala drivers")
+		}
+		// analysis errors are reported when the statement is executed, so the plan is not fetched
+		rows, err := impalaConn.QueryWithOptions(ctx, "EXPLAIN "+query, map[string]string{
+			"EXPLAIN_LEVEL": strconv.Itoa(int(ExplainMinimal)),
+		})
+		if err != nil {
+			return err
+		}
+		return rows.Close()
+	})
+}
+
+func explain(ctx context.Context, conn ConnRawAccess, query string, queryOptions map[string]string) (string, error) {
+	var sb strings.Builder
+	err := conn.Raw(func(driverConn any) error {
+		impalaConn, ok := driverConn.(*isql.Conn)
+		if !ok {
+			return errors.New("explain can operate only on Impala drivers")
+		}
+		rows, err := impalaConn.QueryWithOptions(ctx, "EXPLAIN "+query, queryOptions)
+		if err != nil {
+			return err
+		}
+		dest := make([]driver.Value, len(rows.Columns()))
+		for err = rows.Next(dest); err == nil; err = rows.Next(dest) {
+			_, _ = fmt.Fprintln(&sb, dest[0]) // Fprintln to strings.Builder can't fail
+		}
+		closeErr := rows.Close()
+		if !errors.Is(err, io.EOF) {
+			return err
+		}
+		return closeErr
+	})
+	return sb.String(), err
+}
@@ -0,0 +1,48 @@
+package impala_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sclgo/impala-go"
+	"github.com/sclgo/impala-go/impalatest"
+	"github.com/stretchr/testify/require"
+)
+
+// Integration tests for Explain are in connection_test.go
+
+func TestExplain(t *testing.T) {
+	t.Run("raw conn is not impala", func(t *testing.T) {
+		_, err := impala.Explain(context.Background(), myConn{1}, "SELECT 1")
+		require.Error(t, err)
+	})
+}
+
+func TestValidate(t *testing.T) {
+	srv := impalatest.Start(t)
+	require.NoError(t, srv.AddResult("EXPLAIN SELECT 1", impalatest.Result{
+		Columns: []impalatest.Column{{Name: "Explain String", Type: "STRING"}},
+		Rows:    [][]any{{"PLAN-ROOT SINK"}, {"00:UNION"}},
+	}))
+	require.NoError(t, srv.AddResult("EXPLAIN SELECT * FROM missing", impalatest.Result{
+		Err: "AnalysisException: Could not resolve table reference: 'missing'",
+	}))
+
+	db := srv.OpenDB(t)
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, conn.Close())
+	}()
+
+	require.NoError(t, impala.Validate(ctx, conn, "SELECT 1"))
+	err = impala.Validate(ctx, conn, "SELECT * FROM missing")
+	require.ErrorIs(t, err, impala.ErrObjectNotFound)
+	require.ErrorContains(t, err, "Could not resolve table reference")
+	require.NotContains(t, srv.Statements(), "SELECT 1", "the query itself is not executed")
+
+	t.Run("raw conn is not impala", func(t *testing.T) {
+		require.Error(t, impala.Validate(ctx, myConn{1}, "SELECT 1"))
+	})
+}
@@ -4,6 +4,7 @@ package ftest
 // Create or update connection_int_test.go to add unit tests
 
 import (
+	"cmp"
 	"context"
 	"crypto/tls"
 	"database/sql"
@@ -11,7 +12,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"net"
+	"math/big"
 	"net/http"
 	_ "net/http/pprof"
 	"net/url"
@@ -20,7 +21,6 @@ import (
 	"reflect"
 	"runtime"
 	"slices"
-	"strings"
 	"testing"
 	"time"
 
@@ -79,6 +79,15 @@ func TestIntegration_Impala3(t *testing.T) {
 	fi.SkipLongTest(t)
 	dsn := startImpala3(t)
 	runSuite(t, dsn)
+
+	t.Run("nosasl", func(t *testing.T) {
+		// the container runs without authentication so it accepts a plain binary transport without SASL framing
+		db := fi.NoError(sql.Open("impala", dsn+"&auth=nosasl")).Require(t)
+		defer fi.NoErrorF(db.Close, t)
+		var res int
+		require.NoError(t, db.QueryRow("SELECT 1").Scan(&res))
+		require.Equal(t, 1, res)
+	})
 }
 
 // TestIntegration_Impala4 covers integration with Impala 4.x and TLS
@@ -224,6 +233,46 @@ func runSuite(t *testing.T, dsn string) {
 func runCasesWithDsn(t *testing.T, db *sql.DB, dsn string) {
 	testSet(t, db, dsn)
 
+	t.Run("decimal-as", func(t *testing.T) {
+		testDecimalAs(t, dsn)
+	})
+
+	t.Run("max-rows-returned", func(t *testing.T) {
+		limitDsn := fi.NoError(url.Parse(dsn)).Require(t)
+		query := limitDsn.Query()
+		query.Set("max-rows-returned", "2")
+		limitDsn.RawQuery = query.Encode()
+
+		dbLimit := fi.NoError(sql.Open("impala", limitDsn.String())).Require(t)
+		defer fi.NoErrorF(dbLimit.Close, t)
+
+		rows, err := dbLimit.Query("SELECT * FROM (VALUES (1 AS a), (2), (3)) t")
+		require.NoError(t, err)
+		defer fi.NoErrorF(rows.Close, t)
+		for rows.Next() {
+		}
+		require.ErrorIs(t, rows.Err(), impala.ErrRowLimitExceeded)
+	})
+
+	t.Run("statement-log", func(t *testing.T) {
+		logDsn := fi.NoError(url.Parse(dsn)).Require(t)
+		query := logDsn.Query()
+		query.Set("statement-log", "true")
+		logDsn.RawQuery = query.Encode()
+
+		dbLog := fi.NoError(sql.Open("impala", logDsn.String())).Require(t)
+		defer fi.NoErrorF(dbLog.Close, t)
+		conn := fi.NoError(dbLog.Conn(context.Background())).Require(t)
+		defer fi.NoErrorF(conn.Close, t)
+
+		rows, err := conn.QueryContext(context.Background(), "SELECT 1")
+		require.NoError(t, err)
+		require.NoError(t, rows.Close())
+		// the content of the log varies between Impala versions
+		_, err = impala.LastStatementLog(conn)
+		require.NoError(t, err)
+	})
+
 	t.Run("reused session expired", func(t *testing.T) {
 		reuseSessionDsn := fi.NoError(url.Parse(dsn)).Require(t)
 		query := reuseSessionDsn.Query()
@@ -250,6 +299,26 @@ func runCasesWithDsn(t *testing.T, db *sql.DB, dsn string) {
 	})
 }
 
+func testDecimalAs(t *testing.T, dsn string) {
+	decimalAsDsn := fi.NoError(url.Parse(dsn)).Require(t)
+	query := decimalAsDsn.Query()
+	query.Set("decimal-as", "rat")
+	decimalAsDsn.RawQuery = query.Encode()
+
+	db := fi.NoError(sql.Open("impala", decimalAsDsn.String())).Require(t)
+	defer fi.NoErrorF(db.Close, t)
+
+	rows, err := db.Query("select cast(-1.1 as decimal(38,30))")
+	require.NoError(t, err)
+	defer fi.NoErrorF(rows.Close, t)
+	column := fi.NoError(rows.ColumnTypes()).Require(t)[0]
+	require.Equal(t, reflect.TypeFor[*big.Rat](), column.ScanType())
+	require.True(t, rows.Next())
+	var res *big.Rat
+	require.NoError(t, rows.Scan(&res))
+	require.Equal(t, big.NewRat(-11, 10), res)
+}
+
 func runHappyCases(t *testing.T, db *sql.DB) {
 	t.Run("Pinger", func(t *testing.T) {
 		testPinger(t, db)
@@ -269,6 +338,36 @@ func runHappyCases(t *testing.T, db *sql.DB) {
 	t.Run("decimal support", func(t *testing.T) {
 		testDecimal(t, db)
 	})
+	t.Run("Explain", func(t *testing.T) {
+		testExplain(t, db)
+	})
+	t.Run("quoting", func(t *testing.T) {
+		value := `it's a \ backslash`
+		var res string
+		stmt := "SELECT " + impala.QuoteString(value) + " AS " + impala.QuoteIdentifier("select")
+		require.NoError(t, db.QueryRow(stmt).Scan(&res))
+		require.Equal(t, value, res)
+		require.NoError(t, db.QueryRow("SELECT ?", value).Scan(&res))
+		require.Equal(t, value, res)
+	})
+	t.Run("ListRunningQueries", func(t *testing.T) {
+		ctx := context.Background()
+		conn := fi.NoError(db.Conn(ctx)).Require(t)
+		defer fi.NoErrorF(conn.Close, t)
+		// sys.impala_query_live requires workload management, which is not enabled in all test environments
+		_, err := impala.ListRunningQueries(ctx, conn)
+		if err != nil {
+			require.ErrorIs(t, err, impala.ErrNotSupported)
+		}
+	})
+	t.Run("query labels", func(t *testing.T) {
+		ctx := impala.WithQueryLabels(context.Background(), map[string]string{"REQUEST_POOL": "default-pool"})
+		var res int
+		require.NoError(t, db.QueryRowContext(ctx, "SELECT 1").Scan(&res))
+		ctx = impala.WithQueryLabels(ctx, map[string]string{"REQUEST_POOL": ""})
+		_, err := db.ExecContext(ctx, "SELECT 1")
+		require.ErrorIs(t, err, impala.ErrInvalidQueryLabel)
+	})
 }
 
 func testSet(t *testing.T, db *sql.DB, dsn string) {
@@ -336,6 +435,17 @@ func runErrorCases(t *testing.T, db *sql.DB) {
 		require.ErrorContains(t, err, "ImpalaRuntimeException")
 	})
 
+	t.Run("object not found", func(t *testing.T) {
+		var val any
+		err := db.QueryRow("SELECT * FROM no_such_table").Scan(&val)
+		require.ErrorIs(t, err, impala.ErrObjectNotFound)
+		require.ErrorContains(t, err, "no_such_table")
+
+		_, err = db.Exec("USE no_such_db")
+		require.ErrorIs(t, err, impala.ErrObjectNotFound)
+		require.ErrorContains(t, err, "no_such_db")
+	})
+
 	t.Run("Context Cancelled", func(t *testing.T) {
 
 		startTime := time.Now()
@@ -356,6 +466,25 @@ func runErrorCases(t *testing.T, db *sql.DB) {
 
 	})
 
+	t.Run("Canceler", func(t *testing.T) {
+		startTime := time.Now()
+		bkgCtx := context.Background()
+		conn, err := db.Conn(bkgCtx)
+		require.NoError(t, err)
+		defer fi.NoErrorF(conn.Close, t)
+		canceler, err := impala.NewCanceler(conn)
+		require.NoError(t, err)
+		go func() {
+			time.Sleep(1 * time.Second)
+			assert.NoError(t, canceler.Cancel(bkgCtx))
+		}()
+		_, err = conn.ExecContext(bkgCtx, "SELECT SLEEP(10000)")
+		require.Error(t, err) // Impala reports either cancelled or error state
+		require.Less(t, time.Since(startTime), 5*time.Second)
+		// the connection remains usable
+		require.NoError(t, conn.PingContext(bkgCtx))
+	})
+
 	t.Run("session expired", func(t *testing.T) {
 		bkgCtx := context.Background()
 		conn, err := db.Conn(bkgCtx)
@@ -407,6 +536,84 @@ func runImpala4SpecificTests(t *testing.T, dsn string) {
 		require.ErrorContains(t, err, "ClassNotFoundException")
 	})
 
+	t.Run("nosasl against a server that requires auth", func(t *testing.T) {
+		noSaslDsn, err := url.Parse(dsn)
+		require.NoError(t, err)
+		query := noSaslDsn.Query()
+		query.Set("auth", "nosasl")
+		noSaslDsn.RawQuery = query.Encode()
+		noSaslDb := fi.NoError(sql.Open("impala", noSaslDsn.String())).Require(t)
+		defer fi.NoErrorF(noSaslDb.Close, t)
+		err = noSaslDb.Ping()
+		require.ErrorIs(t, err, impala.ErrSASLRequired)
+	})
+
+	t.Run("spool results", func(t *testing.T) {
+		spoolDsn := fi.NoError(url.Parse(dsn)).Require(t)
+		query := spoolDsn.Query()
+		query.Set("spool-results", "true")
+		spoolDsn.RawQuery = query.Encode()
+		spoolDb := fi.NoError(sql.Open("impala", spoolDsn.String())).Require(t)
+		defer fi.NoErrorF(spoolDb.Close, t)
+
+		conn := fi.NoError(spoolDb.Conn(context.Background())).Require(t)
+		defer fi.NoErrorF(conn.Close, t)
+		_, err := conn.ExecContext(context.Background(), `SET FETCH_ROWS_TIMEOUT_MS="500"`)
+		require.NoError(t, err)
+
+		// The first rows of a large generated result arrive long before the query could produce all of them.
+		// The sleep per row makes the query take far longer than the test allows if rows were not streamed.
+		digits := "(VALUES (0 AS d), (1), (2), (3), (4), (5), (6), (7), (8), (9))"
+		startTime := time.Now()
+		rows := fi.NoError(conn.QueryContext(context.Background(),
+			"SELECT a.d, sleep(10) FROM "+digits+" a CROSS JOIN "+digits+" b CROSS JOIN "+digits+" c "+
+				"CROSS JOIN "+digits+" e")).Require(t)
+		defer fi.NoErrorF(rows.Close, t)
+		require.True(t, rows.Next())
+		require.Less(t, time.Since(startTime), 10*time.Second)
+	})
+
+	t.Run("rewind", func(t *testing.T) {
+		cacheDsn := fi.NoError(url.Parse(dsn)).Require(t)
+		query := cacheDsn.Query()
+		query.Set("result-cache-size", "100")
+		cacheDsn.RawQuery = query.Encode()
+		cacheDb := fi.NoError(sql.Open("impala", cacheDsn.String())).Require(t)
+		defer fi.NoErrorF(cacheDb.Close, t)
+
+		conn := fi.NoError(cacheDb.Conn(context.Background())).Require(t)
+		defer fi.NoErrorF(conn.Close, t)
+		err := conn.Raw(func(driverConn any) error {
+			rows, err := driverConn.(driver.QueryerContext).QueryContext(context.Background(),
+				"SELECT * FROM (VALUES (1 AS x), (2)) v ORDER BY x", nil)
+			if err != nil {
+				return err
+			}
+			defer fi.NoErrorF(rows.Close, t)
+			readAll := func() []driver.Value {
+				var res []driver.Value
+				dest := make([]driver.Value, 1)
+				for rows.Next(dest) == nil {
+					res = append(res, dest[0])
+				}
+				return res
+			}
+			require.Equal(t, []driver.Value{int8(1), int8(2)}, readAll())
+			require.NoError(t, rows.(impala.Rewinder).Rewind())
+			require.Equal(t, []driver.Value{int8(1), int8(2)}, readAll())
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("auth mechanism", func(t *testing.T) {
+		conn := fi.NoError(db.Conn(context.Background())).Require(t)
+		defer fi.NoErrorF(conn.Close, t)
+		mech, err := impala.AuthMechanism(conn)
+		require.NoError(t, err)
+		require.Equal(t, impala.AuthMechanismPlain, mech)
+	})
+
 	t.Run("built-in certs", func(t *testing.T) {
 		noCertsDsnUrl, err := url.Parse(dsn)
 		require.NoError(t, err)
@@ -416,7 +623,7 @@ func runImpala4SpecificTests(t *testing.T, dsn string) {
 		builtInCertsDb := fi.NoError(sql.Open("impala", noCertsDsnUrl.String())).Require(t)
 		defer fi.NoErrorF(builtInCertsDb.Close, t)
 		err = builtInCertsDb.Ping()
-		require.Falsef(t, strings.Contains(err.Error(), "bad dsn"), "actual dsn: %s", err)
+		require.NotErrorIs(t, err, impala.ErrBadDSN)
 		require.ErrorContains(t, err, "using system root CAs")
 		expectedErrorType := &tls.CertificateVerificationError{}
 		require.ErrorAs(t, err, &expectedErrorType)
@@ -478,6 +685,40 @@ func startImpala4(t *testing.T) string {
 
 func testPinger(t *testing.T, db *sql.DB) {
 	require.NoError(t, db.Ping())
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	require.NoError(t, err)
+	defer fi.NoErrorF(conn.Close, t)
+	latency, err := impala.PingWithLatency(ctx, conn)
+	require.NoError(t, err)
+	require.Positive(t, latency)
+}
+
+func testExplain(t *testing.T, db *sql.DB) {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	require.NoError(t, err)
+	defer fi.NoErrorF(conn.Close, t)
+
+	plan, err := impala.Explain(ctx, conn, "SELECT 1")
+	require.NoError(t, err)
+	require.Contains(t, plan, "UNION")
+
+	verbosePlan, err := impala.ExplainWithLevel(ctx, conn, "SELECT 1", impala.ExplainVerbose)
+	require.NoError(t, err)
+	require.Greater(t, len(verbosePlan), len(plan))
+
+	// the level is not kept in the session
+	samePlan, err := impala.Explain(ctx, conn, "SELECT 1")
+	require.NoError(t, err)
+	require.Equal(t, plan, samePlan)
+
+	require.NoError(t, impala.Validate(ctx, conn, "SELECT 1"))
+	err = impala.Validate(ctx, conn, "SELECT * FROM no_such_table")
+	require.ErrorIs(t, err, impala.ErrObjectNotFound)
+	err = impala.Validate(ctx, conn, "SELEC 1")
+	require.ErrorContains(t, err, "ParseException")
 }
 
 func testDecimal(t *testing.T, db *sql.DB) {
@@ -601,16 +842,44 @@ func testMetadata(t *testing.T, conn *sql.DB) {
 			return tbl.Name == "test" && tbl.Schema == "default" && tbl.Type == "TABLE"
 		}))
 	})
+	t.Run("Tables with literal names", func(t *testing.T) {
+		literal := m.WithLiteralNames()
+		res, err := literal.GetTables(context.Background(), "default", "test")
+		require.NoError(t, err)
+		require.Contains(t, res, impala.TableName{Schema: "default", Name: "test", Type: "TABLE"})
+		res, err = literal.GetTables(context.Background(), "default", "tes%")
+		require.NoError(t, err)
+		require.Empty(t, res)
+	})
+	t.Run("Sorted results", func(t *testing.T) {
+		sorted := m.WithSortedResults()
+		tables, err := sorted.GetTables(context.Background(), "%", "%")
+		require.NoError(t, err)
+		require.True(t, slices.IsSortedFunc(tables, func(a, b impala.TableName) int {
+			return cmp.Or(cmp.Compare(a.Schema, b.Schema), cmp.Compare(a.Name, b.Name))
+		}))
+		schemas, err := sorted.GetSchemas(context.Background(), "%")
+		require.NoError(t, err)
+		require.True(t, slices.IsSorted(schemas))
+	})
 	t.Run("Schemas", func(t *testing.T) {
 		res, err := m.GetSchemas(context.Background(), "defaul%")
 		require.NoError(t, err)
 		require.Contains(t, res, "default")
 	})
+	t.Run("TypeInfo", func(t *testing.T) {
+		res, err := m.GetTypeInfo(context.Background())
+		require.NoError(t, err)
+		require.True(t, slices.ContainsFunc(res, func(ti impala.TypeInfo) bool {
+			return ti.Name == "BIGINT"
+		}))
+	})
 	t.Run("Columns", func(t *testing.T) {
 		res, err := m.GetColumns(context.Background(), "defaul%", "tes%", "%")
 		require.NoError(t, err)
 		require.True(t, slices.ContainsFunc(res, func(tbl impala.ColumnName) bool {
-			return tbl.TableName == "test" && tbl.Schema == "default" && tbl.ColumnName == "a"
+			return tbl.TableName == "test" && tbl.Schema == "default" && tbl.ColumnName == "a" &&
+				tbl.DatabaseTypeName != "" && tbl.Position > 0
 		}))
 	})
 }
@@ -647,6 +916,56 @@ func testInsert(t *testing.T, conn *sql.DB) {
 		require.NoError(t, st.Close()) // close is no-op anyway
 	})
 
+	t.Run("typed parameters", func(t *testing.T) {
+		_, err := conn.Exec("DROP TABLE IF EXISTS test_typed")
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			_, err := conn.Exec("DROP TABLE IF EXISTS test_typed")
+			require.NoError(t, err)
+		})
+		_, err = conn.Exec("CREATE TABLE test_typed(ts timestamp, d date, amount decimal(10,2), name varchar(10))")
+		require.NoError(t, err)
+		ct, err := impala.NewMetadata(conn).GetColumnTypes(context.Background(), "default", "test_typed")
+		require.NoError(t, err)
+		day := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+		// without the casts, Impala rejects the DOUBLE and STRING values for a possible loss of precision
+		_, err = conn.Exec("INSERT INTO test_typed VALUES (?, ?, ?, ?)",
+			ct.Arg("ts", now), ct.Arg("d", day), ct.Arg("amount", 12.5), ct.Arg("name", "abc"))
+		require.NoError(t, err)
+		var d, amount, name string
+		require.NoError(t, conn.QueryRow("SELECT d, amount, name FROM test_typed").Scan(&d, &amount, &name))
+		require.Equal(t, "2024-01-02", d)
+		require.Equal(t, "12.50", amount)
+		require.Equal(t, "abc", name)
+
+		_, err = impala.NewMetadata(conn).GetColumnTypes(context.Background(), "default", "test_missing")
+		require.ErrorIs(t, err, impala.ErrObjectNotFound)
+	})
+
+	t.Run("CTAS rows affected", func(t *testing.T) {
+		_, err := conn.Exec("DROP TABLE IF EXISTS test_ctas")
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			_, err := conn.Exec("DROP TABLE IF EXISTS test_ctas")
+			require.NoError(t, err)
+		})
+		res, err := conn.Exec("CREATE TABLE test_ctas AS SELECT * FROM (VALUES (1 AS a), (2), (3)) t")
+		require.NoError(t, err)
+		rowsAdded, err := res.RowsAffected()
+		require.NoError(t, err)
+		require.Equal(t, int64(3), rowsAdded)
+	})
+
+	t.Run("statement log", func(t *testing.T) {
+		sqlConn := fi.NoError(conn.Conn(context.Background())).Require(t)
+		defer fi.NoErrorF(sqlConn.Close, t)
+		_, err := sqlConn.ExecContext(context.Background(), "INSERT INTO test (a) VALUES ('log')")
+		require.NoError(t, err)
+		// the log is available only with statement-log
+		_, err = impala.LastStatementLog(sqlConn)
+		require.ErrorContains(t, err, "statement-log")
+	})
+
 	t.Run("cancel DML from Query", func(t *testing.T) {
 		startTime := time.Now()
 		dmlRes, err := conn.Query("INSERT INTO test (a) VALUES (cast(SLEEP(10000) as string))")
@@ -854,11 +1173,13 @@ func getDsn(ctx context.Context, t *testing.T, c testcontainers.Container, useri
 	port := fi.NoError(c.MappedPort(ctx, dbPort)).Require(t).Port()
 	host := fi.NoError(c.Host(ctx)).Require(t)
 
-	u := &url.URL{
-		Scheme:   "impala",
-		Host:     net.JoinHostPort(host, port),
-		User:     userinfo,
-		RawQuery: "log=stderr",
+	password, _ := userinfo.Password()
+	dsn := impala.DSN{
+		Host:     host,
+		Port:     port,
+		Username: userinfo.Username(),
+		Password: password,
+		Params:   url.Values{"log": {"stderr"}},
 	}
-	return u.String()
+	return dsn.String()
 }
@@ -0,0 +1,69 @@
+package impala
+
+import (
+	"context"
+	"io"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/samber/lo"
+	"github.com/sclgo/impala-go/internal/hive"
+	"github.com/sclgo/impala-go/internal/isql"
+)
+
+// HiveClient is a low-level client for the HiveServer2 API of Impala, for advanced use cases where
+// the caller opens and authenticates the Thrift transport itself. Most users should use database/sql instead.
+//
+// HiveClient implements ConnRawAccess, so it can be used with the helpers in this package that accept it,
+// like NewMetadataFromConn and Explain. HiveClient must not be used concurrently by multiple goroutines.
+type HiveClient struct {
+	conn *isql.Conn
+}
+
+var _ ConnRawAccess = (*HiveClient)(nil)
+
+// NewHiveClient creates a HiveClient that communicates over transport with the Thrift binary protocol.
+// transport must be open and already authenticated, if the server requires it e.g. wrapped in SASL.
+// Only the fields of opts that configure sessions and queries, like MemoryLimit, BatchSize, and LogOut, are used.
+// Connection fields, like Host and UseTLS, are ignored.
+func NewHiveClient(transport thrift.TTransport, opts *Options) *HiveClient {
+	if opts.LogOut == nil {
+		opts.LogOut = io.Discard
+	}
+	conf := &thrift.TConfiguration{
+		TBinaryStrictRead:  lo.ToPtr(false),
+		TBinaryStrictWrite: lo.ToPtr(true),
+	}
+	protocol := thrift.NewTBinaryProtocolConf(transport, conf)
+	tclient := thrift.NewTStandardClient(protocol, protocol)
+	logger := newLogger(opts)
+	client := hive.NewClient(tclient, logger, hiveOptions(opts))
+	return &HiveClient{
+		conn: isql.NewConn(client, transport, logger, isql.Options{
+			ReuseSession:  true,
+			AuthMechanism: authMechanism(transport),
+		}),
+	}
+}
+
+// OpenSession opens a session on the server, if one is not open already.
+// Other methods open the session on demand so calling OpenSession is optional. It is useful to validate
+// the transport and the session options early.
+func (c *HiveClient) OpenSession(ctx context.Context) error {
+	_, err := c.conn.OpenSession(ctx)
+	return err
+}
+
+// Metadata returns a Metadata instance that uses this client
+func (c *HiveClient) Metadata() *Metadata {
+	return NewMetadataFromConn(c)
+}
+
+// Raw implements ConnRawAccess. It calls f with the driver connection, which uses this client.
+func (c *HiveClient) Raw(f func(driverConn any) error) error {
+	return f(c.conn)
+}
+
+// Close closes the session, if open, and the transport
+func (c *HiveClient) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,99 @@
+package impala
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/sclgo/impala-go/internal/generated/cli_service"
+	"github.com/sclgo/impala-go/internal/generated/impalaservice"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHiveClient(t *testing.T) {
+	handler := &schemasHandler{}
+	addr := startThriftServer(t, impalaservice.NewImpalaHiveServer2ServiceProcessor(handler))
+
+	transport := thrift.NewTSocketConf(addr, nil)
+	require.NoError(t, transport.Open())
+	client := NewHiveClient(transport, &Options{MemoryLimit: "1g"})
+
+	ctx := context.Background()
+	require.NoError(t, client.OpenSession(ctx))
+	require.Equal(t, "1g", handler.sessionConf["MEM_LIMIT"])
+
+	schemas, err := client.Metadata().GetSchemas(ctx, "%")
+	require.NoError(t, err)
+	require.Equal(t, []string{"default"}, schemas)
+
+	require.NoError(t, client.Close())
+	require.True(t, handler.sessionClosed)
+}
+
+func startThriftServer(t *testing.T, processor thrift.TProcessor) string {
+	serverSocket, err := thrift.NewTServerSocket("127.0.0.1:0")
+	require.NoError(t, err)
+	require.NoError(t, serverSocket.Listen())
+	server := thrift.NewTSimpleServer4(processor, serverSocket, thrift.NewTTransportFactory(), thrift.NewTBinaryProtocolFactoryConf(nil))
+	go func() {
+		_ = server.Serve()
+	}()
+	t.Cleanup(func() {
+		_ = server.Stop()
+	})
+	return serverSocket.Addr().String()
+}
+
+// schemasHandler is a minimal Impala server, which supports listing schemas
+type schemasHandler struct {
+	impalaservice.ImpalaHiveServer2Service
+
+	sessionConf   map[string]string
+	sessionClosed bool
+}
+
+var (
+	successStatus = &cli_service.TStatus{StatusCode: cli_service.TStatusCode_SUCCESS_STATUS}
+	testHandle    = &cli_service.THandleIdentifier{GUID: make([]byte, 16), Secret: make([]byte, 16)}
+)
+
+func (h *schemasHandler) OpenSession(_ context.Context, req *cli_service.TOpenSessionReq) (*cli_service.TOpenSessionResp, error) {
+	h.sessionConf = req.Configuration
+	return &cli_service.TOpenSessionResp{
+		Status:                successStatus,
+		ServerProtocolVersion: cli_service.TProtocolVersion_HIVE_CLI_SERVICE_PROTOCOL_V7,
+		SessionHandle:         &cli_service.TSessionHandle{SessionId: testHandle},
+	}, nil
+}
+
+func (h *schemasHandler) CloseSession(context.Context, *cli_service.TCloseSessionReq) (*cli_service.TCloseSessionResp, error) {
+	h.sessionClosed = true
+	return &cli_service.TCloseSessionResp{Status: successStatus}, nil
+}
+
+func (h *schemasHandler) GetSchemas(context.Context, *cli_service.TGetSchemasReq) (*cli_service.TGetSchemasResp, error) {
+	return &cli_service.TGetSchemasResp{
+		Status:          successStatus,
+		OperationHandle: &cli_service.TOperationHandle{OperationId: testHandle, HasResultSet: true},
+	}, nil
+}
+
+func (h *schemasHandler) FetchResults(context.Context, *cli_service.TFetchResultsReq) (*cli_service.TFetchResultsResp, error) {
+	return &cli_service.TFetchResultsResp{
+		Status: successStatus,
+		Results: &cli_service.TRowSet{
+			Rows: []*cli_service.TRow{},
+			Columns: []*cli_service.TColumn{
+				{StringVal: &cli_service.TStringColumn{Values: []string{"default"}, Nulls: []byte{0}}},
+			},
+		},
+	}, nil
+}
+
+func (h *schemasHandler) PingImpalaHS2Service(context.Context, *impalaservice.TPingImpalaHS2ServiceReq) (*impalaservice.TPingImpalaHS2ServiceResp, error) {
+	return &impalaservice.TPingImpalaHS2ServiceResp{Status: successStatus}, nil
+}
+
+func (h *schemasHandler) CloseImpalaOperation(context.Context, *impalaservice.TCloseImpalaOperationReq) (*impalaservice.TCloseImpalaOperationResp, error) {
+	return &impalaservice.TCloseImpalaOperationResp{Status: successStatus}, nil
+}
@@ -0,0 +1,170 @@
+package impala
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"maps"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"runtime/debug"
+	"strings"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/samber/lo"
+)
+
+// Transport modes for Options.Transport
+const (
+	// TransportBinary is the HiveServer2 Thrift binary protocol over TCP. This is the default.
+	TransportBinary = "binary"
+	// TransportHTTP is the HiveServer2 Thrift binary protocol over HTTP or, if TLS is enabled, HTTPS
+	TransportHTTP = "http"
+	// TransportUnix is the HiveServer2 Thrift binary protocol over the unix domain socket at Options.SocketPath
+	TransportUnix = "unix"
+)
+
+const (
+	defaultHTTPPort = "28000"
+	defaultHTTPPath = "cliservice"
+)
+
+// openHTTPTransport creates a transport for the Impala HTTP endpoint. Unlike TCP transports, the HTTP transport
+// connects lazily so connection errors are reported when the session is opened.
+func openHTTPTransport(opts *Options, hostPort string) (thrift.TTransport, error) {
+	scheme := "http"
+	var tlsConfig *tls.Config
+	if opts.UseTLS {
+		scheme = "https"
+		var err error
+		tlsConfig, err = getTLSConfig(opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	proxy := http.ProxyFromEnvironment
+	if opts.HTTPProxy != "" {
+		proxyURL, err := url.Parse(opts.HTTPProxy)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid http-proxy: %w", ErrBadDSN, err)
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	dialer := &net.Dialer{Timeout: opts.ConnectTimeout}
+	dial := dialer.DialContext
+	if opts.DialContext != nil {
+		dial = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			ctx, cancel := withConnectTimeout(ctx, opts)
+			defer cancel()
+			return opts.DialContext(ctx, network, addr)
+		}
+	}
+
+	// cookiejar.New never returns an error
+	jar, _ := cookiejar.New(nil)
+	httpClient := &http.Client{
+		Transport: &headerTransport{
+			base: &http.Transport{
+				Proxy:           proxy,
+				DialContext:     dial,
+				TLSClientConfig: tlsConfig,
+			},
+			headers:   opts.HTTPHeaders,
+			userAgent: lo.CoalesceOrEmpty(opts.ClientIdentifier, defaultUserAgent()),
+		},
+		// Impala returns an auth. cookie so credentials are not validated on every request
+		Jar: jar,
+	}
+
+	endpoint := url.URL{
+		Scheme: scheme,
+		Host:   hostPort,
+		Path:   "/" + strings.TrimPrefix(opts.HTTPPath, "/"),
+	}
+	if opts.HTTPPath == "" {
+		endpoint.Path = "/" + defaultHTTPPath
+	}
+
+	transport, err := thrift.NewTHttpClientWithOptions(endpoint.String(), thrift.THttpClientOptions{
+		Client: httpClient,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrBadDSN, err)
+	}
+	httpTransport := transport.(*thrift.THttpClient) // type guaranteed by NewTHttpClientWithOptions implementation
+
+	if opts.UseLDAP {
+		if opts.Username == "" {
+			return nil, fmt.Errorf("%w: provide username for LDAP auth", ErrBadDSN)
+		}
+		credentials := base64.StdEncoding.EncodeToString([]byte(opts.Username + ":" + opts.Password))
+		httpTransport.SetHeader("Authorization", "Basic "+credentials)
+	}
+
+	return httpTransport, nil
+}
+
+type httpHeadersKey struct{}
+
+// WithHTTPHeaders returns a context that adds the given headers to the HTTP requests to Impala, made with
+// this context, on connections that use TransportHTTP. Headers in the context override Options.HTTPHeaders with
+// the same name. The context is used for requests made by the driver methods that take it as a parameter
+// e.g. QueryContext. Headers from previous WithHTTPHeaders calls on the parent context are kept.
+func WithHTTPHeaders(ctx context.Context, headers map[string]string) context.Context {
+	merged := maps.Clone(httpHeadersFromContext(ctx))
+	if merged == nil {
+		merged = make(map[string]string, len(headers))
+	}
+	maps.Copy(merged, headers)
+	return context.WithValue(ctx, httpHeadersKey{}, merged)
+}
+
+func httpHeadersFromContext(ctx context.Context) map[string]string {
+	headers, _ := ctx.Value(httpHeadersKey{}).(map[string]string)
+	return headers
+}
+
+// headerTransport adds static headers and headers from the request context to each request
+type headerTransport struct {
+	base      http.RoundTripper
+	headers   map[string]string
+	userAgent string
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctxHeaders := httpHeadersFromContext(req.Context())
+	// RoundTripper must not modify the request
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", t.userAgent)
+	for name, value := range t.headers {
+		req.Header.Set(name, value)
+	}
+	for name, value := range ctxHeaders {
+		req.Header.Set(name, value)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// defaultUserAgent returns impala-go/<version> where version is the version of this module in the build
+func defaultUserAgent() string {
+	version := "unknown"
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if info.Main.Path == modulePath {
+			version = info.Main.Version
+		}
+		for _, dep := range info.Deps {
+			if dep.Path == modulePath {
+				version = dep.Version
+			}
+		}
+	}
+	return "impala-go/" + version
+}
+
+const modulePath = "github.com/sclgo/impala-go"
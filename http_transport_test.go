@@ -0,0 +1,75 @@
+package impala
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPTransport_Proxy(t *testing.T) {
+	requests := make(chan *http.Request, 1)
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case requests <- r:
+		default:
+		}
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer proxy.Close()
+
+	opts := &Options{
+		Host:      "impala.internal",
+		Port:      defaultHTTPPort,
+		Transport: TransportHTTP,
+		HTTPProxy: proxy.URL,
+		UseLDAP:   true,
+		Username:  "fry",
+		Password:  "secret",
+		HTTPHeaders: map[string]string{
+			"X-Impala-Pool":    "pool1",
+			"X-Correlation-Id": "static",
+		},
+	}
+	conn, err := connect(context.Background(), opts)
+	require.NoError(t, err)
+	ctx := WithHTTPHeaders(context.Background(), map[string]string{"X-Correlation-Id": "dynamic"})
+	_, err = conn.OpenSession(ctx)
+	require.ErrorContains(t, err, "502")
+
+	req := <-requests
+	require.Equal(t, "http://impala.internal:28000/cliservice", req.URL.String())
+	user, pass, ok := req.BasicAuth()
+	require.True(t, ok)
+	require.Equal(t, "fry", user)
+	require.Equal(t, "secret", pass)
+	require.Equal(t, "pool1", req.Header.Get("X-Impala-Pool"))
+	require.Equal(t, "dynamic", req.Header.Get("X-Correlation-Id"))
+	require.True(t, strings.HasPrefix(req.Header.Get("User-Agent"), "impala-go/"))
+}
+
+func TestWithHTTPHeaders(t *testing.T) {
+	ctx := WithHTTPHeaders(context.Background(), map[string]string{"A": "1", "B": "1"})
+	child := WithHTTPHeaders(ctx, map[string]string{"B": "2"})
+	require.Equal(t, map[string]string{"A": "1", "B": "2"}, httpHeadersFromContext(child))
+	require.Equal(t, map[string]string{"A": "1", "B": "1"}, httpHeadersFromContext(ctx))
+}
+
+func TestHTTPTransport_Negative(t *testing.T) {
+	t.Run("ldap without username", func(t *testing.T) {
+		_, err := connect(context.Background(), &Options{Transport: TransportHTTP, UseLDAP: true})
+		require.ErrorIs(t, err, ErrBadDSN)
+	})
+	t.Run("invalid proxy", func(t *testing.T) {
+		_, err := connect(context.Background(), &Options{Transport: TransportHTTP, HTTPProxy: "://"})
+		require.ErrorIs(t, err, ErrBadDSN)
+		require.ErrorContains(t, err, "http-proxy")
+	})
+	t.Run("invalid transport", func(t *testing.T) {
+		_, err := connect(context.Background(), &Options{Transport: "carrier-pigeon"})
+		require.ErrorIs(t, err, ErrBadDSN)
+	})
+}
@@ -0,0 +1,60 @@
+package impala
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/sclgo/impala-go/internal/isql"
+)
+
+// ErrInvalidIdentifier means that a name can't be used as a database, table or column name
+var ErrInvalidIdentifier = errors.New("impala: invalid identifier")
+
+// maxIdentifierLength is the maximum length of database, table and column names in Impala
+const maxIdentifierLength = 128
+
+// objectNameRegex matches the names, which the Hive Metastore accepts for databases and tables
+var objectNameRegex = regexp.MustCompile(`^\w+$`)
+
+// QuoteIdentifier encloses name in backticks, so it can be used in SQL even if it is a reserved word,
+// e.g. `default`, or contains special characters. Backslashes and backticks in name are escaped, so the result
+// is always a single identifier. Note that Impala doesn't allow such characters in database, table or column names.
+func QuoteIdentifier(name string) string {
+	return isql.QuoteIdentifier(name)
+}
+
+// QuoteString returns value as an Impala string literal, enclosed in single quotes, with backslashes and single
+// quotes escaped. The driver quotes string arguments of statements, e.g. in ExecContext, the same way.
+func QuoteString(value string) string {
+	return isql.QuoteString(value)
+}
+
+// validateObjectName checks that name is a valid database or table name
+func validateObjectName(name string) error {
+	if !objectNameRegex.MatchString(name) || len(name) > maxIdentifierLength {
+		return fmt.Errorf("%w: %q: names must have 1 to %d letters, digits or underscores",
+			ErrInvalidIdentifier, name, maxIdentifierLength)
+	}
+	return nil
+}
+
+// UseDatabase makes name the current database of the session on conn, like USE. The name is validated and
+// quoted, so it may be a reserved word. Invalid names fail with ErrInvalidIdentifier without reaching the server.
+// The current database is reset with the session, unless ReuseSession is enabled, so UseDatabase is most
+// useful with a *sql.Conn, which implements ConnRawAccess. To use a database on all connections of a sql.DB,
+// configure the init-sql parameter instead.
+func UseDatabase(ctx context.Context, conn ConnRawAccess, name string) error {
+	if err := validateObjectName(name); err != nil {
+		return err
+	}
+	return conn.Raw(func(driverConn any) error {
+		impalaConn, ok := driverConn.(*isql.Conn)
+		if !ok {
+			return errors.New("database can be selected only with Impala drivers")
+		}
+		_, err := impalaConn.ExecContext(ctx, "USE "+QuoteIdentifier(name), nil)
+		return err
+	})
+}
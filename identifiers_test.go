@@ -0,0 +1,44 @@
+package impala
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sclgo/impala-go/impalatest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUseDatabase(t *testing.T) {
+	srv := impalatest.Start(t)
+	db := srv.OpenDB(t)
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, conn.Close())
+	}()
+
+	require.NoError(t, UseDatabase(ctx, conn, "analytics_2024"))
+	require.NoError(t, UseDatabase(ctx, conn, "default"))
+	for _, name := range []string{"", "a b", "a`b", "db; DROP TABLE t", "a.b", strings.Repeat("a", 129)} {
+		require.ErrorIs(t, UseDatabase(ctx, conn, name), ErrInvalidIdentifier, name)
+	}
+	require.Equal(t, []string{"USE `analytics_2024`", "USE `default`"}, srv.Statements())
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	require.Equal(t, "`t`", QuoteIdentifier("t"))
+	require.Equal(t, "`select`", QuoteIdentifier("select"))
+	require.Equal(t, "`my table`", QuoteIdentifier("my table"))
+	require.Equal(t, "`a\\`b`", QuoteIdentifier("a`b"))
+	require.Equal(t, "`a\\\\`", QuoteIdentifier("a\\"))
+}
+
+func TestQuoteString(t *testing.T) {
+	require.Equal(t, "'abc'", QuoteString("abc"))
+	require.Equal(t, "''", QuoteString(""))
+	require.Equal(t, `'it\'s'`, QuoteString("it's"))
+	require.Equal(t, `'C:\\temp\\'`, QuoteString(`C:\temp\`))
+	require.Equal(t, "'`select`'", QuoteString("`select`"))
+}
@@ -1,9 +1,14 @@
 package impala
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
 	"io"
+	"net"
 	"time"
+
+	"github.com/sclgo/impala-go/internal/hive"
 )
 
 func init() {
@@ -20,6 +25,12 @@ type Options struct {
 	Port     string
 	Username string
 	Password string
+	// PasswordFile is the path of a file that contains the password, if Password is empty.
+	// The file is read on every connect. Trailing line breaks are removed.
+	PasswordFile string
+	// PasswordEnv is the name of an environment variable that contains the password,
+	// if both Password and PasswordFile are empty. The variable is read on every connect.
+	PasswordEnv string
 
 	// ReuseSession disables resetting the session when database/sql SPI requests it.
 	// The connection and session will still be validated. database/sql asks to reset the session
@@ -33,26 +44,166 @@ type Options struct {
 	// `usql` returns the connection to the pool after each statement, relying on the typical driver behavior.
 	ReuseSession bool
 
+	// ReadOnly rejects statements other than queries with ErrReadOnly before they reach the server.
+	// The driver checks the leading keyword of each statement, ignoring comments. SELECT, VALUES, WITH, SHOW,
+	// DESCRIBE and EXPLAIN are allowed, as are USE and SET, which change only the session.
+	// This is a guardrail against mistakes, not a security boundary - use Impala authorization (Ranger)
+	// to enforce read-only access.
+	ReadOnly bool
+
 	UseLDAP bool
+	// SASLTimeout limits the duration of the SASL negotiation, when UseLDAP is enabled, in addition to
+	// the deadline of the context passed to Connect. 0 means no limit other than the context.
+	SASLTimeout time.Duration
 
 	UseTLS     bool
 	CACertPath string
 
 	// TlsInsecureSkipVerify configures the tls.Config InsecureSkipVerify flag for
 	// a TLS connection to Impala. Behaves the same way as AllowSelfSignedCerts in the official JDBC driver.
+	// This is unsafe and meant only for testing. When enabled, a warning is logged to LogOut once per process.
 	TLSInsecureSkipVerify bool
 
+	// TLSCertPin is the hex SHA-256 fingerprint of the server certificate. Bytes may be separated by colons.
+	// When set, connections to servers with a different leaf certificate fail with ErrCertPinMismatch.
+	// If CACertPath is empty, the pin replaces CA and host name verification. Otherwise, both are checked.
+	TLSCertPin string
+
+	// TLSServerName is the name used for SNI and for verifying the server certificate, if different from Host,
+	// e.g. when Host is the address of a load balancer. Empty means Host.
+	TLSServerName string
+
+	// TLSMinVersion is the minimum TLS version, e.g. tls.VersionTLS13. 0 means tls.VersionTLS12.
+	TLSMinVersion uint16
+	// TLSCipherSuites restricts the cipher suites for TLS 1.0-1.2 - see tls.Config.CipherSuites.
+	// TLS 1.3 cipher suites are not configurable. nil means the Go defaults.
+	TLSCipherSuites []uint16
+
 	BufferSize int
 	BatchSize  int
 
+	// BatchBytes, if positive, enables adaptive batch sizes: the number of rows fetched per request is computed
+	// for each query result from its columns, so that a batch takes about BatchBytes bytes, instead of BatchSize.
+	// Wide results are then fetched in smaller batches, and narrow results in larger ones, up to 65536 rows.
+	// The size of variable-length values is estimated, e.g. STRING values as 64 bytes.
+	BatchBytes int64
+
 	// MemoryLimit configures the MEM_LIMIT Impala property for the connection
 	// https://impala.apache.org/docs/build/html/topics/impala_mem_limit.html
 	MemoryLimit string
 	// QueryTimeout in seconds - for QUERY_TIMEOUT_S session configuration value
 	// https://impala.apache.org/docs/build/html/topics/impala_query_timeout_s.html
 	QueryTimeout int
+	// FetchMaxWait bounds how long reading rows waits while the server keeps reporting that the results are
+	// not ready yet, if the context has no deadline. When exceeded, the query is cancelled and reading fails with
+	// ErrFetchTimeout. 0 or negative value means no limit.
+	FetchMaxWait time.Duration
+
+	// MaxRowsReturned limits the number of rows returned for a single query result.
+	// When the result has more rows, reading the next row fails with ErrRowLimitExceeded and the query is cancelled
+	// on the server. This is a client-side safety valve, independent of the server-side limits in Impala.
+	// 0 or negative value means no limit.
+	MaxRowsReturned int64
+
+	// SessionConfig configures arbitrary Impala query options for the connection at session level, e.g. MT_DOP.
+	// Keys are case-insensitive. Dedicated options, e.g. MemoryLimit and RequestPool, take precedence if they are set.
+	// Unlike InitSQL, the options are sent with the request that opens the session.
+	// https://impala.apache.org/docs/build/html/topics/impala_query_options.html
+	// SessionConfig can't be configured with a DSN.
+	SessionConfig map[string]string
+
+	// RequestPool selects the admission control pool for all queries on the connection by configuring the
+	// REQUEST_POOL Impala property at session level, if not empty.
+	// https://impala.apache.org/docs/build/html/topics/impala_request_pool.html
+	RequestPool string
+
+	// Timezone configures the TIMEZONE Impala property at session level, if not empty. It is a server-side setting:
+	// it changes the results of timestamp functions like now() and from_unixtime(). TIMESTAMP values have no zone
+	// in Impala, and the driver still returns them as time.Time values in UTC.
+	// https://impala.apache.org/docs/build/html/topics/impala_timezone.html
+	Timezone string
+
+	// SpoolResults enables result spooling by configuring the SPOOL_QUERY_RESULTS Impala property at session level.
+	// With spooling, Impala buffers the results of running queries so the client can fetch rows before
+	// the query completes, and the query releases its resources sooner. Supported by Impala 4.x.
+	// https://impala.apache.org/docs/build/html/topics/impala_spool_query_results.html
+	SpoolResults bool
+
+	// ResultCacheSize, if positive, enables the Impala result cache for each query, which keeps up to this number
+	// of rows on the server, so that Rows can be rewound to the first row - see Rewinder.
+	// The cache uses memory on the coordinator, in addition to result spooling, which doesn't support rewinding alone.
+	ResultCacheSize int64
+
+	// MaxResultBytes limits the total size of the values fetched for a single query result.
+	// When the limit is exceeded, fetching rows fails with ErrResultSizeExceeded, instead of
+	// the client running out of memory. The size is approximate - it doesn't include protocol overhead.
+	// 0 or negative value means no limit.
+	MaxResultBytes int64
+
+	// MaxCellBytes limits the size of individual STRING, VARCHAR, and CHAR values, e.g. large JSON documents, so that
+	// the rows returned by a query don't keep many of them in memory. Larger values fail with ErrCellTooLarge, or
+	// are truncated to at most MaxCellBytes bytes, without splitting UTF-8 characters, if TruncateCells is enabled.
+	// The whole batch is still received from the server before the limit is checked, so configure a small BatchSize
+	// for results with many large values. 0 or negative value means no limit.
+	MaxCellBytes int
+
+	// TruncateCells truncates values larger than MaxCellBytes instead of failing with ErrCellTooLarge.
+	// Truncated values can't be told apart from values, which had exactly MaxCellBytes bytes.
+	TruncateCells bool
+
+	// DecimalAs selects the Go type of DECIMAL values and the matching ScanType:
+	// DecimalAsString (default if empty) for lossless round-tripping, DecimalAsFloat64, or DecimalAsRat for *big.Rat.
+	DecimalAs string
+
+	// DateAs selects the Go type of DATE values and the matching ScanType: DateAsString (default if empty) for
+	// strings like 2024-01-02, as sent by Impala, or DateAsTime for time.Time at midnight UTC.
+	DateAs string
+
+	// TypeNames selects the names returned by sql.ColumnType.DatabaseTypeName: TypeNamesThrift (default if empty)
+	// for the base type name e.g. DECIMAL, as recommended by database/sql, or TypeNamesSQL for the type as written in
+	// Impala DDL, including the length of CHAR and VARCHAR, and the precision and scale of DECIMAL e.g. DECIMAL(10,2).
+	// ValueConverters always match the base type name.
+	TypeNames string
+
+	// CharTrim enables removing the trailing spaces, which Impala adds to CHAR(n) values shorter than n.
+	// Disabled by default, so CHAR values are returned exactly as Impala reports them.
+	CharTrim bool
+
+	// NullAsZero returns NULL values of numeric and BOOLEAN columns as the zero value of the column type,
+	// e.g. int64(0) for BIGINT, "0" for DECIMAL with DecimalAsString, or false for BOOLEAN, instead of nil,
+	// so legacy code can scan them into int64 or float64 rather than sql.Null* types.
+	// This is lossy: NULL can't be told apart from zero. Disabled by default.
+	NullAsZero bool
+
+	// ResultChecksum enables computing a rolling hash of all values returned by each result set, e.g. so a data
+	// integrity test can compare the results of two runs. Use LastResultChecksum to get the hash after the rows are
+	// closed. This is a debugging and testing aid: it costs CPU time for every cell. Disabled by default.
+	ResultChecksum bool
+
+	// StatementLog enables getting the log, which Impala reports for each statement, before the statement is
+	// closed. Use LastStatementLog to get it. This costs a request per statement. Disabled by default.
+	StatementLog bool
+
+	// ComplexJSON selects the formatting of ARRAY, MAP, and STRUCT values, which Impala returns as JSON strings:
+	// ComplexJSONCompact for single-line JSON without insignificant whitespace, or ComplexJSONIndent for
+	// JSON indented with two spaces. Empty means the values are returned exactly as Impala sends them.
+	ComplexJSON string
+
+	// ValueConverters customize the values of result columns matched by name or type, e.g. to return
+	// time.Duration or application-specific types. The first matching converter applies. See ValueConverter.
+	// ValueConverters can't be configured with a DSN.
+	ValueConverters []ValueConverter
 
 	LogOut io.Writer
+	// LogLevel selects the messages written to LogOut: LogLevelError, LogLevelInfo (default if empty),
+	// or LogLevelDebug, which includes the statements and the fetched results.
+	// Values from statements and results are never logged at the other levels.
+	LogLevel string
+
+	// OnQueryEvent, if not nil, is called for each QueryEvent, regardless of LogOut and LogLevel.
+	// It is called synchronously by the goroutine that uses the connection so it should return quickly.
+	// OnQueryEvent can't be configured with a DSN.
+	OnQueryEvent func(QueryEvent)
 
 	// TCP transport configuration
 
@@ -63,10 +214,82 @@ type Options struct {
 
 	// ConnectTimeout configures the max wait for initial connection to server. 0 or negative value means no limit.
 	ConnectTimeout time.Duration
+
+	// MaxConcurrentOpens limits how many connections a connector opens at the same time, so the TLS and SASL
+	// handshakes of a pool ramp-up don't all hit the coordinator at once. Further opens wait for their turn.
+	// Unlike sql.DB.SetMaxOpenConns, it doesn't limit the connections that are already open.
+	// The limit is per connector - sql.Open and sql.OpenDB use one connector per sql.DB. Driver.Open isn't limited.
+	// 0 or negative value means no limit.
+	MaxConcurrentOpens int
+
+	// Transport selects how the driver communicates with Impala: TransportBinary (default if empty), TransportHTTP,
+	// or TransportUnix.
+	// With TransportHTTP, the default Impala port is 28000 instead of 21050. Port is not updated automatically
+	// when using Options directly, only when parsing a DSN.
+	Transport string
+
+	// SocketPath is the path of the unix domain socket of Impala, used only with TransportUnix. Port is ignored.
+	// The socket is dialed with DialContext, if set, with network "unix" and SocketPath as the address.
+	// TLS is typically unnecessary over a local socket.
+	SocketPath string
+
+	// HTTPPath is the URL path of the Impala HTTP endpoint. Default is "cliservice". Used only with TransportHTTP.
+	HTTPPath string
+
+	// HTTPProxy is the URL of an HTTP proxy, used only with TransportHTTP. If empty, the proxy is configured
+	// from the HTTP_PROXY, HTTPS_PROXY, and NO_PROXY environment variables (see http.ProxyFromEnvironment).
+	// If UseTLS is enabled, the connection is tunneled through the proxy with HTTP CONNECT so
+	// TLS is end-to-end between the driver and Impala and the proxy can't observe the traffic.
+	HTTPProxy string
+
+	// HTTPHeaders are added to every HTTP request to Impala, used only with TransportHTTP.
+	// Use WithHTTPHeaders to add headers to the requests for a specific context.
+	HTTPHeaders map[string]string
+
+	// ClientIdentifier identifies the application to Impala administrators. If set, it is sent as
+	// the CLIENT_IDENTIFIER query option when the session is opened, so it is shown in the query list and
+	// the query profiles in the Impala Web UI. CLIENT_IDENTIFIER is supported by Impala 4.x.
+	// With TransportHTTP, the value is also sent as the User-Agent header. If ClientIdentifier is empty,
+	// the User-Agent is impala-go/<version>.
+	ClientIdentifier string
+
+	// DialContext, if set, is used to open the network connection to the server instead of the default dialer
+	// e.g. to connect through an SSH tunnel or a SOCKS proxy. addr is Host and Port joined with net.JoinHostPort.
+	// If UseTLS is enabled, the TLS handshake is done over the connection returned by DialContext.
+	// ConnectTimeout applies to the context passed to DialContext.
+	// DialContext can't be configured with a DSN.
+	DialContext func(ctx context.Context, network, addr string) (net.Conn, error)
+
+	// InitSQL statements are executed in order each time the driver opens a session on a connection, before
+	// OnConnect, e.g. SET MT_DOP=4 or USE analytics. If a statement fails, the error is returned like when
+	// OnConnect fails. Each element is a single statement.
+	InitSQL []string
+
+	// OnConnect, if set, is called right after the driver opens a session on a connection, before the session
+	// is used, e.g. to run SET or USE statements, or to register functions, on every connection.
+	// conn is the driver connection, which implements driver.ExecerContext and driver.QueryerContext.
+	// If OnConnect fails, the session is closed and the statement, which opened it, fails with the error. The error
+	// doesn't have driver.ErrBadConn in the tree, unless OnConnect returned it, so database/sql doesn't retry the
+	// statement on other connections, which would fail the same way.
+	// Unless ReuseSession is enabled, database/sql asks the driver to reset the session when it reuses
+	// a connection, so OnConnect is called again for the new session.
+	// OnConnect can't be configured with a DSN.
+	OnConnect func(ctx context.Context, conn driver.Conn) error
 }
 
+// Supported values of Options.LogLevel
+const (
+	// LogLevelError logs only failures and warnings
+	LogLevelError = hive.LogLevelError
+	// LogLevelInfo also logs the lifecycle of sessions and operations. This is the default.
+	LogLevelInfo = hive.LogLevelInfo
+	// LogLevelDebug also logs details, like the statements and the fetched results, which are too verbose and
+	// sensitive for production
+	LogLevelDebug = hive.LogLevelDebug
+)
+
 func (o *Options) systemCAStoreSelected() bool {
-	return o.CACertPath == "" && !o.TLSInsecureSkipVerify
+	return o.CACertPath == "" && !o.TLSInsecureSkipVerify && o.TLSCertPin == ""
 }
 
 var (
@@ -0,0 +1,137 @@
+package impalatest
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/sclgo/impala-go/internal/generated/cli_service"
+	"github.com/sclgo/impala-go/internal/hive"
+)
+
+// ColumnsRequest contains the patterns of a GetColumns request, as sent by the client
+type ColumnsRequest struct {
+	Schema string
+	Table  string
+	Column string
+}
+
+type table struct {
+	schema  string
+	name    string
+	columns []Column
+}
+
+// AddTable registers a table, whose columns are returned by GetColumns requests with matching patterns
+// e.g. for impala.Metadata.GetColumns. Patterns are matched like LIKE in Impala, ignoring case, with \ escaping
+// the wildcards % and _. Column types must be type names without qualifiers e.g. DECIMAL, not DECIMAL(10,2).
+func (s *Server) AddTable(schema string, name string, columns ...Column) error {
+	for _, c := range columns {
+		if _, err := hive.NewColDesc(c.Name, c.Type); err != nil {
+			return err
+		}
+	}
+	s.handler.mu.Lock()
+	defer s.handler.mu.Unlock()
+	s.handler.tables = append(s.handler.tables, table{schema: schema, name: name, columns: columns})
+	return nil
+}
+
+// ColumnsRequests returns the patterns of the GetColumns requests received so far, in order
+func (s *Server) ColumnsRequests() []ColumnsRequest {
+	s.handler.mu.Lock()
+	defer s.handler.mu.Unlock()
+	return append([]ColumnsRequest(nil), s.handler.columnsRequests...)
+}
+
+// getColumnsSchema contains the columns of the JDBC DatabaseMetaData.getColumns result up to ORDINAL_POSITION
+var getColumnsSchema = []Column{
+	{Name: "TABLE_CAT", Type: "STRING"},
+	{Name: "TABLE_SCHEM", Type: "STRING"},
+	{Name: "TABLE_NAME", Type: "STRING"},
+	{Name: "COLUMN_NAME", Type: "STRING"},
+	{Name: "DATA_TYPE", Type: "INT"},
+	{Name: "TYPE_NAME", Type: "STRING"},
+	{Name: "COLUMN_SIZE", Type: "INT"},
+	{Name: "BUFFER_LENGTH", Type: "TINYINT"},
+	{Name: "DECIMAL_DIGITS", Type: "INT"},
+	{Name: "NUM_PREC_RADIX", Type: "INT"},
+	{Name: "NULLABLE", Type: "INT"},
+	{Name: "REMARKS", Type: "STRING"},
+	{Name: "COLUMN_DEF", Type: "STRING"},
+	{Name: "SQL_DATA_TYPE", Type: "INT"},
+	{Name: "SQL_DATETIME_SUB", Type: "INT"},
+	{Name: "CHAR_OCTET_LENGTH", Type: "INT"},
+	{Name: "ORDINAL_POSITION", Type: "INT"},
+}
+
+func (h *handler) GetColumns(_ context.Context, req *cli_service.TGetColumnsReq) (*cli_service.TGetColumnsResp, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	status := h.call("GetColumns")
+	colReq := ColumnsRequest{
+		Schema: string(req.GetSchemaName()),
+		Table:  string(req.GetTableName()),
+		Column: string(req.GetColumnName()),
+	}
+	h.columnsRequests = append(h.columnsRequests, colReq)
+	resp := &cli_service.TGetColumnsResp{Status: status}
+	if status.StatusCode != cli_service.TStatusCode_SUCCESS_STATUS {
+		return resp, nil
+	}
+
+	schemaRe, tableRe, columnRe := likeRegexp(colReq.Schema), likeRegexp(colReq.Table), likeRegexp(colReq.Column)
+	res := Result{Columns: getColumnsSchema}
+	for _, tbl := range h.tables {
+		if !schemaRe.MatchString(tbl.schema) || !tableRe.MatchString(tbl.name) {
+			continue
+		}
+		for i, c := range tbl.columns {
+			if !columnRe.MatchString(c.Name) {
+				continue
+			}
+			row := make([]any, len(getColumnsSchema))
+			row[1], row[2], row[3], row[5], row[16] = tbl.schema, tbl.name, c.Name, strings.ToUpper(c.Type), i+1
+			res.Rows = append(res.Rows, row)
+		}
+	}
+	prepared, err := prepareResult(res)
+	if err != nil {
+		return nil, err
+	}
+	handle := newHandle()
+	h.operations[string(handle.GUID)] = &operation{res: prepared}
+	resp.OperationHandle = &cli_service.TOperationHandle{
+		OperationId:   handle,
+		OperationType: cli_service.TOperationType_GET_COLUMNS,
+		HasResultSet:  true,
+	}
+	return resp, nil
+}
+
+// likeRegexp converts a LIKE pattern to a regular expression. An empty pattern matches everything.
+func likeRegexp(pattern string) *regexp.Regexp {
+	if pattern == "" {
+		pattern = "%"
+	}
+	var sb strings.Builder
+	sb.WriteString("(?is)^")
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '%':
+			sb.WriteString(".*")
+		case r == '_':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.MustCompile(sb.String())
+}
@@ -0,0 +1,161 @@
+package impalatest
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/sclgo/impala-go/internal/generated/cli_service"
+	"github.com/sclgo/impala-go/internal/hive"
+)
+
+// column holds the values of a result column in the wire format
+type column struct {
+	kind    string
+	bools   []bool
+	ints    []int64
+	doubles []float64
+	strings []string
+	nulls   []bool
+}
+
+func prepareResult(res Result) (*preparedResult, error) {
+	prepared := &preparedResult{res: res}
+	if res.Columns == nil {
+		if len(res.Rows) > 0 {
+			return nil, fmt.Errorf("rows without columns")
+		}
+		return prepared, nil
+	}
+	prepared.schema = &cli_service.TTableSchema{}
+	for i, c := range res.Columns {
+		cd, err := hive.NewColDesc(c.Name, c.Type)
+		if err != nil {
+			return nil, err
+		}
+		prepared.schema.Columns = append(prepared.schema.Columns, &cli_service.TColumnDesc{
+			ColumnName: c.Name,
+			TypeDesc: &cli_service.TTypeDesc{
+				Types: []*cli_service.TTypeEntry{{
+					PrimitiveEntry: &cli_service.TPrimitiveTypeEntry{Type: mustTypeID(cd.DatabaseTypeName)},
+				}},
+			},
+			Position: int32(i + 1),
+		})
+		prepared.columns = append(prepared.columns, &column{kind: cd.DatabaseTypeName})
+	}
+	for r, row := range res.Rows {
+		if len(row) != len(res.Columns) {
+			return nil, fmt.Errorf("row %d has %d values, expected %d", r, len(row), len(res.Columns))
+		}
+		for i, v := range row {
+			if err := prepared.columns[i].add(v); err != nil {
+				return nil, fmt.Errorf("row %d, column %s: %w", r, res.Columns[i].Name, err)
+			}
+		}
+	}
+	return prepared, nil
+}
+
+// mustTypeID returns the Thrift type id of a type name, already validated by hive.NewColDesc
+func mustTypeID(dbtype string) cli_service.TTypeId {
+	typeID, err := cli_service.TTypeIdFromString(dbtype + "_TYPE")
+	if err != nil {
+		panic(err)
+	}
+	return typeID
+}
+
+func (c *column) add(v any) error {
+	c.nulls = append(c.nulls, v == nil)
+	switch c.kind {
+	case "BOOLEAN":
+		b, ok := v.(bool)
+		if !ok && v != nil {
+			return fmt.Errorf("expected bool, got %T", v)
+		}
+		c.bools = append(c.bools, b)
+	case "TINYINT", "SMALLINT", "INT", "BIGINT":
+		var n int64
+		if v != nil {
+			rv := reflect.ValueOf(v)
+			switch {
+			case rv.CanInt():
+				n = rv.Int()
+			case rv.CanUint():
+				n = int64(rv.Uint())
+			default:
+				return fmt.Errorf("expected integer, got %T", v)
+			}
+		}
+		c.ints = append(c.ints, n)
+	case "FLOAT", "DOUBLE":
+		var f float64
+		if v != nil {
+			rv := reflect.ValueOf(v)
+			if !rv.CanFloat() {
+				return fmt.Errorf("expected float, got %T", v)
+			}
+			f = rv.Float()
+		}
+		c.doubles = append(c.doubles, f)
+	default:
+		var s string
+		switch tv := v.(type) {
+		case nil:
+		case time.Time:
+			s = tv.Format(hive.TimestampFormat)
+			if c.kind == "DATE" {
+				s = tv.Format(hive.DateFormat)
+			}
+		default:
+			s = fmt.Sprint(v)
+		}
+		c.strings = append(c.strings, s)
+	}
+	return nil
+}
+
+// slice returns the values of rows [start, end) as a Thrift column
+func (c *column) slice(start, end int) *cli_service.TColumn {
+	nulls := bitmap(c.nulls[start:end])
+	switch c.kind {
+	case "BOOLEAN":
+		return &cli_service.TColumn{BoolVal: &cli_service.TBoolColumn{Values: c.bools[start:end], Nulls: nulls}}
+	case "TINYINT":
+		values := make([]int8, 0, end-start)
+		for _, n := range c.ints[start:end] {
+			values = append(values, int8(n))
+		}
+		return &cli_service.TColumn{ByteVal: &cli_service.TByteColumn{Values: values, Nulls: nulls}}
+	case "SMALLINT":
+		values := make([]int16, 0, end-start)
+		for _, n := range c.ints[start:end] {
+			values = append(values, int16(n))
+		}
+		return &cli_service.TColumn{I16Val: &cli_service.TI16Column{Values: values, Nulls: nulls}}
+	case "INT":
+		values := make([]int32, 0, end-start)
+		for _, n := range c.ints[start:end] {
+			values = append(values, int32(n))
+		}
+		return &cli_service.TColumn{I32Val: &cli_service.TI32Column{Values: values, Nulls: nulls}}
+	case "BIGINT":
+		return &cli_service.TColumn{I64Val: &cli_service.TI64Column{Values: c.ints[start:end], Nulls: nulls}}
+	case "FLOAT", "DOUBLE":
+		return &cli_service.TColumn{DoubleVal: &cli_service.TDoubleColumn{Values: c.doubles[start:end], Nulls: nulls}}
+	default:
+		return &cli_service.TColumn{StringVal: &cli_service.TStringColumn{Values: c.strings[start:end], Nulls: nulls}}
+	}
+}
+
+// bitmap encodes null flags in the HiveServer2 format: bit i%8 of byte i/8 is set if row i is NULL
+func bitmap(nulls []bool) []byte {
+	b := make([]byte, (len(nulls)+7)/8)
+	for i, null := range nulls {
+		if null {
+			b[i/8] |= 1 << (uint(i) % 8)
+		}
+	}
+	return b
+}
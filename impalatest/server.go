@@ -0,0 +1,381 @@
+// Package impalatest provides a fake Impala server for tests, which exercise code that uses the impala driver
+// without running Impala e.g. in a container.
+//
+// The fake server speaks the same HiveServer2 Thrift protocol as Impala over TCP without TLS or authentication.
+// It returns the results registered with AddResult for matching statements, the columns of the tables registered
+// with AddTable for GetColumns requests, and the failures registered with FailNext. It doesn't parse or run SQL.
+package impalatest
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/sclgo/impala-go/internal/generated/cli_service"
+	"github.com/sclgo/impala-go/internal/generated/impalaservice"
+)
+
+// Column describes a column of a Result
+type Column struct {
+	Name string
+	// Type is an Impala type name e.g. INT or STRING
+	Type string
+}
+
+// Result is the canned response to a statement
+type Result struct {
+	// Columns is the schema of the result set. Statements without Columns, like INSERT, have no result set.
+	Columns []Column
+	// Rows contains the values of each row, in the order of Columns. nil values are NULL.
+	// Values are converted to the wire format of the column type - see AddResult.
+	Rows [][]any
+	// RowsAffected is reported when the operation is closed, for statements without a result set
+	RowsAffected int64
+	// Log is the operation log e.g. with warnings
+	Log string
+	// Err, if not empty, is the error message, which the server reports when the statement is executed
+	Err string
+}
+
+// Server is a fake Impala server. It is safe for concurrent use.
+type Server struct {
+	server   *thrift.TSimpleServer
+	listener *thrift.TServerSocket
+	handler  *handler
+}
+
+// NewServer starts a fake Impala server, listening on a local port. Close it when it is no longer needed.
+func NewServer() (*Server, error) {
+	listener, err := thrift.NewTServerSocket("127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	if err = listener.Listen(); err != nil {
+		return nil, err
+	}
+	h := &handler{
+		results:    map[string]*preparedResult{},
+		failures:   map[string][]string{},
+		operations: map[string]*operation{},
+	}
+	processor := impalaservice.NewImpalaHiveServer2ServiceProcessor(h)
+	// Unsupported methods fail with a Thrift application exception instead of a nil pointer panic
+	for name := range processor.ProcessorMap() {
+		if !supportedMethods[name] {
+			delete(processor.ProcessorMap(), name)
+		}
+	}
+	server := thrift.NewTSimpleServer4(processor, listener, thrift.NewTTransportFactory(),
+		thrift.NewTBinaryProtocolFactoryConf(nil))
+	go func() {
+		_ = server.Serve()
+	}()
+	return &Server{server: server, listener: listener, handler: h}, nil
+}
+
+// Addr returns the host:port address of the server
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// DSN returns a data source name for connecting to the server with the impala driver
+func (s *Server) DSN() string {
+	return "impala://" + s.Addr()
+}
+
+// Close stops the server. It waits for clients to disconnect so close sql.DB instances connected to the server first.
+func (s *Server) Close() error {
+	return s.server.Stop()
+}
+
+// AddResult registers the response to stmt, which must match the statement text sent by the driver,
+// ignoring leading and trailing whitespace. Note that the driver replaces placeholders with argument values
+// before sending statements. Statements without a registered result succeed without a result set.
+//
+// Row values are converted according to the column type: BOOLEAN requires bool; TINYINT, SMALLINT, INT,
+// and BIGINT require integers; FLOAT and DOUBLE require floats; other types are sent as strings, like Impala
+// sends DECIMAL and TIMESTAMP values. time.Time values are formatted like Impala timestamps and other values
+// with fmt.Sprint.
+func (s *Server) AddResult(stmt string, res Result) error {
+	prepared, err := prepareResult(res)
+	if err != nil {
+		return fmt.Errorf("impalatest: invalid result for %q: %w", stmt, err)
+	}
+	s.handler.mu.Lock()
+	defer s.handler.mu.Unlock()
+	s.handler.results[strings.TrimSpace(stmt)] = prepared
+	return nil
+}
+
+// FailNext makes the next call to the Thrift method, e.g. OpenSession or FetchResults, fail with an error status
+// with the given message, like Impala reports failures. Repeated calls queue failures for subsequent calls.
+func (s *Server) FailNext(method string, message string) {
+	s.handler.mu.Lock()
+	defer s.handler.mu.Unlock()
+	s.handler.failures[method] = append(s.handler.failures[method], message)
+}
+
+// Calls returns the names of the Thrift methods called so far, in order
+func (s *Server) Calls() []string {
+	s.handler.mu.Lock()
+	defer s.handler.mu.Unlock()
+	return append([]string(nil), s.handler.calls...)
+}
+
+// Statements returns the statements executed so far, in order
+func (s *Server) Statements() []string {
+	s.handler.mu.Lock()
+	defer s.handler.mu.Unlock()
+	return append([]string(nil), s.handler.statements...)
+}
+
+var supportedMethods = map[string]bool{
+	"OpenSession":          true,
+	"CloseSession":         true,
+	"GetInfo":              true,
+	"ExecuteStatement":     true,
+	"GetOperationStatus":   true,
+	"GetResultSetMetadata": true,
+	"FetchResults":         true,
+	"GetLog":               true,
+	"GetColumns":           true,
+	"CancelOperation":      true,
+	"CloseOperation":       true,
+	"CloseImpalaOperation": true,
+	"PingImpalaHS2Service": true,
+}
+
+// preparedResult is a Result in the wire format
+type preparedResult struct {
+	res     Result
+	schema  *cli_service.TTableSchema
+	columns []*column
+}
+
+type operation struct {
+	res     *preparedResult
+	fetched int
+}
+
+type handler struct {
+	// the methods, which are not implemented, are removed from the processor in NewServer
+	impalaservice.ImpalaHiveServer2Service
+
+	mu         sync.Mutex
+	results    map[string]*preparedResult
+	failures   map[string][]string
+	operations map[string]*operation
+	calls      []string
+	statements []string
+
+	tables          []table
+	columnsRequests []ColumnsRequest
+}
+
+var successStatus = &cli_service.TStatus{StatusCode: cli_service.TStatusCode_SUCCESS_STATUS}
+
+// call records a call to method and returns the status of the response, which is an error if a failure is queued
+func (h *handler) call(method string) *cli_service.TStatus {
+	h.calls = append(h.calls, method)
+	failures := h.failures[method]
+	if len(failures) == 0 {
+		return successStatus
+	}
+	h.failures[method] = failures[1:]
+	return errorStatus(failures[0])
+}
+
+func errorStatus(message string) *cli_service.TStatus {
+	return &cli_service.TStatus{
+		StatusCode:   cli_service.TStatusCode_ERROR_STATUS,
+		ErrorMessage: &message,
+	}
+}
+
+func newHandle() *cli_service.THandleIdentifier {
+	guid := make([]byte, 16)
+	secret := make([]byte, 16)
+	_, _ = rand.Read(guid)
+	_, _ = rand.Read(secret)
+	return &cli_service.THandleIdentifier{GUID: guid, Secret: secret}
+}
+
+func (h *handler) OpenSession(_ context.Context, _ *cli_service.TOpenSessionReq) (*cli_service.TOpenSessionResp, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return &cli_service.TOpenSessionResp{
+		Status:                h.call("OpenSession"),
+		ServerProtocolVersion: cli_service.TProtocolVersion_HIVE_CLI_SERVICE_PROTOCOL_V7,
+		SessionHandle:         &cli_service.TSessionHandle{SessionId: newHandle()},
+		Configuration:         map[string]string{},
+	}, nil
+}
+
+func (h *handler) CloseSession(_ context.Context, _ *cli_service.TCloseSessionReq) (*cli_service.TCloseSessionResp, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return &cli_service.TCloseSessionResp{Status: h.call("CloseSession")}, nil
+}
+
+func (h *handler) GetInfo(_ context.Context, _ *cli_service.TGetInfoReq) (*cli_service.TGetInfoResp, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	name := "Impala"
+	return &cli_service.TGetInfoResp{
+		Status:    h.call("GetInfo"),
+		InfoValue: &cli_service.TGetInfoValue{StringValue: &name},
+	}, nil
+}
+
+func (h *handler) ExecuteStatement(_ context.Context, req *cli_service.TExecuteStatementReq) (*cli_service.TExecuteStatementResp, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	status := h.call("ExecuteStatement")
+	stmt := strings.TrimSpace(req.Statement)
+	h.statements = append(h.statements, stmt)
+	res := h.results[stmt]
+	if res == nil {
+		res = &preparedResult{}
+	}
+	if status.StatusCode == cli_service.TStatusCode_SUCCESS_STATUS && res.res.Err != "" {
+		status = errorStatus(res.res.Err)
+	}
+	resp := &cli_service.TExecuteStatementResp{Status: status}
+	if status.StatusCode != cli_service.TStatusCode_SUCCESS_STATUS {
+		return resp, nil
+	}
+	handle := newHandle()
+	h.operations[string(handle.GUID)] = &operation{res: res}
+	resp.OperationHandle = &cli_service.TOperationHandle{
+		OperationId:   handle,
+		OperationType: cli_service.TOperationType_EXECUTE_STATEMENT,
+		HasResultSet:  res.schema != nil,
+	}
+	return resp, nil
+}
+
+// operation returns the operation with the given handle or an error status
+func (h *handler) operation(handle *cli_service.TOperationHandle) (*operation, *cli_service.TStatus) {
+	op := h.operations[string(handle.GetOperationId().GetGUID())]
+	if op == nil {
+		return nil, &cli_service.TStatus{
+			StatusCode:   cli_service.TStatusCode_INVALID_HANDLE_STATUS,
+			ErrorMessage: &invalidHandle,
+		}
+	}
+	return op, successStatus
+}
+
+var invalidHandle = "Invalid query handle"
+
+func (h *handler) GetOperationStatus(_ context.Context, req *cli_service.TGetOperationStatusReq) (*cli_service.TGetOperationStatusResp, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	status := h.call("GetOperationStatus")
+	if status.StatusCode == cli_service.TStatusCode_SUCCESS_STATUS {
+		_, status = h.operation(req.OperationHandle)
+	}
+	return &cli_service.TGetOperationStatusResp{
+		Status:         status,
+		OperationState: cli_service.TOperationStatePtr(cli_service.TOperationState_FINISHED_STATE),
+	}, nil
+}
+
+func (h *handler) GetResultSetMetadata(_ context.Context, req *cli_service.TGetResultSetMetadataReq) (*cli_service.TGetResultSetMetadataResp, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	status := h.call("GetResultSetMetadata")
+	var op *operation
+	if status.StatusCode == cli_service.TStatusCode_SUCCESS_STATUS {
+		op, status = h.operation(req.OperationHandle)
+	}
+	resp := &cli_service.TGetResultSetMetadataResp{Status: status}
+	if op != nil {
+		resp.Schema = op.res.schema
+	}
+	return resp, nil
+}
+
+func (h *handler) FetchResults(_ context.Context, req *cli_service.TFetchResultsReq) (*cli_service.TFetchResultsResp, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	status := h.call("FetchResults")
+	var op *operation
+	if status.StatusCode == cli_service.TStatusCode_SUCCESS_STATUS {
+		op, status = h.operation(req.OperationHandle)
+	}
+	resp := &cli_service.TFetchResultsResp{Status: status}
+	if op == nil {
+		return resp, nil
+	}
+	start := op.fetched
+	end := min(len(op.res.res.Rows), start+max(int(req.MaxRows), 1))
+	op.fetched = end
+	resp.HasMoreRows = thrift.BoolPtr(end < len(op.res.res.Rows))
+	resp.Results = &cli_service.TRowSet{
+		StartRowOffset: int64(start),
+		Rows:           []*cli_service.TRow{},
+	}
+	for _, col := range op.res.columns {
+		resp.Results.Columns = append(resp.Results.Columns, col.slice(start, end))
+	}
+	return resp, nil
+}
+
+func (h *handler) GetLog(_ context.Context, req *cli_service.TGetLogReq) (*cli_service.TGetLogResp, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	status := h.call("GetLog")
+	var op *operation
+	if status.StatusCode == cli_service.TStatusCode_SUCCESS_STATUS {
+		op, status = h.operation(req.OperationHandle)
+	}
+	resp := &cli_service.TGetLogResp{Status: status}
+	if op != nil {
+		resp.Log = op.res.res.Log
+	}
+	return resp, nil
+}
+
+func (h *handler) CancelOperation(_ context.Context, _ *cli_service.TCancelOperationReq) (*cli_service.TCancelOperationResp, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return &cli_service.TCancelOperationResp{Status: h.call("CancelOperation")}, nil
+}
+
+func (h *handler) CloseOperation(_ context.Context, req *cli_service.TCloseOperationReq) (*cli_service.TCloseOperationResp, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	status := h.call("CloseOperation")
+	delete(h.operations, string(req.OperationHandle.GetOperationId().GetGUID()))
+	return &cli_service.TCloseOperationResp{Status: status}, nil
+}
+
+func (h *handler) CloseImpalaOperation(_ context.Context, req *impalaservice.TCloseImpalaOperationReq) (*impalaservice.TCloseImpalaOperationResp, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	status := h.call("CloseImpalaOperation")
+	var op *operation
+	if status.StatusCode == cli_service.TStatusCode_SUCCESS_STATUS {
+		op, status = h.operation(req.OperationHandle)
+	}
+	resp := &impalaservice.TCloseImpalaOperationResp{Status: status}
+	if op != nil {
+		delete(h.operations, string(req.OperationHandle.GetOperationId().GetGUID()))
+		if op.res.schema == nil {
+			resp.DmlResult_ = &impalaservice.TDmlResult_{RowsModified: map[string]int64{"": op.res.res.RowsAffected}}
+		}
+	}
+	return resp, nil
+}
+
+func (h *handler) PingImpalaHS2Service(_ context.Context, _ *impalaservice.TPingImpalaHS2ServiceReq) (*impalaservice.TPingImpalaHS2ServiceResp, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return &impalaservice.TPingImpalaHS2ServiceResp{Status: h.call("PingImpalaHS2Service")}, nil
+}
+
+var _ impalaservice.ImpalaHiveServer2Service = (*handler)(nil)
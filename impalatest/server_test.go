@@ -0,0 +1,139 @@
+package impalatest_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/sclgo/impala-go"
+	"github.com/sclgo/impala-go/impalatest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("query", func(t *testing.T) {
+		srv, db := impalatest.OpenDB(t)
+		ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		err := srv.AddResult("SELECT * FROM t", impalatest.Result{
+			Columns: []impalatest.Column{
+				{Name: "id", Type: "INT"},
+				{Name: "name", Type: "STRING"},
+				{Name: "created", Type: "TIMESTAMP"},
+				{Name: "active", Type: "BOOLEAN"},
+			},
+			Rows: [][]any{
+				{1, "a", ts, true},
+				{2, nil, nil, nil},
+			},
+		})
+		require.NoError(t, err)
+
+		rows, err := db.QueryContext(ctx, " SELECT * FROM t ")
+		require.NoError(t, err)
+		defer rows.Close()
+
+		var (
+			id      int
+			name    sql.NullString
+			created sql.NullTime
+			active  sql.NullBool
+		)
+		require.True(t, rows.Next())
+		require.NoError(t, rows.Scan(&id, &name, &created, &active))
+		require.Equal(t, 1, id)
+		require.Equal(t, sql.NullString{String: "a", Valid: true}, name)
+		require.Equal(t, ts, created.Time)
+		require.True(t, active.Bool)
+
+		require.True(t, rows.Next())
+		require.NoError(t, rows.Scan(&id, &name, &created, &active))
+		require.Equal(t, 2, id)
+		require.False(t, name.Valid)
+		require.False(t, created.Valid)
+		require.False(t, active.Valid)
+
+		require.False(t, rows.Next())
+		require.NoError(t, rows.Err())
+	})
+
+	t.Run("exec", func(t *testing.T) {
+		srv, db := impalatest.OpenDB(t)
+		require.NoError(t, srv.AddResult("INSERT INTO t VALUES (1)", impalatest.Result{RowsAffected: 1}))
+
+		res, err := db.ExecContext(ctx, "INSERT INTO t VALUES (?)", 1)
+		require.NoError(t, err)
+		n, err := res.RowsAffected()
+		require.NoError(t, err)
+		require.Equal(t, int64(1), n)
+		require.Equal(t, []string{"INSERT INTO t VALUES (1)"}, srv.Statements())
+	})
+
+	t.Run("statement error", func(t *testing.T) {
+		srv, db := impalatest.OpenDB(t)
+		require.NoError(t, srv.AddResult("SELECT * FROM missing", impalatest.Result{
+			Err: "AnalysisException: Could not resolve table reference: 'missing'",
+		}))
+
+		_, err := db.QueryContext(ctx, "SELECT * FROM missing")
+		require.ErrorContains(t, err, "Could not resolve table reference")
+	})
+
+	t.Run("fail next", func(t *testing.T) {
+		srv, db := impalatest.OpenDB(t)
+		require.NoError(t, srv.AddResult("SELECT 1", impalatest.Result{
+			Columns: []impalatest.Column{{Name: "1", Type: "TINYINT"}},
+			Rows:    [][]any{{1}},
+		}))
+		srv.FailNext("FetchResults", "injected failure")
+
+		rows, err := db.QueryContext(ctx, "SELECT 1")
+		require.NoError(t, err)
+		defer rows.Close()
+		require.False(t, rows.Next())
+		require.ErrorContains(t, rows.Err(), "injected failure")
+		require.Contains(t, srv.Calls(), "FetchResults")
+	})
+
+	t.Run("invalid result", func(t *testing.T) {
+		srv := impalatest.Start(t)
+
+		err := srv.AddResult("SELECT 1", impalatest.Result{
+			Columns: []impalatest.Column{{Name: "x", Type: "INT"}},
+			Rows:    [][]any{{"one"}},
+		})
+		require.ErrorContains(t, err, "expected integer")
+		err = srv.AddResult("SELECT 1", impalatest.Result{
+			Columns: []impalatest.Column{{Name: "x", Type: "NOSUCHTYPE"}},
+		})
+		require.ErrorContains(t, err, "unknown type")
+		require.ErrorContains(t, srv.AddTable("db", "t", impalatest.Column{Name: "x", Type: "NOSUCHTYPE"}), "unknown type")
+	})
+
+	t.Run("columns", func(t *testing.T) {
+		srv, db := impalatest.OpenDB(t)
+		require.NoError(t, srv.AddTable("db", "a_b", impalatest.Column{Name: "id", Type: "INT"}))
+		require.NoError(t, srv.AddTable("db", "axb", impalatest.Column{Name: "id", Type: "INT"}))
+		meta := impala.NewMetadata(db)
+
+		names := func(cols []impala.ColumnName) []string {
+			var res []string
+			for _, c := range cols {
+				res = append(res, c.TableName+"."+c.ColumnName)
+			}
+			return res
+		}
+		cols, err := meta.GetColumns(ctx, "DB", "a_b", "%")
+		require.NoError(t, err)
+		require.Equal(t, []string{"a_b.id", "axb.id"}, names(cols), "_ is a wildcard, case is ignored")
+		cols, err = meta.GetColumns(ctx, "db", `a\_b`, "")
+		require.NoError(t, err)
+		require.Equal(t, []string{"a_b.id"}, names(cols))
+		cols, err = meta.GetColumns(ctx, "db", "a_b", `\%`)
+		require.NoError(t, err)
+		require.Empty(t, cols)
+		require.Equal(t, impalatest.ColumnsRequest{Schema: "db", Table: "a_b", Column: `\%`}, srv.ColumnsRequests()[2])
+	})
+}
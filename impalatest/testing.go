@@ -0,0 +1,39 @@
+package impalatest
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/murfffi/gorich/fi"
+	"github.com/stretchr/testify/require"
+)
+
+// Start starts a Server for the current test and closes it when the test and its subtests complete.
+func Start(t *testing.T) *Server {
+	srv, err := NewServer()
+	require.NoError(t, err)
+	fi.CleanupF(t, srv.Close)
+	return srv
+}
+
+// OpenDB opens a sql.DB connected to the server and closes it when the test completes, before
+// the server is closed. params are added to the query string of the DSN e.g. "readonly=true".
+// The impala driver must be registered e.g. with a blank import of github.com/sclgo/impala-go.
+func (s *Server) OpenDB(t *testing.T, params ...string) *sql.DB {
+	dsn := s.DSN()
+	if len(params) > 0 {
+		dsn += "?" + strings.Join(params, "&")
+	}
+	db, err := sql.Open("impala", dsn)
+	require.NoError(t, err)
+	fi.CleanupF(t, db.Close)
+	return db
+}
+
+// OpenDB starts a Server for the current test and opens a sql.DB connected to it.
+// Both are closed when the test completes. See Server.OpenDB for params.
+func OpenDB(t *testing.T, params ...string) (*Server, *sql.DB) {
+	srv := Start(t)
+	return srv, srv.OpenDB(t, params...)
+}
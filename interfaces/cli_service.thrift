@@ -1151,7 +1151,7 @@ service TCLIService {
 
   TExecuteStatementResp ExecuteStatement(1:TExecuteStatementReq req);
 
-  // TGetTypeInfoResp GetTypeInfo(1:TGetTypeInfoReq req);
+  TGetTypeInfoResp GetTypeInfo(1:TGetTypeInfoReq req);
 
   TGetCatalogsResp GetCatalogs(1:TGetCatalogsReq req);
 
@@ -26,6 +26,7 @@ func Usage() {
 	fmt.Fprintln(os.Stderr, "  TCloseSessionResp CloseSession(TCloseSessionReq req)")
 	fmt.Fprintln(os.Stderr, "  TGetInfoResp GetInfo(TGetInfoReq req)")
 	fmt.Fprintln(os.Stderr, "  TExecuteStatementResp ExecuteStatement(TExecuteStatementReq req)")
+	fmt.Fprintln(os.Stderr, "  TGetTypeInfoResp GetTypeInfo(TGetTypeInfoReq req)")
 	fmt.Fprintln(os.Stderr, "  TGetCatalogsResp GetCatalogs(TGetCatalogsReq req)")
 	fmt.Fprintln(os.Stderr, "  TGetSchemasResp GetSchemas(TGetSchemasReq req)")
 	fmt.Fprintln(os.Stderr, "  TGetTablesResp GetTables(TGetTablesReq req)")
@@ -256,6 +257,31 @@ func main() {
 		fmt.Print(client.ExecuteStatement(context.Background(), value0))
 		fmt.Print("\n")
 		break
+	case "GetTypeInfo":
+		if flag.NArg() - 1 != 1 {
+			fmt.Fprintln(os.Stderr, "GetTypeInfo requires 1 args")
+			flag.Usage()
+		}
+		arg170 := flag.Arg(1)
+		mbTrans171 := thrift.NewTMemoryBufferLen(len(arg170))
+		defer mbTrans171.Close()
+		_, err172 := mbTrans171.WriteString(arg170)
+		if err172 != nil {
+			Usage()
+			return
+		}
+		factory173 := thrift.NewTJSONProtocolFactory()
+		jsProt174 := factory173.GetProtocol(mbTrans171)
+		argvalue0 := cli_service.NewTGetTypeInfoReq()
+		err175 := argvalue0.Read(context.Background(), jsProt174)
+		if err175 != nil {
+			Usage()
+			return
+		}
+		value0 := argvalue0
+		fmt.Print(client.GetTypeInfo(context.Background(), value0))
+		fmt.Print("\n")
+		break
 	case "GetCatalogs":
 		if flag.NArg() - 1 != 1 {
 			fmt.Fprintln(os.Stderr, "GetCatalogs requires 1 args")
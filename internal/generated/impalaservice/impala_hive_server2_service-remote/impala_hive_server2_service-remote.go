@@ -30,6 +30,7 @@ func Usage() {
 	fmt.Fprintln(os.Stderr, "  TCloseSessionResp CloseSession(TCloseSessionReq req)")
 	fmt.Fprintln(os.Stderr, "  TGetInfoResp GetInfo(TGetInfoReq req)")
 	fmt.Fprintln(os.Stderr, "  TExecuteStatementResp ExecuteStatement(TExecuteStatementReq req)")
+	fmt.Fprintln(os.Stderr, "  TGetTypeInfoResp GetTypeInfo(TGetTypeInfoReq req)")
 	fmt.Fprintln(os.Stderr, "  TGetCatalogsResp GetCatalogs(TGetCatalogsReq req)")
 	fmt.Fprintln(os.Stderr, "  TGetSchemasResp GetSchemas(TGetSchemasReq req)")
 	fmt.Fprintln(os.Stderr, "  TGetTablesResp GetTables(TGetTablesReq req)")
@@ -310,6 +311,31 @@ func main() {
 		fmt.Print(client.ExecuteStatement(context.Background(), value0))
 		fmt.Print("\n")
 		break
+	case "GetTypeInfo":
+		if flag.NArg() - 1 != 1 {
+			fmt.Fprintln(os.Stderr, "GetTypeInfo requires 1 args")
+			flag.Usage()
+		}
+		arg55 := flag.Arg(1)
+		mbTrans56 := thrift.NewTMemoryBufferLen(len(arg55))
+		defer mbTrans56.Close()
+		_, err57 := mbTrans56.WriteString(arg55)
+		if err57 != nil {
+			Usage()
+			return
+		}
+		factory58 := thrift.NewTJSONProtocolFactory()
+		jsProt59 := factory58.GetProtocol(mbTrans56)
+		argvalue0 := cli_service.NewTGetTypeInfoReq()
+		err60 := argvalue0.Read(context.Background(), jsProt59)
+		if err60 != nil {
+			Usage()
+			return
+		}
+		value0 := argvalue0
+		fmt.Print(client.GetTypeInfo(context.Background(), value0))
+		fmt.Print("\n")
+		break
 	case "GetCatalogs":
 		if flag.NArg() - 1 != 1 {
 			fmt.Fprintln(os.Stderr, "GetCatalogs requires 1 args")
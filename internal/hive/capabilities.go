@@ -0,0 +1,54 @@
+package hive
+
+import (
+	"context"
+	"errors"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/sclgo/impala-go/internal/generated/cli_service"
+	"github.com/sclgo/impala-go/internal/generated/impalaservice"
+)
+
+// Capabilities describes optional features of the server
+type Capabilities struct {
+	// ImpalaExtension reports whether the server implements ImpalaHiveServer2Service, the Impala extension
+	// of HiveServer2, e.g. CloseImpalaOperation, which reports the rows modified by DML statements.
+	// Other HiveServer2 servers, e.g. Hive, implement only the base protocol.
+	ImpalaExtension bool
+	// Version is the server version reported by the extension, if available
+	Version string
+}
+
+// probeCapabilities detects the capabilities of the server with PingImpalaHS2Service, which has no side effects,
+// and records them in the client
+func (c *Client) probeCapabilities(ctx context.Context, h *cli_service.TSessionHandle) (Capabilities, error) {
+	resp, err := c.client.PingImpalaHS2Service(ctx, &impalaservice.TPingImpalaHS2ServiceReq{SessionHandle: h})
+	var appErr thrift.TApplicationException
+	var caps Capabilities
+	switch {
+	case errors.As(err, &appErr) && appErr.TypeId() == thrift.UNKNOWN_METHOD:
+		c.log.Infof("server doesn't implement the Impala extension of HiveServer2")
+	case err != nil:
+		return Capabilities{}, err
+	default:
+		// an error status still means that the server knows the method
+		caps = Capabilities{ImpalaExtension: true, Version: resp.GetVersion()}
+	}
+	c.caps = &caps
+	return caps, nil
+}
+
+// Capabilities returns the capabilities of the server, detected when the first session was opened.
+// If that failed, they are detected again.
+func (s *Session) Capabilities(ctx context.Context) (Capabilities, error) {
+	if s.hive.caps != nil {
+		return *s.hive.caps, nil
+	}
+	return s.hive.probeCapabilities(ctx, s.h)
+}
+
+// impalaExtension reports whether the Impala extension methods can be used. Until the capabilities are known,
+// the driver assumes an Impala server.
+func (c *Client) impalaExtension() bool {
+	return c.caps == nil || c.caps.ImpalaExtension
+}
@@ -2,34 +2,132 @@ package hive
 
 import (
 	"context"
-	"log"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/samber/lo"
 	"github.com/sclgo/impala-go/internal/generated/cli_service"
 	"github.com/sclgo/impala-go/internal/generated/impalaservice"
 )
 
+// ErrSessionLimit means the server rejected a new session because a limit on the number of sessions was reached
+var ErrSessionLimit = errors.New("impala: the server rejected the session because of a session limit")
+
+// sessionLimitMessages are the parts of the errors, which Impala and Hive report when they reject a session
+// because of a limit, like the max_hs2_sessions_per_user Impala flag
+var sessionLimitMessages = []string{
+	"exceeds coordinator limit",
+	"too many sessions",
+	"too many open sessions",
+}
+
 // Client represents Hive Client
 type Client struct {
 	client impalaservice.ImpalaHiveServer2Service
 	opts   *Options
-	log    *log.Logger
+	log    *Logger
+	// caps is detected when the first session is opened
+	caps *Capabilities
 }
 
 // Options for Hive Client
 type Options struct {
 	MaxRows int64
+	// BatchBytes, if positive, replaces MaxRows for result sets with a number of rows computed from the schema,
+	// so that a batch takes about BatchBytes bytes
+	BatchBytes int64
 	// MemLimit configures the MEM_LIMIT Impala property at session level
 	// https://impala.apache.org/docs/build/html/topics/impala_mem_limit.html
 	MemLimit string
 	// QueryTimeout in seconds - for QUERY_TIMEOUT_S session configuration value
 	// https://impala.apache.org/docs/build/html/topics/impala_query_timeout_s.html
 	QueryTimeout int
+	// FetchMaxWait bounds how long a fetch waits while the server reports that the results are not ready yet,
+	// if the context has no deadline. When exceeded, the operation is cancelled and the fetch fails with
+	// ErrFetchTimeout. 0 or negative means no limit.
+	FetchMaxWait time.Duration
+	// MaxResultBytes limits the total size of the values fetched by a single result set.
+	// 0 or negative means no limit.
+	MaxResultBytes int64
+	// MaxRowsReturned limits the number of rows returned by a single result set.
+	// When exceeded, the operation is cancelled. 0 or negative means no limit.
+	MaxRowsReturned int64
+	// MaxCellBytes limits the size of individual STRING, VARCHAR, and CHAR values. Larger values fail with
+	// ErrCellTooLarge, or are truncated if TruncateCells is enabled. 0 or negative means no limit.
+	MaxCellBytes int
+	// TruncateCells truncates values larger than MaxCellBytes instead of failing
+	TruncateCells bool
+	// ClientIdentifier configures the CLIENT_IDENTIFIER Impala query option at session level, if not empty
+	ClientIdentifier string
+	// DecimalAs selects the Go type of DECIMAL values - one of the DecimalAs constants. Empty means DecimalAsString.
+	DecimalAs string
+	// DateAs selects the Go type of DATE values - one of the DateAs constants. Empty means DateAsString.
+	DateAs string
+	// SessionConfig configures arbitrary Impala query options at session level. Keys are case-insensitive.
+	// The options above take precedence over the same keys in SessionConfig, if they are set.
+	SessionConfig map[string]string
+	// RequestPool configures the REQUEST_POOL Impala query option at session level, if not empty
+	// https://impala.apache.org/docs/build/html/topics/impala_request_pool.html
+	RequestPool string
+	// Timezone configures the TIMEZONE Impala query option at session level, if not empty
+	// https://impala.apache.org/docs/build/html/topics/impala_timezone.html
+	Timezone string
+	// CharTrim enables removing the trailing spaces, which pad CHAR values to the column length
+	CharTrim bool
+	// NullAsZero returns the zero value of the column type instead of nil for NULL values of numeric and BOOLEAN columns
+	NullAsZero bool
+	// ResultChecksum enables computing a hash of the values returned by each result set, see ResultSet.Checksum
+	ResultChecksum bool
+	// SpoolResults configures the SPOOL_QUERY_RESULTS Impala property at session level, if enabled, and
+	// disables the client-side backoff between fetches that return no rows yet
+	SpoolResults bool
+	// ResultCacheSize, if positive, enables the Impala result cache for each statement, which keeps up to this
+	// number of rows so that fetching can be restarted from the first row with ResultSet.Rewind
+	ResultCacheSize int64
+	// ComplexJSON selects the formatting of ARRAY, MAP, and STRUCT values - one of the ComplexJSON constants.
+	// Empty means the values are returned as the server sends them.
+	ComplexJSON string
+	// TypeNames selects the type names reported by ColDesc.ColumnTypeDatabaseTypeName - one of the TypeNames
+	// constants. Empty means TypeNamesThrift.
+	TypeNames string
+	// ValueConverters customize the values of matching result columns. The first match applies.
+	ValueConverters []ValueConverter
+	// OnEvent, if not nil, is called synchronously for each Event
+	OnEvent func(Event)
 }
 
+// Modes for Options.ComplexJSON
+const (
+	ComplexJSONCompact = "compact"
+	ComplexJSONIndent  = "indent"
+)
+
+// Modes for Options.TypeNames
+const (
+	TypeNamesThrift = "thrift"
+	TypeNamesSQL    = "sql"
+)
+
+// Modes for Options.DecimalAs
+const (
+	DecimalAsString  = "string"
+	DecimalAsFloat64 = "float64"
+	DecimalAsRat     = "rat"
+)
+
+// Modes for Options.DateAs
+const (
+	DateAsString = "string"
+	DateAsTime   = "time"
+)
+
 // NewClient creates Hive Client
-func NewClient(client thrift.TClient, log *log.Logger, opts *Options) *Client {
+func NewClient(client thrift.TClient, log *Logger, opts *Options) *Client {
 	return &Client{
 		client: impalaservice.NewImpalaHiveServer2ServiceClient(client),
 		log:    log,
@@ -38,27 +136,118 @@ func NewClient(client thrift.TClient, log *log.Logger, opts *Options) *Client {
 }
 
 // OpenSession creates new hive session
-func (c *Client) OpenSession(ctx context.Context) (*Session, error) {
+// clientProtocol is the HiveServer2 protocol version, which the driver requests. Servers return column-based
+// results in protocol V6 and later. TFetchResultsReq has no field to select the encoding, so the protocol version
+// is the only way to request it.
+const clientProtocol = cli_service.TProtocolVersion_HIVE_CLI_SERVICE_PROTOCOL_V7
 
-	cfg := map[string]string{
-		"MEM_LIMIT":       c.opts.MemLimit,
-		"QUERY_TIMEOUT_S": strconv.Itoa(c.opts.QueryTimeout),
+// sessionConfig returns the configuration of new sessions: Options.SessionConfig with the dedicated options on top
+func (c *Client) sessionConfig() map[string]string {
+	cfg := make(map[string]string, len(c.opts.SessionConfig)+6)
+	for key, value := range c.opts.SessionConfig {
+		// Impala query options are case-insensitive, so the keys are normalized for the precedence below
+		cfg[strings.ToUpper(key)] = value
+	}
+	// MEM_LIMIT and QUERY_TIMEOUT_S are always sent, even if empty or 0, unless given in SessionConfig
+	if _, ok := cfg["MEM_LIMIT"]; !ok || c.opts.MemLimit != "" {
+		cfg["MEM_LIMIT"] = c.opts.MemLimit
+	}
+	if _, ok := cfg["QUERY_TIMEOUT_S"]; !ok || c.opts.QueryTimeout != 0 {
+		cfg["QUERY_TIMEOUT_S"] = strconv.Itoa(c.opts.QueryTimeout)
+	}
+	if c.opts.ClientIdentifier != "" {
+		// Unlike the options above, CLIENT_IDENTIFIER is sent only if set because older Impala versions
+		// may not recognize it.
+		cfg["CLIENT_IDENTIFIER"] = c.opts.ClientIdentifier
 	}
+	if c.opts.RequestPool != "" {
+		cfg["REQUEST_POOL"] = c.opts.RequestPool
+	}
+	if c.opts.SpoolResults {
+		cfg["SPOOL_QUERY_RESULTS"] = "true"
+	}
+	if c.opts.Timezone != "" {
+		cfg["TIMEZONE"] = c.opts.Timezone
+	}
+	return cfg
+}
+
+func (c *Client) OpenSession(ctx context.Context) (*Session, error) {
+	cfg := c.sessionConfig()
 
 	req := cli_service.TOpenSessionReq{
-		ClientProtocol: cli_service.TProtocolVersion_HIVE_CLI_SERVICE_PROTOCOL_V7,
+		ClientProtocol: clientProtocol,
 		Configuration:  cfg,
 	}
 
 	resp, err := c.client.OpenSession(ctx, &req)
-	if err != nil {
-		return nil, err
+	if err == nil {
+		err = checkSessionLimit(checkStatus(resp))
 	}
-	if err = checkStatus(resp); err != nil {
+	if err != nil {
+		c.emit(Event{Phase: PhaseOpen, Err: err})
 		return nil, err
 	}
 
-	c.log.Printf("open session: %s", guid(resp.SessionHandle.GetSessionId().GUID))
-	c.log.Printf("session config: %v", resp.Configuration)
+	c.log.Infof("open session: %s", guid(resp.SessionHandle.GetSessionId().GUID))
+	if resp.ServerProtocolVersion < cli_service.TProtocolVersion_HIVE_CLI_SERVICE_PROTOCOL_V6 {
+		// results are row-based in older protocols so fetching them will fail with ErrRowBasedResults
+		c.log.Errorf("server protocol %v doesn't support column-based results", resp.ServerProtocolVersion)
+	}
+	if c.caps == nil {
+		if _, err = c.probeCapabilities(ctx, resp.SessionHandle); err != nil {
+			c.log.Errorf("failed to detect server capabilities: %v", err)
+		}
+	}
+	c.emit(Event{Phase: PhaseOpen})
+	c.log.Infof("session config: %v", resp.Configuration)
 	return &Session{h: resp.SessionHandle, hive: c}, nil
 }
+
+// CancelOperation cancels the given operation, which may have been started by another Client
+// connected to the same server. Client doesn't need an open session to cancel operations.
+func (c *Client) CancelOperation(ctx context.Context, op *Operation) error {
+	c.log.Infof("cancel operation: %v", guid(op.h.OperationId.GUID))
+	req := cli_service.TCancelOperationReq{
+		OperationHandle: op.h,
+	}
+	resp, err := c.client.CancelOperation(ctx, &req)
+	if err != nil {
+		return err
+	}
+	return checkStatus(resp)
+}
+
+// AttachOperation returns the operation, identified by a handle from Operation.Handle, so its results can
+// be fetched, e.g. after Rows were detached from it. The operation must have a result set.
+func (c *Client) AttachOperation(handle string) (*Operation, error) {
+	guidHex, secretHex, found := strings.Cut(handle, ":")
+	guidBytes, guidErr := hex.DecodeString(guidHex)
+	secret, secretErr := hex.DecodeString(secretHex)
+	if !found || guidErr != nil || secretErr != nil || len(guidBytes) != 16 {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidHandle, handle)
+	}
+	h := &cli_service.TOperationHandle{
+		OperationId:   &cli_service.THandleIdentifier{GUID: guidBytes, Secret: secret},
+		OperationType: cli_service.TOperationType_EXECUTE_STATEMENT,
+		HasResultSet:  true,
+	}
+	c.log.Infof("attach operation: %s", guid(guidBytes))
+	return &Operation{h: h, hive: c}, nil
+}
+
+// checkSessionLimit wraps ErrSessionLimit around err if the server rejected the session because of a limit
+func checkSessionLimit(err error) error {
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) {
+		return err
+	}
+	status := statusErr.Status()
+	msg := strings.ToLower(status.GetErrorMessage())
+	if lo.SomeBy(sessionLimitMessages, func(m string) bool {
+		return strings.Contains(msg, m)
+	}) {
+		return fmt.Errorf("%w: %w", ErrSessionLimit, err)
+	}
+	return err
+}
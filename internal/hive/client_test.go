@@ -0,0 +1,268 @@
+package hive
+
+import (
+	"context"
+	"errors"
+	"log"
+	"testing"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	"github.com/sclgo/impala-go/internal/generated/cli_service"
+	"github.com/sclgo/impala-go/internal/generated/impalaservice"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_OpenSession(t *testing.T) {
+	openSession := func(t *testing.T, opts *Options) map[string]string {
+		mock := &sessionThriftClient{}
+		client := &Client{
+			client: mock,
+			opts:   opts,
+			log:    NewLogger(log.Default(), LogLevelDebug),
+		}
+		_, err := client.OpenSession(context.Background())
+		require.NoError(t, err)
+		require.NotNil(t, mock.req)
+		return mock.req.Configuration
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		cfg := openSession(t, &Options{})
+		require.Equal(t, map[string]string{"MEM_LIMIT": "", "QUERY_TIMEOUT_S": "0"}, cfg)
+	})
+
+	t.Run("client identifier", func(t *testing.T) {
+		cfg := openSession(t, &Options{ClientIdentifier: "etl-job"})
+		require.Equal(t, "etl-job", cfg["CLIENT_IDENTIFIER"])
+	})
+
+	t.Run("request pool", func(t *testing.T) {
+		cfg := openSession(t, &Options{RequestPool: "root.etl"})
+		require.Equal(t, "root.etl", cfg["REQUEST_POOL"])
+	})
+
+	t.Run("timezone", func(t *testing.T) {
+		cfg := openSession(t, &Options{Timezone: "Europe/Berlin"})
+		require.Equal(t, "Europe/Berlin", cfg["TIMEZONE"])
+	})
+
+	t.Run("protocol", func(t *testing.T) {
+		mock := &sessionThriftClient{}
+		client := &Client{client: mock, opts: &Options{}, log: NewLogger(log.Default(), LogLevelError)}
+		_, err := client.OpenSession(context.Background())
+		require.NoError(t, err)
+		// protocol V6 and later use column-based results, which ResultSet requires
+		require.GreaterOrEqual(t, mock.req.ClientProtocol, cli_service.TProtocolVersion_HIVE_CLI_SERVICE_PROTOCOL_V6)
+	})
+
+	t.Run("spool results", func(t *testing.T) {
+		cfg := openSession(t, &Options{SpoolResults: true})
+		require.Equal(t, "true", cfg["SPOOL_QUERY_RESULTS"])
+	})
+
+	t.Run("session config", func(t *testing.T) {
+		cfg := openSession(t, &Options{
+			SessionConfig: map[string]string{"mt_dop": "4", "MEM_LIMIT": "2g", "QUERY_TIMEOUT_S": "60"},
+		})
+		require.Equal(t, map[string]string{"MT_DOP": "4", "MEM_LIMIT": "2g", "QUERY_TIMEOUT_S": "60"}, cfg)
+	})
+
+	t.Run("session config precedence", func(t *testing.T) {
+		cfg := openSession(t, &Options{
+			MemLimit:     "1g",
+			QueryTimeout: 30,
+			RequestPool:  "root.etl",
+			SessionConfig: map[string]string{
+				"MEM_LIMIT": "2g", "query_timeout_s": "60", "REQUEST_POOL": "root.adhoc", "TIMEZONE": "UTC",
+			},
+		})
+		require.Equal(t, map[string]string{
+			"MEM_LIMIT": "1g", "QUERY_TIMEOUT_S": "30", "REQUEST_POOL": "root.etl", "TIMEZONE": "UTC",
+		}, cfg)
+	})
+}
+
+func TestSession_ExecuteStatement_ResultCache(t *testing.T) {
+	execute := func(t *testing.T, opts *Options, queryOptions map[string]string) map[string]string {
+		mock := &sessionThriftClient{}
+		client := &Client{
+			client: mock,
+			opts:   opts,
+			log:    NewLogger(log.Default(), LogLevelError),
+		}
+		session, err := client.OpenSession(context.Background())
+		require.NoError(t, err)
+		_, err = session.ExecuteStatement(context.Background(), "SELECT 1", queryOptions)
+		require.NoError(t, err)
+		return mock.execReq.ConfOverlay
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		require.Nil(t, execute(t, &Options{}, nil))
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		queryOptions := map[string]string{"MT_DOP": "2"}
+		overlay := execute(t, &Options{ResultCacheSize: 1000}, queryOptions)
+		require.Equal(t, map[string]string{"MT_DOP": "2", "impala.resultset.cache.size": "1000"}, overlay)
+		require.Len(t, queryOptions, 1, "the caller's map must not be modified")
+	})
+}
+
+type sessionThriftClient struct {
+	impalaservice.ImpalaHiveServer2Service
+
+	req     *cli_service.TOpenSessionReq
+	execReq *cli_service.TExecuteStatementReq
+	// pingErr, if not nil, is returned by PingImpalaHS2Service
+	pingErr error
+	// closeMethod is the method last used to close an operation
+	closeMethod string
+}
+
+func (m *sessionThriftClient) PingImpalaHS2Service(context.Context, *impalaservice.TPingImpalaHS2ServiceReq) (*impalaservice.TPingImpalaHS2ServiceResp, error) {
+	if m.pingErr != nil {
+		return nil, m.pingErr
+	}
+	return &impalaservice.TPingImpalaHS2ServiceResp{
+		Status:  &cli_service.TStatus{StatusCode: cli_service.TStatusCode_SUCCESS_STATUS},
+		Version: lo.ToPtr("impalad version 4.5.0"),
+	}, nil
+}
+
+func (m *sessionThriftClient) CloseOperation(context.Context, *cli_service.TCloseOperationReq) (*cli_service.TCloseOperationResp, error) {
+	m.closeMethod = "CloseOperation"
+	return &cli_service.TCloseOperationResp{
+		Status: &cli_service.TStatus{StatusCode: cli_service.TStatusCode_SUCCESS_STATUS},
+	}, nil
+}
+
+func (m *sessionThriftClient) CloseImpalaOperation(context.Context, *impalaservice.TCloseImpalaOperationReq) (*impalaservice.TCloseImpalaOperationResp, error) {
+	m.closeMethod = "CloseImpalaOperation"
+	return &impalaservice.TCloseImpalaOperationResp{
+		Status: &cli_service.TStatus{StatusCode: cli_service.TStatusCode_SUCCESS_STATUS},
+	}, nil
+}
+
+func (m *sessionThriftClient) ExecuteStatement(_ context.Context, req *cli_service.TExecuteStatementReq) (*cli_service.TExecuteStatementResp, error) {
+	m.execReq = req
+	return &cli_service.TExecuteStatementResp{
+		Status: &cli_service.TStatus{StatusCode: cli_service.TStatusCode_SUCCESS_STATUS},
+		OperationHandle: &cli_service.TOperationHandle{
+			OperationId: &cli_service.THandleIdentifier{GUID: make([]byte, 16)},
+		},
+	}, nil
+}
+
+func (m *sessionThriftClient) OpenSession(_ context.Context, req *cli_service.TOpenSessionReq) (*cli_service.TOpenSessionResp, error) {
+	m.req = req
+	sessionID := uuid.New()
+	return &cli_service.TOpenSessionResp{
+		Status: &cli_service.TStatus{
+			StatusCode: cli_service.TStatusCode_SUCCESS_STATUS,
+		},
+		ServerProtocolVersion: cli_service.TProtocolVersion_HIVE_CLI_SERVICE_PROTOCOL_V7,
+		SessionHandle: &cli_service.TSessionHandle{
+			SessionId: &cli_service.THandleIdentifier{
+				GUID: sessionID[:],
+			},
+		},
+	}, nil
+}
+
+func TestClient_Capabilities(t *testing.T) {
+	ctx := context.Background()
+	run := func(t *testing.T, mock *sessionThriftClient) *Session {
+		client := &Client{client: mock, opts: &Options{}, log: NewLogger(log.Default(), LogLevelError)}
+		session, err := client.OpenSession(ctx)
+		require.NoError(t, err)
+		op, err := session.ExecuteStatement(ctx, "INSERT INTO t VALUES (1)", nil)
+		require.NoError(t, err)
+		_, err = op.Close(ctx)
+		require.NoError(t, err)
+		return session
+	}
+
+	t.Run("impala", func(t *testing.T) {
+		mock := &sessionThriftClient{}
+		caps, err := run(t, mock).Capabilities(ctx)
+		require.NoError(t, err)
+		require.Equal(t, Capabilities{ImpalaExtension: true, Version: "impalad version 4.5.0"}, caps)
+		require.Equal(t, "CloseImpalaOperation", mock.closeMethod)
+	})
+
+	t.Run("hive", func(t *testing.T) {
+		mock := &sessionThriftClient{
+			pingErr: thrift.NewTApplicationException(thrift.UNKNOWN_METHOD, "Unknown function PingImpalaHS2Service"),
+		}
+		caps, err := run(t, mock).Capabilities(ctx)
+		require.NoError(t, err)
+		require.False(t, caps.ImpalaExtension)
+		require.Equal(t, "CloseOperation", mock.closeMethod)
+	})
+
+	t.Run("probe failed", func(t *testing.T) {
+		mock := &sessionThriftClient{pingErr: errors.New("connection reset")}
+		session := run(t, mock)
+		// the Impala extension is assumed
+		require.Equal(t, "CloseImpalaOperation", mock.closeMethod)
+		_, err := session.Capabilities(ctx)
+		require.ErrorContains(t, err, "connection reset")
+
+		// detected again on demand
+		mock.pingErr = nil
+		caps, err := session.Capabilities(ctx)
+		require.NoError(t, err)
+		require.True(t, caps.ImpalaExtension)
+	})
+}
+
+func TestClient_CancelOperation(t *testing.T) {
+	mock := &cancelThriftClient{}
+	client := &Client{
+		client: mock,
+		opts:   &Options{},
+		log:    NewLogger(log.Default(), LogLevelDebug),
+	}
+	h := &cli_service.TOperationHandle{
+		OperationId: &cli_service.THandleIdentifier{GUID: make([]byte, 16)},
+	}
+	err := client.CancelOperation(context.Background(), &Operation{h: h})
+	require.NoError(t, err)
+	require.Same(t, h, mock.req.OperationHandle)
+}
+
+func TestClient_AttachOperation(t *testing.T) {
+	client := &Client{opts: &Options{}, log: NewLogger(log.Default(), LogLevelError)}
+	id := uuid.New()
+	op := &Operation{h: &cli_service.TOperationHandle{
+		OperationId: &cli_service.THandleIdentifier{GUID: id[:], Secret: []byte{1, 2, 3}},
+	}}
+
+	attached, err := client.AttachOperation(op.Handle())
+	require.NoError(t, err)
+	require.Equal(t, op.h.OperationId, attached.h.OperationId)
+	require.True(t, attached.HasResultSet())
+
+	for _, handle := range []string{"", "abc", "zz:00", "00:00", op.Handle() + "x"} {
+		_, err = client.AttachOperation(handle)
+		require.ErrorIs(t, err, ErrInvalidHandle, handle)
+	}
+}
+
+type cancelThriftClient struct {
+	impalaservice.ImpalaHiveServer2Service
+
+	req *cli_service.TCancelOperationReq
+}
+
+func (m *cancelThriftClient) CancelOperation(_ context.Context, req *cli_service.TCancelOperationReq) (*cli_service.TCancelOperationResp, error) {
+	m.req = req
+	return &cli_service.TCancelOperationResp{
+		Status: &cli_service.TStatus{
+			StatusCode: cli_service.TStatusCode_SUCCESS_STATUS,
+		},
+	}, nil
+}
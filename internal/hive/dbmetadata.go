@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"iter"
+	"strings"
 	"time"
 
 	"github.com/samber/lo"
@@ -23,8 +24,64 @@ type ColumnName struct {
 	Schema     string
 	TableName  string
 	ColumnName string
+
+	// Position is the ordinal position of the column within the table, as reported by the server
+	Position int
+	// DatabaseTypeName is the type of the column without any qualifiers e.g. DECIMAL, not DECIMAL(10,2).
+	// It matches the DatabaseTypeName of the column in a query result.
+	DatabaseTypeName string
+
+	// Length is set for CHAR and VARCHAR columns
+	Length    int64
+	HasLength bool
+
+	// Precision and Scale are set for DECIMAL columns
+	Precision         int64
+	Scale             int64
+	HasPrecisionScale bool
+}
+
+// SQLTypeName returns the type of the column as written in Impala DDL, with the length of CHAR and VARCHAR,
+// and the precision and scale of DECIMAL e.g. DECIMAL(10,2)
+func (c ColumnName) SQLTypeName() string {
+	switch {
+	case c.HasPrecisionScale:
+		return fmt.Sprintf("%s(%d,%d)", c.DatabaseTypeName, c.Precision, c.Scale)
+	case c.HasLength:
+		return fmt.Sprintf("%s(%d)", c.DatabaseTypeName, c.Length)
+	default:
+		return c.DatabaseTypeName
+	}
+}
+
+// TypeInfo describes a data type supported by the server, following JDBC DatabaseMetaData.getTypeInfo
+type TypeInfo struct {
+	Name string
+	// DataType is the SQL data type code from java.sql.Types
+	DataType int
+	// Precision is the maximum precision of the type
+	Precision     int
+	Nullable      bool
+	CaseSensitive bool
 }
 
+// Indexes of the columns in the result of GetTypeInfo.
+const (
+	getTypeInfoTypeName      = 0
+	getTypeInfoDataType      = 1
+	getTypeInfoPrecision     = 2
+	getTypeInfoNullable      = 6
+	getTypeInfoCaseSensitive = 7
+)
+
+// Indexes of the columns in the result of GetColumns. The result follows JDBC DatabaseMetaData.getColumns
+const (
+	getColumnsTypeName        = 5
+	getColumnsColumnSize      = 6
+	getColumnsDecimalDigits   = 8
+	getColumnsOrdinalPosition = 16
+)
+
 // DBMetadata exposes the database schema. It does not own the underlying client and session
 // so they must be open while the objects and the data iterators are used.
 type DBMetadata struct {
@@ -65,13 +122,21 @@ func (m DBMetadata) GetColumnsSeq(ctx context.Context, schemaPattern string, tab
 		hive: m.hive,
 	}
 
-	rs, err := op.FetchResults(ctx, tableResultSchema)
+	// unlike the other metadata operations, the result schema of GetColumns contains non-string columns
+	// so we retrieve it from the server, instead of hardcoding it.
+	schema, err := op.GetResultSetMetadata(ctx)
+	if err != nil {
+		release(ctx, op)
+		return nil, &err
+	}
+
+	rs, err := op.fetchMetadata(ctx, schema)
 	if err != nil {
 		return nil, &err
 	}
 
 	return func(yield func(name ColumnName) bool) {
-		err = read(ctx, op, rs, 4, readColumn, yield)
+		err = read(ctx, op, rs, len(schema.Columns), readColumn, yield)
 	}, &err
 }
 
@@ -96,7 +161,7 @@ func (m DBMetadata) GetTablesSeq(ctx context.Context, schemaPattern string, tabl
 		hive: m.hive,
 	}
 
-	rs, err := op.FetchResults(ctx, tableResultSchema)
+	rs, err := op.fetchMetadata(ctx, tableResultSchema)
 	if err != nil {
 		return nil, &err
 	}
@@ -124,7 +189,7 @@ func (m DBMetadata) GetSchemasSeq(ctx context.Context, pattern string) (iter.Seq
 		hive: m.hive,
 	}
 
-	rs, err := op.FetchResults(ctx, tableResultSchema)
+	rs, err := op.fetchMetadata(ctx, tableResultSchema)
 	if err != nil {
 		return nil, &err
 	}
@@ -134,6 +199,41 @@ func (m DBMetadata) GetSchemasSeq(ctx context.Context, pattern string) (iter.Seq
 	}, &err
 }
 
+// GetTypeInfoSeq returns the data types supported by the server as an iterator.
+func (m DBMetadata) GetTypeInfoSeq(ctx context.Context) (iter.Seq[TypeInfo], *error) {
+	req := cli_service.TGetTypeInfoReq{
+		SessionHandle: m.h,
+	}
+
+	resp, err := m.hive.client.GetTypeInfo(ctx, &req)
+	if err != nil {
+		return nil, &err
+	}
+	if err = checkStatus(resp); err != nil {
+		return nil, &err
+	}
+	op := &Operation{
+		h:    resp.OperationHandle,
+		hive: m.hive,
+	}
+
+	// like GetColumns, the result contains non-string columns
+	schema, err := op.GetResultSetMetadata(ctx)
+	if err != nil {
+		release(ctx, op)
+		return nil, &err
+	}
+
+	rs, err := op.fetchMetadata(ctx, schema)
+	if err != nil {
+		return nil, &err
+	}
+
+	return func(yield func(TypeInfo) bool) {
+		err = read(ctx, op, rs, len(schema.Columns), readTypeInfo, yield)
+	}, &err
+}
+
 func read[T any](ctx context.Context, op *Operation, rs *ResultSet, rowLength int, readf func([]driver.Value) T, yield func(T) bool) error {
 	row := make([]driver.Value, rowLength)
 	for i := range row {
@@ -149,14 +249,23 @@ func read[T any](ctx context.Context, op *Operation, rs *ResultSet, rowLength in
 	if errors.Is(err, io.EOF) {
 		err = nil
 	}
-	if ctx.Err() != nil {
+	if err == nil && ctx.Err() != nil {
 		err = ctx.Err()
 	}
-	_ = withFallbackCtx(ctx, func(ctx context.Context) error {
-		_, err := op.Close(ctx)
+	release(ctx, op)
+	return err
+}
+
+// release closes op. If ctx is done, release cancels op first so the server stops working on it,
+// e.g. listing a large catalog.
+func release(ctx context.Context, op *Operation) {
+	_ = withFallbackCtx(ctx, func(cleanupCtx context.Context) error {
+		if ctx.Err() != nil {
+			_ = op.Cancel(cleanupCtx)
+		}
+		_, err := op.Close(cleanupCtx)
 		return err
 	})
-	return err
 }
 
 func readTable(row []driver.Value) TableName {
@@ -168,11 +277,65 @@ func readTable(row []driver.Value) TableName {
 }
 
 func readColumn(row []driver.Value) ColumnName {
-	return ColumnName{
+	col := ColumnName{
 		Schema:     fmt.Sprintf("%v", row[1]),
 		TableName:  fmt.Sprintf("%v", row[2]),
 		ColumnName: fmt.Sprintf("%v", row[3]),
-		// There is no column 4
+	}
+	if len(row) <= getColumnsOrdinalPosition {
+		return col // the server returned only the basic columns
+	}
+	col.Position = int(toInt64(row[getColumnsOrdinalPosition]))
+
+	typeName := strings.ToUpper(fmt.Sprintf("%v", row[getColumnsTypeName]))
+	typeName, _, _ = strings.Cut(typeName, "(")
+	col.DatabaseTypeName = typeName
+
+	// We reuse the same logic as for query results by converting the size columns to type qualifiers
+	qualifiers := map[string]*cli_service.TTypeQualifierValue{}
+	columnSize := &cli_service.TTypeQualifierValue{I32Value: lo.ToPtr(int32(toInt64(row[getColumnsColumnSize])))}
+	switch typeName {
+	case "CHAR", "VARCHAR":
+		qualifiers["characterMaximumLength"] = columnSize
+	case "DECIMAL":
+		qualifiers["precision"] = columnSize
+		qualifiers["scale"] = &cli_service.TTypeQualifierValue{I32Value: lo.ToPtr(int32(toInt64(row[getColumnsDecimalDigits])))}
+	}
+	cd := &ColDesc{}
+	cd.setQualifiers(qualifiers)
+	col.Length, col.HasLength = cd.Length, cd.HasLength
+	col.Precision, col.Scale, col.HasPrecisionScale = cd.Precision, cd.Scale, cd.HasPrecisionScale
+	return col
+}
+
+func readTypeInfo(row []driver.Value) TypeInfo {
+	res := TypeInfo{
+		Name: fmt.Sprintf("%v", row[getTypeInfoTypeName]),
+	}
+	if len(row) <= getTypeInfoCaseSensitive {
+		return res
+	}
+	res.DataType = int(toInt64(row[getTypeInfoDataType]))
+	res.Precision = int(toInt64(row[getTypeInfoPrecision]))
+	// 1 is typeNullable in JDBC; 0 is typeNoNulls and 2 is typeNullableUnknown
+	res.Nullable = toInt64(row[getTypeInfoNullable]) == 1
+	res.CaseSensitive, _ = row[getTypeInfoCaseSensitive].(bool)
+	return res
+}
+
+// toInt64 converts integer values returned by ResultSet to int64. Other values, including nil, become 0.
+func toInt64(v driver.Value) int64 {
+	switch n := v.(type) {
+	case int8:
+		return int64(n)
+	case int16:
+		return int64(n)
+	case int32:
+		return int64(n)
+	case int64:
+		return n
+	default:
+		return 0
 	}
 }
 
@@ -2,11 +2,14 @@ package hive
 
 import (
 	"context"
+	"database/sql/driver"
 	"log"
 	"slices"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/samber/lo"
 	"github.com/sclgo/impala-go/internal/generated/cli_service"
 	"github.com/sclgo/impala-go/internal/generated/impalaservice"
 	"github.com/stretchr/testify/require"
@@ -18,7 +21,7 @@ func TestDBMetadata_GetTablesSeq(t *testing.T) {
 		hive := &Client{
 			client: mock,
 			opts:   &Options{},
-			log:    log.Default(),
+			log:    NewLogger(log.Default(), LogLevelDebug),
 		}
 		dbMeta := DBMetadata{
 			h:    &cli_service.TSessionHandle{},
@@ -44,14 +47,106 @@ func TestDBMetadata_GetTablesSeq(t *testing.T) {
 		require.NoError(t, *errPtr)
 		require.NotZero(t, mock.closeCalls)
 	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		mock := &thriftClient{
+			getTablesStatus: cli_service.TStatusCode_SUCCESS_STATUS,
+			fetchStalls:     true,
+		}
+		hive := &Client{
+			client: mock,
+			opts:   &Options{},
+			log:    NewLogger(log.Default(), LogLevelDebug),
+		}
+		dbMeta := DBMetadata{
+			h:    &cli_service.TSessionHandle{},
+			hive: hive,
+		}
+
+		opuuid := uuid.New()
+		mock.getTablesResp = &cli_service.TGetTablesResp{
+			OperationHandle: &cli_service.TOperationHandle{
+				OperationId: &cli_service.THandleIdentifier{
+					GUID: opuuid[:],
+				},
+			},
+			Status: &cli_service.TStatus{
+				StatusCode: mock.getTablesStatus,
+			},
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		seq, errPtr := dbMeta.GetTablesSeq(ctx, "", "")
+		require.NoError(t, *errPtr)
+		require.Empty(t, slices.Collect(seq))
+		require.ErrorIs(t, *errPtr, context.DeadlineExceeded)
+		require.Equal(t, 1, mock.cancelCalls)
+		require.Equal(t, 1, mock.closeCalls)
+	})
+}
+
+func TestReadColumn(t *testing.T) {
+	row := func(typeName string, size int32, digits int32) []driver.Value {
+		res := make([]driver.Value, 23)
+		for i := range res {
+			res[i] = ""
+		}
+		res[1], res[2], res[3] = "default", "tbl", "col"
+		res[getColumnsTypeName] = typeName
+		res[getColumnsColumnSize] = size
+		res[getColumnsDecimalDigits] = digits
+		res[getColumnsOrdinalPosition] = int32(3)
+		return res
+	}
+
+	t.Run("decimal", func(t *testing.T) {
+		col := readColumn(row("DECIMAL", 10, 2))
+		require.Equal(t, ColumnName{
+			Schema: "default", TableName: "tbl", ColumnName: "col", Position: 3, DatabaseTypeName: "DECIMAL",
+			Precision: 10, Scale: 2, HasPrecisionScale: true,
+		}, col)
+	})
+
+	t.Run("char", func(t *testing.T) {
+		col := readColumn(row("char(5)", 5, 0))
+		require.Equal(t, "CHAR", col.DatabaseTypeName)
+		require.True(t, col.HasLength)
+		require.EqualValues(t, 5, col.Length)
+		require.False(t, col.HasPrecisionScale)
+	})
+
+	t.Run("int", func(t *testing.T) {
+		col := readColumn(row("INT", 10, 0))
+		require.Equal(t, "INT", col.DatabaseTypeName)
+		require.False(t, col.HasLength)
+		require.False(t, col.HasPrecisionScale)
+	})
+
+	t.Run("basic columns only", func(t *testing.T) {
+		col := readColumn([]driver.Value{"", "default", "tbl", "col"})
+		require.Equal(t, ColumnName{Schema: "default", TableName: "tbl", ColumnName: "col"}, col)
+	})
+}
+
+func TestReadTypeInfo(t *testing.T) {
+	row := []driver.Value{"DECIMAL", int32(3), int32(38), nil, nil, nil, int16(1), false, int16(3)}
+	require.Equal(t, TypeInfo{
+		Name:      "DECIMAL",
+		DataType:  3,
+		Precision: 38,
+		Nullable:  true,
+	}, readTypeInfo(row))
 }
 
 type thriftClient struct {
 	impalaservice.ImpalaHiveServer2Service
 
 	closeCalls      int
+	cancelCalls     int
 	getTablesResp   *cli_service.TGetTablesResp
 	getTablesStatus cli_service.TStatusCode
+	// fetchStalls makes FetchResults report that the results are not ready yet, forever
+	fetchStalls bool
 }
 
 func (m *thriftClient) GetTables(context.Context, *cli_service.TGetTablesReq) (*cli_service.TGetTablesResp, error) {
@@ -61,7 +156,7 @@ func (m *thriftClient) GetTables(context.Context, *cli_service.TGetTablesReq) (*
 func (m *thriftClient) FetchResults(context.Context, *cli_service.TFetchResultsReq) (*cli_service.TFetchResultsResp, error) {
 	return &cli_service.TFetchResultsResp{
 		Status: &cli_service.TStatus{
-			StatusCode: m.getTablesStatus,
+			StatusCode: lo.Ternary(m.fetchStalls, cli_service.TStatusCode_STILL_EXECUTING_STATUS, m.getTablesStatus),
 		},
 		HasMoreRows: nil,
 		Results:     nil,
@@ -76,3 +171,12 @@ func (m *thriftClient) CloseImpalaOperation(context.Context, *impalaservice.TClo
 		},
 	}, nil
 }
+
+func (m *thriftClient) CancelOperation(context.Context, *cli_service.TCancelOperationReq) (*cli_service.TCancelOperationResp, error) {
+	m.cancelCalls++
+	return &cli_service.TCancelOperationResp{
+		Status: &cli_service.TStatus{
+			StatusCode: cli_service.TStatusCode_SUCCESS_STATUS,
+		},
+	}, nil
+}
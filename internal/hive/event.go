@@ -0,0 +1,78 @@
+package hive
+
+import "github.com/sclgo/impala-go/internal/generated/cli_service"
+
+// Phases of Event
+const (
+	PhaseOpen    = "open"
+	PhaseExecute = "execute"
+	PhaseQueued  = "queued"
+	PhaseFetch   = "fetch"
+	PhaseClose   = "close"
+)
+
+// Event describes a step in the lifecycle of a session or a query. Events are emitted where the matching log
+// messages are written, regardless of the log level.
+type Event struct {
+	// Phase is one of the Phase constants
+	Phase string
+	// QueryID identifies the operation on the server, in the same format as in the log. Empty for PhaseOpen.
+	QueryID string
+	// State is the operation state reported by the server e.g. QueryStateFinished, if known
+	State QueryState
+	// Rows is the number of rows fetched in PhaseFetch or the number of rows affected in PhaseClose
+	Rows int64
+	// Err is the error, if the step failed
+	Err error
+}
+
+// QueryState is the state of an operation on the server. The values match the names of the states in
+// the HiveServer2 API e.g. FINISHED_STATE.
+type QueryState string
+
+// States of operations
+const (
+	QueryStateInitialized QueryState = "INITIALIZED_STATE"
+	QueryStatePending     QueryState = "PENDING_STATE"
+	QueryStateRunning     QueryState = "RUNNING_STATE"
+	QueryStateFinished    QueryState = "FINISHED_STATE"
+	QueryStateCanceled    QueryState = "CANCELED_STATE"
+	QueryStateClosed      QueryState = "CLOSED_STATE"
+	QueryStateError       QueryState = "ERROR_STATE"
+	QueryStateUnknown     QueryState = "UNKNOWN_STATE"
+)
+
+// String implements fmt.Stringer
+func (s QueryState) String() string {
+	return string(s)
+}
+
+// queryState maps the state in the generated Thrift API to QueryState
+func queryState(state cli_service.TOperationState) QueryState {
+	switch state {
+	case cli_service.TOperationState_INITIALIZED_STATE:
+		return QueryStateInitialized
+	case cli_service.TOperationState_PENDING_STATE:
+		return QueryStatePending
+	case cli_service.TOperationState_RUNNING_STATE:
+		return QueryStateRunning
+	case cli_service.TOperationState_FINISHED_STATE:
+		return QueryStateFinished
+	case cli_service.TOperationState_CANCELED_STATE:
+		return QueryStateCanceled
+	case cli_service.TOperationState_CLOSED_STATE:
+		return QueryStateClosed
+	case cli_service.TOperationState_ERROR_STATE:
+		return QueryStateError
+	default:
+		// including UKNOWN_STATE, as it is misspelled in the API
+		return QueryStateUnknown
+	}
+}
+
+// emit calls Options.OnEvent, if configured
+func (c *Client) emit(e Event) {
+	if c.opts.OnEvent != nil {
+		c.opts.OnEvent(e)
+	}
+}
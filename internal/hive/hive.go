@@ -3,15 +3,34 @@ package hive
 import (
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/samber/lo"
 	"github.com/sclgo/impala-go/internal/generated/cli_service"
 )
 
 const (
 	// TimestampFormat is JDBC compliant timestamp format
 	TimestampFormat = "2006-01-02 15:04:05.999999999"
+	// DateFormat is the format of DATE values
+	DateFormat = time.DateOnly
 )
 
+// ErrObjectNotFound means the statement referenced a table, view or database that doesn't exist
+var ErrObjectNotFound = errors.New("impala: object not found")
+
+// sqlStateTableNotFound is the SQLSTATE for "base table or view not found"
+const sqlStateTableNotFound = "42S02"
+
+// notFoundMessages are the parts of the analysis errors, which Impala reports for missing tables and databases.
+// Impala usually reports the generic SQLSTATE HY000 so the message is the only reliable indicator.
+var notFoundMessages = []string{
+	"Could not resolve table reference",
+	"Table does not exist",
+	"Database does not exist",
+}
+
 // rpcResponse represents thrift rpc response
 type rpcResponse interface {
 	GetStatus() *cli_service.TStatus
@@ -46,7 +65,7 @@ func checkStatus(resp rpcResponse) (err error) {
 	default:
 		err = &StatusError{*status, fmt.Sprintf("unexpected code: %d; message: %s", code, status.GetErrorMessage())}
 	}
-	return wrapServerError(err)
+	return wrapServerError(checkNotFound(err, status.GetSqlState(), status.GetErrorMessage()))
 }
 
 func checkState(resp *cli_service.TGetOperationStatusResp) (err error) {
@@ -60,10 +79,24 @@ func checkState(resp *cli_service.TGetOperationStatusResp) (err error) {
 		// for example, if the error is discovered by Hive Metastore but not by Impala
 
 		err = fmt.Errorf("%v: %s", state, resp.GetErrorMessage())
+		err = checkNotFound(err, resp.GetSqlState(), resp.GetErrorMessage())
 	}
 	return wrapServerError(err)
 }
 
+// checkNotFound wraps ErrObjectNotFound around err if the server reported that a referenced object doesn't exist
+func checkNotFound(err error, sqlState string, msg string) error {
+	if err == nil {
+		return nil
+	}
+	if sqlState == sqlStateTableNotFound || (strings.Contains(msg, "AnalysisException") && lo.SomeBy(notFoundMessages, func(m string) bool {
+		return strings.Contains(msg, m)
+	})) {
+		return fmt.Errorf("%w: %w", ErrObjectNotFound, err)
+	}
+	return err
+}
+
 func wrapServerError(err error) error {
 	if err == nil {
 		return nil
@@ -0,0 +1,51 @@
+package hive
+
+import (
+	"testing"
+
+	"github.com/sclgo/impala-go/internal/generated/cli_service"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckStatus_ObjectNotFound(t *testing.T) {
+	status := func(sqlState string, msg string) *cli_service.TGetOperationStatusResp {
+		return &cli_service.TGetOperationStatusResp{
+			Status: &cli_service.TStatus{
+				StatusCode:   cli_service.TStatusCode_ERROR_STATUS,
+				SqlState:     &sqlState,
+				ErrorMessage: &msg,
+			},
+		}
+	}
+
+	t.Run("table", func(t *testing.T) {
+		err := checkStatus(status("HY000", "AnalysisException: Could not resolve table reference: 'missing'"))
+		require.ErrorIs(t, err, ErrObjectNotFound)
+		require.ErrorContains(t, err, "Could not resolve table reference: 'missing'")
+	})
+
+	t.Run("database", func(t *testing.T) {
+		err := checkStatus(status("HY000", "AnalysisException: Database does not exist: missing"))
+		require.ErrorIs(t, err, ErrObjectNotFound)
+	})
+
+	t.Run("sqlstate", func(t *testing.T) {
+		err := checkStatus(status(sqlStateTableNotFound, "Table not found"))
+		require.ErrorIs(t, err, ErrObjectNotFound)
+	})
+
+	t.Run("other error", func(t *testing.T) {
+		err := checkStatus(status("HY000", "AnalysisException: Syntax error in line 1"))
+		require.Error(t, err)
+		require.NotErrorIs(t, err, ErrObjectNotFound)
+	})
+
+	t.Run("error state", func(t *testing.T) {
+		msg := "AnalysisException: Table does not exist: default.missing"
+		err := checkState(&cli_service.TGetOperationStatusResp{
+			OperationState: cli_service.TOperationStatePtr(cli_service.TOperationState_ERROR_STATE),
+			ErrorMessage:   &msg,
+		})
+		require.ErrorIs(t, err, ErrObjectNotFound)
+	})
+}
@@ -0,0 +1,58 @@
+package hive
+
+import "log"
+
+// Supported levels of Logger, from the least to the most verbose
+const (
+	LogLevelError = "error"
+	LogLevelInfo  = "info"
+	LogLevelDebug = "debug"
+)
+
+const (
+	levelError = iota
+	levelInfo
+	levelDebug
+)
+
+// Logger writes the messages of the enabled levels to a log.Logger
+type Logger struct {
+	log   *log.Logger
+	level int
+}
+
+// NewLogger creates a Logger, which writes the messages of the given level, one of the LogLevel constants,
+// and less verbose levels to l. Empty means LogLevelInfo.
+func NewLogger(l *log.Logger, level string) *Logger {
+	res := &Logger{log: l}
+	switch level {
+	case LogLevelError:
+		res.level = levelError
+	case LogLevelDebug:
+		res.level = levelDebug
+	default:
+		res.level = levelInfo
+	}
+	return res
+}
+
+// Errorf logs failures and warnings, which need attention
+func (l *Logger) Errorf(format string, v ...any) {
+	l.printf(levelError, format, v...)
+}
+
+// Infof logs the lifecycle of sessions and operations
+func (l *Logger) Infof(format string, v ...any) {
+	l.printf(levelInfo, format, v...)
+}
+
+// Debugf logs details, which are too verbose for production use
+func (l *Logger) Debugf(format string, v ...any) {
+	l.printf(levelDebug, format, v...)
+}
+
+func (l *Logger) printf(level int, format string, v ...any) {
+	if level <= l.level {
+		l.log.Printf(format, v...)
+	}
+}
@@ -0,0 +1,31 @@
+package hive
+
+import (
+	"bytes"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogger(t *testing.T) {
+	tests := []struct {
+		level    string
+		expected string
+	}{
+		{LogLevelError, "e\n"},
+		{"", "e\ni\n"},
+		{LogLevelInfo, "e\ni\n"},
+		{LogLevelDebug, "e\ni\nd\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.level, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := NewLogger(log.New(&buf, "", 0), tt.level)
+			logger.Errorf("e")
+			logger.Infof("i")
+			logger.Debugf("d")
+			require.Equal(t, tt.expected, buf.String())
+		})
+	}
+}
@@ -1,8 +1,10 @@
 package hive
 
 import (
-	"database/sql"
+	"fmt"
+	"math/big"
 	"reflect"
+	"strings"
 	"time"
 
 	"github.com/sclgo/impala-go/internal/generated/cli_service"
@@ -28,10 +30,152 @@ type ColDesc struct {
 	Precision         int64
 	Scale             int64
 	HasPrecisionScale bool
+
+	// sqlTypeName enables reporting the type name with type qualifiers, see ColumnTypeDatabaseTypeName
+	sqlTypeName bool
+	// trimChar enables removing the trailing spaces in CHAR values
+	trimChar bool
+	// maxCellBytes is Options.MaxCellBytes for string columns; 0 means no limit
+	maxCellBytes int
+	// truncateCells is Options.TruncateCells
+	truncateCells bool
+	// nullZero returns the value for NULL values instead of nil, if Options.NullAsZero is enabled, see zeroFor
+	nullZero func() any
+	// jsonFormat is Options.ComplexJSON for complex type columns
+	jsonFormat string
+	// convertRaw and convert are the ValueConverter functions for the column, if any
+	convertRaw func(raw any) (any, bool, error)
+	convert    func(v any) (any, error)
+}
+
+// NewColDesc creates a column description using the same type mapping as query results.
+// typeName can be either an Impala type name like DECIMAL or a Thrift type id like DECIMAL_TYPE.
+// Type qualifiers, like length, precision, and scale, are not set.
+func NewColDesc(name string, typeName string) (*ColDesc, error) {
+	dbtype := strings.TrimSuffix(strings.ToUpper(typeName), "_TYPE")
+	typeId, err := cli_service.TTypeIdFromString(dbtype + "_TYPE")
+	if err != nil {
+		return nil, fmt.Errorf("unknown type %s: %w", typeName, err)
+	}
+	return &ColDesc{
+		Name:             name,
+		DatabaseTypeName: dbtype,
+		ScanType:         typeOf(&cli_service.TPrimitiveTypeEntry{Type: typeId}),
+	}, nil
+}
+
+// setQualifiers populates length, precision, and scale from type qualifiers, reported by the server
+func (cd *ColDesc) setQualifiers(typeQualifiers map[string]*cli_service.TTypeQualifierValue) {
+	cd.Length, cd.HasLength = getMaxLength(typeQualifiers)
+	cd.Precision, cd.Scale, cd.HasPrecisionScale = getPrecisionScale(typeQualifiers)
+}
+
+// ColumnTypeDatabaseTypeName returns the type name reported to database/sql. It is DatabaseTypeName, unless
+// Options.TypeNames is TypeNamesSQL. Then it is the type as written in Impala DDL, with the length of CHAR and
+// VARCHAR, and the precision and scale of DECIMAL e.g. DECIMAL(10,2).
+func (cd *ColDesc) ColumnTypeDatabaseTypeName() string {
+	if !cd.sqlTypeName {
+		return cd.DatabaseTypeName
+	}
+	switch {
+	case cd.HasPrecisionScale:
+		return fmt.Sprintf("%s(%d,%d)", cd.DatabaseTypeName, cd.Precision, cd.Scale)
+	case cd.HasLength:
+		return fmt.Sprintf("%s(%d)", cd.DatabaseTypeName, cd.Length)
+	default:
+		return cd.DatabaseTypeName
+	}
+}
+
+// defaultValueSize is the estimated size of values of variable-length types without a known maximum length
+const defaultValueSize = 64
+
+// estimatedSize returns the approximate size in bytes of a value of the column, as transferred by the server
+func (cd *ColDesc) estimatedSize() int64 {
+	switch cd.DatabaseTypeName {
+	case "BOOLEAN", "TINYINT":
+		return 1
+	case "SMALLINT":
+		return 2
+	case "INT":
+		return 4
+	case "BIGINT", "FLOAT", "DOUBLE":
+		// FLOAT values are sent as doubles
+		return 8
+	case "DATE":
+		return len64(DateFormat)
+	case "TIMESTAMP":
+		return len64(TimestampFormat)
+	case "DECIMAL":
+		if cd.HasPrecisionScale {
+			// digits, sign, and decimal point
+			return cd.Precision + 2
+		}
+	}
+	if cd.HasLength {
+		return cd.Length
+	}
+	return defaultValueSize
+}
+
+func len64(s string) int64 {
+	return int64(len(s))
+}
+
+// zeroFor returns a function that returns the zero value of the ScanType of cd, if its type is numeric or BOOLEAN.
+// It must be called before any ValueConverter replaces the ScanType.
+func zeroFor(cd *ColDesc) func() any {
+	switch cd.DatabaseTypeName {
+	case "BOOLEAN", "TINYINT", "SMALLINT", "INT", "BIGINT", "FLOAT", "DOUBLE":
+		zero := reflect.Zero(cd.ScanType).Interface()
+		return func() any { return zero }
+	case "DECIMAL":
+		scanType := cd.ScanType
+		// a new *big.Rat for each value since callers may modify it
+		return func() any {
+			v, _ := decimalValue("0", scanType)
+			return v
+		}
+	default:
+		return nil
+	}
+}
+
+// ValueConverter converts the values of result columns, matched by name or type
+type ValueConverter struct {
+	// Column matches columns by name, ignoring case. Empty matches any name.
+	Column string
+	// DatabaseTypeName matches columns by type e.g. BIGINT, ignoring case. Empty matches any type.
+	DatabaseTypeName string
+	// ConvertRaw receives the value as sent by the server, before the built-in decoding. If handled is false,
+	// the value is decoded as usual and passed to Convert. It is not called for NULL values.
+	ConvertRaw func(raw any) (v any, handled bool, err error)
+	// Convert receives the decoded value. It is not called for NULL values.
+	Convert func(v any) (any, error)
+	// ScanType replaces the ScanType of matching columns, if not nil
+	ScanType reflect.Type
+}
+
+func (vc *ValueConverter) matches(cd *ColDesc) bool {
+	return (vc.Column == "" || strings.EqualFold(vc.Column, cd.Name)) &&
+		(vc.DatabaseTypeName == "" || strings.EqualFold(vc.DatabaseTypeName, cd.DatabaseTypeName))
+}
+
+// applyConverter configures cd to use the first of converters that matches it
+func applyConverter(cd *ColDesc, converters []ValueConverter) {
+	for _, vc := range converters {
+		if (vc.Convert != nil || vc.ConvertRaw != nil) && vc.matches(cd) {
+			cd.convertRaw = vc.ConvertRaw
+			cd.convert = vc.Convert
+			if vc.ScanType != nil {
+				cd.ScanType = vc.ScanType
+			}
+			return
+		}
+	}
 }
 
 var (
-	dataTypeNull     = reflect.TypeOf(nil)
 	dataTypeBoolean  = reflect.TypeOf(true)
 	dataTypeFloat64  = reflect.TypeOf(float64(0))
 	dataTypeInt8     = reflect.TypeOf(int8(0))
@@ -40,10 +184,31 @@ var (
 	dataTypeInt64    = reflect.TypeOf(int64(0))
 	dataTypeString   = reflect.TypeOf("")
 	dataTypeDateTime = reflect.TypeOf(time.Time{})
-	dataTypeRawBytes = reflect.TypeOf(sql.RawBytes{})
+	dataTypeBytes    = reflect.TypeFor[[]byte]()
 	dataTypeUnknown  = reflect.TypeFor[any]()
+	dataTypeRat      = reflect.TypeOf((*big.Rat)(nil))
 )
 
+// decimalScanType returns the ScanType of DECIMAL columns for the given Options.DecimalAs mode
+func decimalScanType(mode string) reflect.Type {
+	switch mode {
+	case DecimalAsFloat64:
+		return dataTypeFloat64
+	case DecimalAsRat:
+		return dataTypeRat
+	default:
+		return dataTypeString
+	}
+}
+
+// dateScanType returns the ScanType of DATE columns for the given Options.DateAs mode
+func dateScanType(mode string) reflect.Type {
+	if mode == DateAsTime {
+		return dataTypeDateTime
+	}
+	return dataTypeString
+}
+
 func typeOf(entry *cli_service.TPrimitiveTypeEntry) reflect.Type {
 	switch entry.Type {
 	case cli_service.TTypeId_BOOLEAN_TYPE:
@@ -60,19 +225,23 @@ func typeOf(entry *cli_service.TPrimitiveTypeEntry) reflect.Type {
 		// see comment in internal/hive/result_set.go#value()
 		return dataTypeFloat64
 	case cli_service.TTypeId_NULL_TYPE:
-		return dataTypeNull
+		// the values are always nil
+		return dataTypeUnknown
 	case cli_service.TTypeId_STRING_TYPE, cli_service.TTypeId_CHAR_TYPE, cli_service.TTypeId_VARCHAR_TYPE:
 		return dataTypeString
 	case cli_service.TTypeId_DECIMAL_TYPE: // see note in README
 		return dataTypeString
-	case cli_service.TTypeId_DATE_TYPE, cli_service.TTypeId_TIMESTAMP_TYPE:
+	case cli_service.TTypeId_DATE_TYPE: // see Options.DateAs
+		return dataTypeString
+	case cli_service.TTypeId_TIMESTAMP_TYPE:
 		return dataTypeDateTime
-	case cli_service.TTypeId_BINARY_TYPE, cli_service.TTypeId_ARRAY_TYPE,
-		cli_service.TTypeId_STRUCT_TYPE, cli_service.TTypeId_MAP_TYPE, cli_service.TTypeId_UNION_TYPE:
-		return dataTypeRawBytes
-	case cli_service.TTypeId_USER_DEFINED_TYPE:
-		return dataTypeUnknown
+	case cli_service.TTypeId_BINARY_TYPE:
+		return dataTypeBytes
+	case cli_service.TTypeId_ARRAY_TYPE, cli_service.TTypeId_STRUCT_TYPE, cli_service.TTypeId_MAP_TYPE:
+		// Impala sends complex type values as JSON strings
+		return dataTypeString
 	default:
-		return dataTypeUnknown
+		// the values of other types are returned as sent by the server, which is as strings - see decode
+		return dataTypeString
 	}
 }
@@ -2,6 +2,11 @@ package hive
 
 import (
 	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -10,6 +15,17 @@ import (
 	"github.com/sclgo/impala-go/internal/generated/impalaservice"
 )
 
+// ErrRewindNotSupported means the server can't restart fetching the results from the first row.
+// Impala supports it only for queries with the result cache enabled, whose results didn't exceed the cache size.
+var ErrRewindNotSupported = errors.New("impala: rewinding results is not supported")
+
+// ErrResultsExpired means the server discarded the query while its results were still being fetched
+var ErrResultsExpired = errors.New("impala: query results expired on the server; fetch rows faster or increase IDLE_QUERY_TIMEOUT")
+
+// ErrFetchTimeout means the server kept reporting that the query results are not ready for longer
+// than Options.FetchMaxWait, so the operation was cancelled
+var ErrFetchTimeout = errors.New("impala: gave up waiting for query results")
+
 const (
 	initialBackoff = 100 * time.Millisecond
 	maxBackoff     = time.Second
@@ -19,6 +35,80 @@ const (
 type Operation struct {
 	hive *Client
 	h    *cli_service.TOperationHandle
+
+	inserted int64 // rows inserted according to the summary read by FetchInsertedRows
+	// lastState is the last operation state reported by the server, if any, for error messages
+	lastState QueryState
+	// maxRows, if positive, overrides Options.MaxRows for fetches of this operation
+	maxRows int64
+}
+
+var insertedSummary = regexp.MustCompile(`^Inserted (\d+) row\(s\)$`)
+
+// id returns the operation id in the format used in logs and events
+func (op *Operation) id() string {
+	return guid(op.h.GetOperationId().GetGUID())
+}
+
+// emit sends e with the operation id to Options.OnEvent, if configured
+func (op *Operation) emit(e Event) {
+	if op.hive.opts.OnEvent == nil {
+		return
+	}
+	e.QueryID = op.id()
+	op.hive.emit(e)
+}
+
+// stateName returns the operation state in resp, if set
+func stateName(resp *cli_service.TGetOperationStatusResp) QueryState {
+	if resp == nil || !resp.IsSetOperationState() {
+		return ""
+	}
+	return queryState(resp.GetOperationState())
+}
+
+type batchSizeKey struct{}
+
+// WithBatchSize returns a context that overrides Options.MaxRows and Options.BatchBytes for the fetches of
+// the operations, whose results are fetched with it. n must be positive; otherwise, the context is not changed.
+func WithBatchSize(ctx context.Context, n int) context.Context {
+	if n <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, batchSizeKey{}, n)
+}
+
+func batchSizeFromContext(ctx context.Context) int {
+	n, _ := ctx.Value(batchSizeKey{}).(int)
+	return n
+}
+
+// ErrInvalidHandle means that a handle, passed to Client.AttachOperation, was not returned by Operation.Handle
+var ErrInvalidHandle = errors.New("impala: invalid operation handle")
+
+// ErrOperationNotFound means that the server doesn't have an attached operation anymore, e.g. because it was
+// closed, its session ended, or it expired
+var ErrOperationNotFound = errors.New("impala: operation not found on the server; it was closed or expired")
+
+// CheckAttached verifies that the server still has an operation returned by Client.AttachOperation.
+// It fails with ErrOperationNotFound if the server doesn't recognize the handle.
+func (op *Operation) CheckAttached(ctx context.Context) error {
+	_, err := op.CheckStateAndStatus(ctx)
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		status := statusErr.Status()
+		if status.StatusCode == cli_service.TStatusCode_INVALID_HANDLE_STATUS ||
+			strings.Contains(status.GetErrorMessage(), "query handle") {
+			return fmt.Errorf("%w: %w", ErrOperationNotFound, err)
+		}
+	}
+	return err
+}
+
+// Handle returns an opaque string, which identifies the operation, including the secret that the server requires
+// to access it. Client.AttachOperation accepts the handle to continue fetching the results of the operation.
+func (op *Operation) Handle() string {
+	return hex.EncodeToString(op.h.GetOperationId().GetGUID()) + ":" + hex.EncodeToString(op.h.GetOperationId().GetSecret())
 }
 
 // HasResultSet return if operation has result set
@@ -33,7 +123,7 @@ func (op *Operation) RowsAffected() float64 {
 
 // GetResultSetMetadata return schema
 func (op *Operation) GetResultSetMetadata(ctx context.Context) (*TableSchema, error) {
-	op.hive.log.Printf("fetch metadata for operation: %v", guid(op.h.OperationId.GUID))
+	op.hive.log.Infof("fetch metadata for operation: %v", guid(op.h.OperationId.GUID))
 	req := cli_service.TGetResultSetMetadataReq{
 		OperationHandle: op.h,
 	}
@@ -56,23 +146,40 @@ func (op *Operation) GetResultSetMetadata(ctx context.Context) (*TableSchema, er
 				typeQualifiers = (*entry.TypeQualifiers).Qualifiers
 			}
 			dbtype := strings.TrimSuffix(entry.Type.String(), "_TYPE")
-			maxLength, hasLength := getMaxLength(typeQualifiers)
-			precision, scale, hasPrecisionScale := getPrecisionScale(typeQualifiers)
-			schema.Columns = append(schema.Columns, &ColDesc{
-				Name:              desc.ColumnName,
-				DatabaseTypeName:  dbtype,
-				ScanType:          typeOf(entry),
-				HasLength:         hasLength,
-				Length:            maxLength,
-				Precision:         precision,
-				Scale:             scale,
-				HasPrecisionScale: hasPrecisionScale,
-			})
+			colDesc := &ColDesc{
+				Name:             desc.ColumnName,
+				DatabaseTypeName: dbtype,
+				ScanType:         typeOf(entry),
+			}
+			if entry.Type == cli_service.TTypeId_DECIMAL_TYPE {
+				colDesc.ScanType = decimalScanType(op.hive.opts.DecimalAs)
+			}
+			if entry.Type == cli_service.TTypeId_DATE_TYPE {
+				colDesc.ScanType = dateScanType(op.hive.opts.DateAs)
+			}
+			colDesc.sqlTypeName = op.hive.opts.TypeNames == TypeNamesSQL
+			colDesc.trimChar = op.hive.opts.CharTrim
+			switch dbtype {
+			case "STRING", "CHAR", "VARCHAR":
+				colDesc.maxCellBytes = max(op.hive.opts.MaxCellBytes, 0)
+			}
+			colDesc.truncateCells = op.hive.opts.TruncateCells
+			colDesc.jsonFormat = op.hive.opts.ComplexJSON
+			colDesc.setQualifiers(typeQualifiers)
+			if op.hive.opts.NullAsZero {
+				colDesc.nullZero = zeroFor(colDesc)
+			}
+			applyConverter(colDesc, op.hive.opts.ValueConverters)
+			schema.Columns = append(schema.Columns, colDesc)
 		}
 
+		op.hive.log.Infof("fetch schema: %d columns", len(schema.Columns))
 		for _, col := range schema.Columns {
-			op.hive.log.Printf("fetch schema: %v", col)
+			op.hive.log.Debugf("fetch schema: %v", col)
 		}
+	} else {
+		// e.g. DDL statements; ResultSet.Next reports ErrMissingSchema if the server returns rows anyway
+		op.hive.log.Debugf("fetch schema: no schema")
 	}
 
 	return schema, nil
@@ -80,6 +187,13 @@ func (op *Operation) GetResultSetMetadata(ctx context.Context) (*TableSchema, er
 
 // FetchResults lazily prepares query result from server
 func (op *Operation) FetchResults(ctx context.Context, schema *TableSchema) (*ResultSet, error) {
+	if n := batchSizeFromContext(ctx); n > 0 {
+		op.maxRows = int64(n)
+		op.hive.log.Infof("fetch size for operation %s from context: %d rows", op.id(), op.maxRows)
+	} else if op.hive.opts.BatchBytes > 0 {
+		op.maxRows = adaptiveBatchSize(op.hive.opts.BatchBytes, schema)
+		op.hive.log.Infof("fetch size for operation %s: %d rows", op.id(), op.maxRows)
+	}
 	// Impala server prepares and buffers the query results before they are fetched.
 	rs := ResultSet{
 		idx:    0,
@@ -87,29 +201,59 @@ func (op *Operation) FetchResults(ctx context.Context, schema *TableSchema) (*Re
 		result: nil,
 		more:   true,
 		schema: schema,
+
+		maxBytes: op.hive.opts.MaxResultBytes,
+		maxRows:  op.hive.opts.MaxRowsReturned,
 		// TODO align query context handling with database/sql practices (Github #14)
-		fetchfn: func() (*cli_service.TFetchResultsResp, error) { return fetch(ctx, op) },
+		fetchfn:  func() (*cli_service.TFetchResultsResp, error) { return fetch(ctx, op) },
+		cancelfn: func() error { return op.Cancel(ctx) },
+		checksum: newChecksum(op.hive.opts.ResultChecksum),
+	}
+	if op.hive.opts.ResultCacheSize > 0 {
+		rs.rewindfn = func() (*cli_service.TFetchResultsResp, error) { return fetchFirst(ctx, op) }
 	}
 	return &rs, nil
 }
 
+// fetchMetadata is like FetchResults but for metadata operations, which are not subject to MaxRowsReturned
+func (op *Operation) fetchMetadata(ctx context.Context, schema *TableSchema) (*ResultSet, error) {
+	rs, err := op.FetchResults(ctx, schema)
+	if err != nil {
+		return nil, err
+	}
+	rs.maxRows = 0
+	return rs, nil
+}
+
 // CheckStateAndStatus returns the operation state if both the state and status are ok
 func (op *Operation) CheckStateAndStatus(ctx context.Context) (cli_service.TOperationState, error) {
 	req := cli_service.TGetOperationStatusReq{
 		OperationHandle: op.h,
 	}
 	resp, err := op.hive.client.GetOperationStatus(ctx, &req)
-	if err != nil {
-		return 0, err
+	if err == nil {
+		err = checkStatus(resp)
 	}
-	if err = checkStatus(resp); err != nil {
-		return 0, err
+	if err == nil {
+		err = checkState(resp)
 	}
-	if err = checkState(resp); err != nil {
+	if err != nil {
+		op.lastState = lo.CoalesceOrEmpty(stateName(resp), op.lastState)
+		op.emit(Event{Phase: PhaseExecute, State: stateName(resp), Err: err})
 		return 0, err
 	}
 	state := resp.GetOperationState()
-	op.hive.log.Println("op", guid(op.h.GetOperationId().GetGUID()), "reached success or non-terminal state", state)
+	phase := PhaseExecute
+	if state == cli_service.TOperationState_PENDING_STATE {
+		// Impala reports PENDING while admission control queues the query until resources are available
+		phase = PhaseQueued
+		if op.lastState != QueryStatePending {
+			op.hive.log.Infof("op %s queued by admission control", op.id())
+		}
+	}
+	op.lastState = queryState(state)
+	op.hive.log.Debugf("op %s reached success or non-terminal state %v", op.id(), state)
+	op.emit(Event{Phase: phase, State: op.lastState})
 	return state, nil
 }
 
@@ -125,17 +269,60 @@ func (op *Operation) WaitToFinish(ctx context.Context) error {
 		err = lo.CoalesceOrEmpty(err, ctx.Err())
 		duration = nextDuration(duration)
 	}
-	return err
+	return op.interrupted(err, "waiting for it to finish")
+}
+
+// interrupted adds the operation id and the last known state to err, if the context was cancelled or
+// its deadline exceeded, so it is clear how far the query got. Other errors are returned unchanged.
+func (op *Operation) interrupted(err error, activity string) error {
+	if !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	msg := fmt.Sprintf("impala: query %s interrupted while %s", op.id(), activity)
+	if op.lastState != "" {
+		msg += "; last known state " + op.lastState.String()
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
+
+// maxAdaptiveBatchSize limits the batch size computed by adaptiveBatchSize for very narrow schemas
+const maxAdaptiveBatchSize = 65536
+
+// adaptiveBatchSize returns the number of rows, which fit in batchBytes, according to the estimated row size
+func adaptiveBatchSize(batchBytes int64, schema *TableSchema) int64 {
+	var rowBytes int64
+	for _, col := range schema.Columns {
+		rowBytes += col.estimatedSize()
+	}
+	rowBytes = max(rowBytes, 1)
+	return min(max(batchBytes/rowBytes, 1), maxAdaptiveBatchSize)
 }
 
 func fetch(ctx context.Context, op *Operation) (*cli_service.TFetchResultsResp, error) {
+	return fetchRows(ctx, op, cli_service.TFetchOrientation_FETCH_NEXT)
+}
+
+// fetchFirst restarts fetching from the first row, which the server supports only if the result cache is enabled
+func fetchFirst(ctx context.Context, op *Operation) (*cli_service.TFetchResultsResp, error) {
+	resp, err := fetchRows(ctx, op, cli_service.TFetchOrientation_FETCH_FIRST)
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return nil, fmt.Errorf("%w: %w", ErrRewindNotSupported, err)
+	}
+	return resp, err
+}
+
+func fetchRows(ctx context.Context, op *Operation, orientation cli_service.TFetchOrientation) (*cli_service.TFetchResultsResp, error) {
 	req := cli_service.TFetchResultsReq{
 		OperationHandle: op.h,
-		MaxRows:         op.hive.opts.MaxRows,
+		Orientation:     orientation,
+		MaxRows:         lo.Ternary(op.maxRows > 0, op.maxRows, op.hive.opts.MaxRows),
 	}
 
-	op.hive.log.Printf("fetch results for operation: %v", guid(op.h.OperationId.GUID))
+	op.hive.log.Infof("fetch results for operation: %v", guid(op.h.OperationId.GUID))
 
+	maxWait := op.fetchMaxWait(ctx)
+	start := time.Now()
 	var duration time.Duration
 	fetchStatus := cli_service.TStatusCode_STILL_EXECUTING_STATUS
 	resp := &cli_service.TFetchResultsResp{}
@@ -143,7 +330,9 @@ func fetch(ctx context.Context, op *Operation) (*cli_service.TFetchResultsResp,
 	for fetchStatus == cli_service.TStatusCode_STILL_EXECUTING_STATUS && ctx.Err() == nil {
 		// It is questionable if we need to back-off (sleep) in this case
 		// impala-shell doesn't - https://github.com/apache/impala/blob/1f35747/shell/impala_client.py#L958
-		if duration == 0 {
+		if duration == 0 || op.hive.opts.SpoolResults {
+			// with result spooling, the server already waits up to FETCH_ROWS_TIMEOUT_MS for rows to
+			// become available so fetching again immediately returns partial results promptly
 			duration = initialBackoff
 		} else {
 			sleep(ctx, duration)
@@ -151,17 +340,59 @@ func fetch(ctx context.Context, op *Operation) (*cli_service.TFetchResultsResp,
 		}
 		var err error
 		resp, err = op.hive.client.FetchResults(ctx, &req)
-		if err != nil {
-			return nil, err
+		if err == nil {
+			err = checkExpired(checkStatus(resp))
 		}
-		if err = checkStatus(resp); err != nil {
+		if err != nil {
+			op.emit(Event{Phase: PhaseFetch, Err: err})
 			return nil, err
 		}
 		fetchStatus = resp.GetStatus().StatusCode
+		if fetchStatus == cli_service.TStatusCode_STILL_EXECUTING_STATUS && maxWait > 0 && time.Since(start) >= maxWait {
+			return nil, op.fetchTimedOut(ctx, maxWait)
+		}
 	}
 
-	op.hive.log.Printf("results: %v", resp.Results)
-	return resp, ctx.Err()
+	// never log the results above debug level - they may be large and contain sensitive data
+	op.hive.log.Debugf("results: %v", resp.Results)
+	err := op.interrupted(ctx.Err(), "fetching results")
+	op.emit(Event{Phase: PhaseFetch, Rows: int64(length(resp.Results)), Err: err})
+	return resp, err
+}
+
+// fetchMaxWait returns how long fetchRows waits for results that are not ready, or 0 for no limit.
+// The limit applies only if ctx has no deadline, which bounds the wait already.
+func (op *Operation) fetchMaxWait(ctx context.Context) time.Duration {
+	if _, ok := ctx.Deadline(); ok {
+		return 0
+	}
+	// There is no default limit derived from QUERY_TIMEOUT_S: it is an idle timeout and a client that keeps
+	// fetching is not idle, so legitimate long-running queries may report STILL_EXECUTING for much longer.
+	return max(op.hive.opts.FetchMaxWait, 0)
+}
+
+// fetchTimedOut cancels the operation after fetchRows waited maxWait for results and returns ErrFetchTimeout
+func (op *Operation) fetchTimedOut(ctx context.Context, maxWait time.Duration) error {
+	err := fmt.Errorf("%w: query %s still executing after %v", ErrFetchTimeout, op.id(), maxWait)
+	op.emit(Event{Phase: PhaseFetch, Err: err})
+	if cancelErr := op.Cancel(ctx); cancelErr != nil {
+		op.hive.log.Errorf("failed to cancel op %s: %v", op.id(), cancelErr)
+	}
+	return err
+}
+
+// checkExpired wraps ErrResultsExpired around err if it indicates that the server discarded the query
+func checkExpired(err error) error {
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) {
+		return err
+	}
+	status := statusErr.Status()
+	if status.StatusCode == cli_service.TStatusCode_INVALID_HANDLE_STATUS ||
+		strings.Contains(status.GetErrorMessage(), "expired due to client inactivity") {
+		return fmt.Errorf("%w: %w", ErrResultsExpired, err)
+	}
+	return err
 }
 
 func nextDuration(duration time.Duration) time.Duration {
@@ -172,26 +403,93 @@ func nextDuration(duration time.Duration) time.Duration {
 	return duration
 }
 
+// GetLog returns the log of the operation, which includes the warnings Impala reports for successful queries
+func (op *Operation) GetLog(ctx context.Context) (string, error) {
+	req := cli_service.TGetLogReq{
+		OperationHandle: op.h,
+	}
+	resp, err := op.hive.client.GetLog(ctx, &req)
+	if err != nil {
+		return "", err
+	}
+	if err = checkStatus(resp); err != nil {
+		return "", err
+	}
+	return resp.GetLog(), nil
+}
+
+// Cancel cancels the operation on the server
+func (op *Operation) Cancel(ctx context.Context) error {
+	return op.hive.CancelOperation(ctx, op)
+}
+
 // Close closes operation and returns rows affected if any
 func (op *Operation) Close(ctx context.Context) (int64, error) {
+	if !op.hive.impalaExtension() {
+		return op.closeBase(ctx)
+	}
 	req := impalaservice.TCloseImpalaOperationReq{
 		OperationHandle: op.h,
 	}
 	resp, err := op.hive.client.CloseImpalaOperation(ctx, &req)
+	if err == nil {
+		err = checkStatus(resp)
+	}
 	if err != nil {
+		op.emit(Event{Phase: PhaseClose, Err: err})
 		return 0, err
 	}
-	if err := checkStatus(resp); err != nil {
+
+	op.hive.log.Infof("close operation: %v", op.id())
+	rowsAffected := calcRowsAffected(resp, op.inserted)
+	op.emit(Event{Phase: PhaseClose, Rows: rowsAffected})
+	return rowsAffected, nil
+}
+
+// closeBase closes the operation with the base HiveServer2 method, for servers without the Impala extension.
+// Such servers don't report DML statistics, so only rows read by FetchInsertedRows are reported as affected.
+func (op *Operation) closeBase(ctx context.Context) (int64, error) {
+	req := cli_service.TCloseOperationReq{
+		OperationHandle: op.h,
+	}
+	resp, err := op.hive.client.CloseOperation(ctx, &req)
+	if err == nil {
+		err = checkStatus(resp)
+	}
+	if err != nil {
+		op.emit(Event{Phase: PhaseClose, Err: err})
 		return 0, err
 	}
 
-	op.hive.log.Printf("close operation: %v", guid(op.h.OperationId.GUID))
-	return calcRowsAffected(resp), nil
+	op.hive.log.Infof("close operation: %v", op.id())
+	op.emit(Event{Phase: PhaseClose, Rows: op.inserted})
+	return op.inserted, nil
 }
 
-func calcRowsAffected(resp *impalaservice.TCloseImpalaOperationResp) int64 {
+// FetchInsertedRows reads the "Inserted N row(s)" summary, which Impala returns as the result set
+// of CREATE TABLE AS SELECT statements. Unlike INSERT, CTAS is a DDL statement, so Impala doesn't report
+// its DML stats when the operation is closed. Close reports the rows read here as rows affected.
+// Statements without such a summary are ignored.
+func (op *Operation) FetchInsertedRows(ctx context.Context) error {
+	resp, err := fetch(ctx, op)
+	if err != nil {
+		return err
+	}
+	columns := resp.GetResults().GetColumns()
+	if len(columns) == 0 || columns[0].StringVal == nil || len(columns[0].StringVal.Values) == 0 {
+		return nil
+	}
+	match := insertedSummary.FindStringSubmatch(columns[0].StringVal.Values[0])
+	if match == nil {
+		return nil
+	}
+	op.inserted, err = strconv.ParseInt(match[1], 10, 64)
+	return err
+}
+
+func calcRowsAffected(resp *impalaservice.TCloseImpalaOperationResp, inserted int64) int64 {
 	if resp.DmlResult_ == nil {
-		return 0
+		return inserted
 	}
 	var result int64
 	for _, v := range resp.DmlResult_.GetRowsModified() {
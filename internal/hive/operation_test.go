@@ -1,10 +1,16 @@
 package hive
 
 import (
+	"bytes"
 	"context"
+	"database/sql/driver"
+	"io"
 	"log"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/samber/lo"
 	"github.com/sclgo/impala-go/internal/generated/cli_service"
 	"github.com/sclgo/impala-go/internal/generated/impalaservice"
 	"github.com/stretchr/testify/require"
@@ -15,28 +21,494 @@ func TestOperation(t *testing.T) {
 	hive := &Client{
 		client: mock,
 		opts:   &Options{},
-		log:    log.Default(),
+		log:    NewLogger(log.Default(), LogLevelDebug),
 	}
 
 	t.Run("wait to finish", func(t *testing.T) {
 		op := &Operation{
 			hive: hive,
-			h:    &cli_service.TOperationHandle{},
+			h: &cli_service.TOperationHandle{
+				OperationId: &cli_service.THandleIdentifier{GUID: make([]byte, 16)},
+			},
 		}
 		ctx, cancel := context.WithCancel(context.Background())
 		cancel()
 		err := op.WaitToFinish(ctx)
 		require.ErrorIs(t, err, context.Canceled)
+		require.ErrorContains(t, err, "query 00000000-0000-0000-0000-000000000000 interrupted while waiting")
 		require.True(t, mock.called)
 	})
+
+	t.Run("wait to finish timeout", func(t *testing.T) {
+		op := &Operation{
+			hive: hive,
+			h: &cli_service.TOperationHandle{
+				OperationId: &cli_service.THandleIdentifier{GUID: make([]byte, 16)},
+			},
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		err := op.WaitToFinish(ctx)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.ErrorContains(t, err, "last known state RUNNING_STATE")
+	})
+
+	t.Run("fetch expired", func(t *testing.T) {
+		mock.fetchResp = &cli_service.TFetchResultsResp{
+			Status: &cli_service.TStatus{
+				StatusCode:   cli_service.TStatusCode_ERROR_STATUS,
+				ErrorMessage: lo.ToPtr("Query 1234:5678 expired due to client inactivity (timeout is 1s000ms)"),
+			},
+		}
+		op := &Operation{
+			hive: hive,
+			h: &cli_service.TOperationHandle{
+				OperationId: &cli_service.THandleIdentifier{GUID: make([]byte, 16)},
+			},
+		}
+		_, err := fetch(context.Background(), op)
+		require.ErrorIs(t, err, ErrResultsExpired)
+		require.ErrorContains(t, err, "client inactivity")
+	})
+
+	t.Run("fetch first rejected", func(t *testing.T) {
+		mock.fetchResp = &cli_service.TFetchResultsResp{
+			Status: &cli_service.TStatus{
+				StatusCode:   cli_service.TStatusCode_ERROR_STATUS,
+				ErrorMessage: lo.ToPtr("Restarting of fetch requires enabling of query result caching."),
+			},
+		}
+		op := &Operation{
+			hive: hive,
+			h: &cli_service.TOperationHandle{
+				OperationId: &cli_service.THandleIdentifier{GUID: make([]byte, 16)},
+			},
+		}
+		_, err := fetchFirst(context.Background(), op)
+		require.ErrorIs(t, err, ErrRewindNotSupported)
+		require.ErrorContains(t, err, "result caching")
+		require.Equal(t, cli_service.TFetchOrientation_FETCH_FIRST, mock.fetchReq.Orientation)
+	})
+
+	t.Run("fetch inserted rows", func(t *testing.T) {
+		mock.fetchResp = &cli_service.TFetchResultsResp{
+			Status: &cli_service.TStatus{StatusCode: cli_service.TStatusCode_SUCCESS_STATUS},
+			Results: &cli_service.TRowSet{
+				Columns: []*cli_service.TColumn{
+					{StringVal: &cli_service.TStringColumn{Values: []string{"Inserted 42 row(s)"}}},
+				},
+			},
+		}
+		op := &Operation{
+			hive: hive,
+			h: &cli_service.TOperationHandle{
+				OperationId: &cli_service.THandleIdentifier{GUID: make([]byte, 16)},
+			},
+		}
+		require.NoError(t, op.FetchInsertedRows(context.Background()))
+		rowsAffected, err := op.Close(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, int64(42), rowsAffected)
+	})
+}
+
+func TestFetch_LogLevel(t *testing.T) {
+	mock := &opThriftClient{
+		fetchResp: &cli_service.TFetchResultsResp{
+			Status: &cli_service.TStatus{StatusCode: cli_service.TStatusCode_SUCCESS_STATUS},
+			Results: &cli_service.TRowSet{
+				Columns: []*cli_service.TColumn{
+					{StringVal: &cli_service.TStringColumn{Values: []string{"secret-cell-value"}}},
+				},
+			},
+		},
+	}
+	for _, level := range []string{LogLevelError, LogLevelInfo, LogLevelDebug} {
+		t.Run(level, func(t *testing.T) {
+			var buf bytes.Buffer
+			op := &Operation{
+				hive: &Client{
+					client: mock,
+					opts:   &Options{},
+					log:    NewLogger(log.New(&buf, "", 0), level),
+				},
+				h: &cli_service.TOperationHandle{
+					OperationId: &cli_service.THandleIdentifier{GUID: make([]byte, 16)},
+				},
+			}
+			_, err := fetch(context.Background(), op)
+			require.NoError(t, err)
+			if level == LogLevelDebug {
+				require.Contains(t, buf.String(), "secret-cell-value")
+			} else {
+				require.NotContains(t, buf.String(), "secret-cell-value")
+			}
+		})
+	}
+}
+
+func TestCalcRowsAffected(t *testing.T) {
+	resp := &impalaservice.TCloseImpalaOperationResp{}
+	require.Equal(t, int64(3), calcRowsAffected(resp, 3))
+	resp.DmlResult_ = &impalaservice.TDmlResult_{RowsModified: map[string]int64{"": 2}}
+	require.Equal(t, int64(2), calcRowsAffected(resp, 0))
+}
+
+func TestGetResultSetMetadata_TypeNames(t *testing.T) {
+	qualifiers := func(q map[string]int32) *cli_service.TTypeQualifiers {
+		res := &cli_service.TTypeQualifiers{Qualifiers: map[string]*cli_service.TTypeQualifierValue{}}
+		for k, v := range q {
+			res.Qualifiers[k] = &cli_service.TTypeQualifierValue{I32Value: lo.ToPtr(v)}
+		}
+		return res
+	}
+	column := func(typeID cli_service.TTypeId, q *cli_service.TTypeQualifiers) *cli_service.TColumnDesc {
+		return &cli_service.TColumnDesc{
+			TypeDesc: &cli_service.TTypeDesc{Types: []*cli_service.TTypeEntry{{
+				PrimitiveEntry: &cli_service.TPrimitiveTypeEntry{Type: typeID, TypeQualifiers: q},
+			}}},
+		}
+	}
+	mock := &opThriftClient{
+		metadataResp: &cli_service.TGetResultSetMetadataResp{
+			Status: &cli_service.TStatus{StatusCode: cli_service.TStatusCode_SUCCESS_STATUS},
+			Schema: &cli_service.TTableSchema{Columns: []*cli_service.TColumnDesc{
+				column(cli_service.TTypeId_DECIMAL_TYPE, qualifiers(map[string]int32{"precision": 10, "scale": 2})),
+				column(cli_service.TTypeId_CHAR_TYPE, qualifiers(map[string]int32{"characterMaximumLength": 5})),
+				column(cli_service.TTypeId_VARCHAR_TYPE, qualifiers(map[string]int32{"characterMaximumLength": 20})),
+				column(cli_service.TTypeId_TIMESTAMP_TYPE, nil),
+				column(cli_service.TTypeId_DATE_TYPE, nil),
+				column(cli_service.TTypeId_STRING_TYPE, nil),
+			}},
+		},
+	}
+
+	tests := []struct {
+		typeNames string
+		expected  []string
+	}{
+		{"", []string{"DECIMAL", "CHAR", "VARCHAR", "TIMESTAMP", "DATE", "STRING"}},
+		{TypeNamesThrift, []string{"DECIMAL", "CHAR", "VARCHAR", "TIMESTAMP", "DATE", "STRING"}},
+		{TypeNamesSQL, []string{"DECIMAL(10,2)", "CHAR(5)", "VARCHAR(20)", "TIMESTAMP", "DATE", "STRING"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.typeNames, func(t *testing.T) {
+			op := &Operation{
+				hive: &Client{
+					client: mock,
+					opts:   &Options{TypeNames: tt.typeNames},
+					log:    NewLogger(log.Default(), LogLevelError),
+				},
+				h: &cli_service.TOperationHandle{
+					OperationId: &cli_service.THandleIdentifier{GUID: make([]byte, 16)},
+				},
+			}
+			schema, err := op.GetResultSetMetadata(context.Background())
+			require.NoError(t, err)
+			var names []string
+			for _, col := range schema.Columns {
+				names = append(names, col.ColumnTypeDatabaseTypeName())
+			}
+			require.Equal(t, tt.expected, names)
+			// converters keep matching the base type name
+			require.Equal(t, "DECIMAL", schema.Columns[0].DatabaseTypeName)
+		})
+	}
 }
 
 type opThriftClient struct {
-	called bool
+	called       bool
+	fetchResp    *cli_service.TFetchResultsResp
+	fetchReq     *cli_service.TFetchResultsReq
+	metadataResp *cli_service.TGetResultSetMetadataResp
+	// states, if not empty, are reported by successive GetOperationStatus calls instead of RUNNING_STATE
+	states []cli_service.TOperationState
 	impalaservice.ImpalaHiveServer2Service
 }
 
+func (c *opThriftClient) FetchResults(_ context.Context, req *cli_service.TFetchResultsReq) (*cli_service.TFetchResultsResp, error) {
+	c.fetchReq = req
+	return c.fetchResp, nil
+}
+
+func (c *opThriftClient) GetResultSetMetadata(context.Context, *cli_service.TGetResultSetMetadataReq) (*cli_service.TGetResultSetMetadataResp, error) {
+	return c.metadataResp, nil
+}
+
 func (c *opThriftClient) GetOperationStatus(ctx context.Context, _ *cli_service.TGetOperationStatusReq) (*cli_service.TGetOperationStatusResp, error) {
 	c.called = true
-	return &cli_service.TGetOperationStatusResp{}, ctx.Err()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	state := cli_service.TOperationState_RUNNING_STATE
+	if len(c.states) > 0 {
+		state, c.states = c.states[0], c.states[1:]
+	}
+	return &cli_service.TGetOperationStatusResp{
+		Status:         &cli_service.TStatus{StatusCode: cli_service.TStatusCode_SUCCESS_STATUS},
+		OperationState: cli_service.TOperationStatePtr(state),
+	}, nil
+}
+
+func (c *opThriftClient) CloseImpalaOperation(context.Context, *impalaservice.TCloseImpalaOperationReq) (*impalaservice.TCloseImpalaOperationResp, error) {
+	return &impalaservice.TCloseImpalaOperationResp{
+		Status: &cli_service.TStatus{StatusCode: cli_service.TStatusCode_SUCCESS_STATUS},
+	}, nil
+}
+
+func TestGetResultSetMetadata_NoSchema(t *testing.T) {
+	mock := &opThriftClient{
+		metadataResp: &cli_service.TGetResultSetMetadataResp{
+			Status: &cli_service.TStatus{StatusCode: cli_service.TStatusCode_SUCCESS_STATUS},
+		},
+	}
+	op := &Operation{
+		hive: &Client{
+			client: mock,
+			opts:   &Options{},
+			log:    NewLogger(log.Default(), LogLevelError),
+		},
+		h: &cli_service.TOperationHandle{
+			OperationId: &cli_service.THandleIdentifier{GUID: make([]byte, 16)},
+		},
+	}
+	schema, err := op.GetResultSetMetadata(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, schema.Columns)
+
+	t.Run("no rows", func(t *testing.T) {
+		mock.fetchResp = &cli_service.TFetchResultsResp{
+			Status:  &cli_service.TStatus{StatusCode: cli_service.TStatusCode_SUCCESS_STATUS},
+			Results: &cli_service.TRowSet{},
+		}
+		rs, err := op.FetchResults(context.Background(), schema)
+		require.NoError(t, err)
+		require.Equal(t, io.EOF, rs.Next(nil))
+	})
+
+	t.Run("rows without schema", func(t *testing.T) {
+		mock.fetchResp = &cli_service.TFetchResultsResp{
+			Status: &cli_service.TStatus{StatusCode: cli_service.TStatusCode_SUCCESS_STATUS},
+			Results: &cli_service.TRowSet{
+				Columns: []*cli_service.TColumn{
+					{I32Val: &cli_service.TI32Column{Values: []int32{1, 2}}},
+				},
+			},
+		}
+		rs, err := op.FetchResults(context.Background(), schema)
+		require.NoError(t, err)
+		err = rs.Next(nil)
+		require.ErrorIs(t, err, ErrMissingSchema)
+		require.ErrorContains(t, err, "2 rows")
+		// a caller, which ignores the schema, gets an error instead of a panic
+		err = rs.Next(make([]driver.Value, 1))
+		require.ErrorIs(t, err, ErrMissingSchema)
+	})
+}
+
+func TestAdaptiveBatchSize(t *testing.T) {
+	schema := func(cols ...*ColDesc) *TableSchema {
+		return &TableSchema{Columns: cols}
+	}
+	const mb = 1 << 20
+	tests := []struct {
+		name     string
+		budget   int64
+		schema   *TableSchema
+		expected int64
+	}{
+		{"narrow", mb, schema(&ColDesc{DatabaseTypeName: "INT"}), 65536},
+		{"fixed width", 64 * 1024, schema(&ColDesc{DatabaseTypeName: "BIGINT"}, &ColDesc{DatabaseTypeName: "BOOLEAN"}), 64 * 1024 / 9},
+		{"varchar", mb, schema(&ColDesc{DatabaseTypeName: "VARCHAR", Length: 1000, HasLength: true}), mb / 1000},
+		{"decimal", mb, schema(&ColDesc{DatabaseTypeName: "DECIMAL", Precision: 38, Scale: 2, HasPrecisionScale: true}), mb / 40},
+		{"string", mb, schema(&ColDesc{DatabaseTypeName: "STRING"}, &ColDesc{DatabaseTypeName: "TIMESTAMP"}), mb / (64 + 29)},
+		{"row wider than budget", 100, schema(&ColDesc{DatabaseTypeName: "CHAR", Length: 255, HasLength: true}), 1},
+		{"no columns", mb, schema(), 65536},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, adaptiveBatchSize(tt.budget, tt.schema))
+		})
+	}
+}
+
+func TestFetchResults_BatchBytes(t *testing.T) {
+	mock := &opThriftClient{
+		fetchResp: &cli_service.TFetchResultsResp{
+			Status:  &cli_service.TStatus{StatusCode: cli_service.TStatusCode_SUCCESS_STATUS},
+			Results: &cli_service.TRowSet{},
+		},
+	}
+	op := &Operation{
+		hive: &Client{
+			client: mock,
+			opts:   &Options{MaxRows: 1024, BatchBytes: 4096},
+			log:    NewLogger(log.Default(), LogLevelError),
+		},
+		h: &cli_service.TOperationHandle{
+			OperationId: &cli_service.THandleIdentifier{GUID: make([]byte, 16)},
+		},
+	}
+	rs, err := op.FetchResults(context.Background(), &TableSchema{Columns: []*ColDesc{{DatabaseTypeName: "STRING"}}})
+	require.NoError(t, err)
+	require.Equal(t, io.EOF, rs.Next(make([]driver.Value, 1)))
+	require.Equal(t, int64(64), mock.fetchReq.MaxRows)
+	require.Zero(t, mock.fetchReq.GetFetchType(), "0 selects the result rows, 1 the operation log")
+	require.Equal(t, cli_service.TFetchOrientation_FETCH_NEXT, mock.fetchReq.Orientation)
+}
+
+func TestFetchResults_WithBatchSize(t *testing.T) {
+	mock := &opThriftClient{
+		fetchResp: &cli_service.TFetchResultsResp{
+			Status:  &cli_service.TStatus{StatusCode: cli_service.TStatusCode_SUCCESS_STATUS},
+			Results: &cli_service.TRowSet{},
+		},
+	}
+	newOp := func() *Operation {
+		return &Operation{
+			hive: &Client{
+				client: mock,
+				opts:   &Options{MaxRows: 1024, BatchBytes: 4096},
+				log:    NewLogger(log.Default(), LogLevelError),
+			},
+			h: &cli_service.TOperationHandle{
+				OperationId: &cli_service.THandleIdentifier{GUID: make([]byte, 16)},
+			},
+		}
+	}
+	schema := &TableSchema{Columns: []*ColDesc{{DatabaseTypeName: "STRING"}}}
+
+	rs, err := newOp().FetchResults(WithBatchSize(context.Background(), 10), schema)
+	require.NoError(t, err)
+	require.Equal(t, io.EOF, rs.Next(make([]driver.Value, 1)))
+	require.Equal(t, int64(10), mock.fetchReq.MaxRows, "the context overrides the options")
+
+	rs, err = newOp().FetchResults(WithBatchSize(context.Background(), 0), schema)
+	require.NoError(t, err)
+	require.Equal(t, io.EOF, rs.Next(make([]driver.Value, 1)))
+	require.Equal(t, int64(64), mock.fetchReq.MaxRows, "0 doesn't override the options")
+}
+
+func TestWaitToFinish_Queued(t *testing.T) {
+	var events []Event
+	var logOut bytes.Buffer
+	mock := &opThriftClient{
+		states: []cli_service.TOperationState{
+			cli_service.TOperationState_PENDING_STATE,
+			cli_service.TOperationState_PENDING_STATE,
+			cli_service.TOperationState_RUNNING_STATE,
+			cli_service.TOperationState_FINISHED_STATE,
+		},
+	}
+	op := &Operation{
+		hive: &Client{
+			client: mock,
+			opts:   &Options{OnEvent: func(e Event) { events = append(events, e) }},
+			log:    NewLogger(log.New(&logOut, "", 0), LogLevelInfo),
+		},
+		h: &cli_service.TOperationHandle{
+			OperationId: &cli_service.THandleIdentifier{GUID: make([]byte, 16)},
+		},
+	}
+	require.NoError(t, op.WaitToFinish(context.Background()))
+
+	queryID := "00000000-0000-0000-0000-000000000000"
+	require.Equal(t, []Event{
+		{Phase: PhaseQueued, QueryID: queryID, State: "PENDING_STATE"},
+		{Phase: PhaseQueued, QueryID: queryID, State: "PENDING_STATE"},
+		{Phase: PhaseExecute, QueryID: queryID, State: "RUNNING_STATE"},
+		{Phase: PhaseExecute, QueryID: queryID, State: "FINISHED_STATE"},
+	}, events)
+	require.Equal(t, 1, strings.Count(logOut.String(), "queued by admission control"))
+}
+
+func TestFetch_Events(t *testing.T) {
+	var events []Event
+	mock := &opThriftClient{
+		fetchResp: &cli_service.TFetchResultsResp{
+			Status: &cli_service.TStatus{StatusCode: cli_service.TStatusCode_SUCCESS_STATUS},
+			Results: &cli_service.TRowSet{
+				Columns: []*cli_service.TColumn{
+					{I32Val: &cli_service.TI32Column{Values: []int32{1, 2}}},
+				},
+			},
+		},
+	}
+	op := &Operation{
+		hive: &Client{
+			client: mock,
+			opts:   &Options{OnEvent: func(e Event) { events = append(events, e) }},
+			log:    NewLogger(log.Default(), LogLevelError),
+		},
+		h: &cli_service.TOperationHandle{
+			OperationId: &cli_service.THandleIdentifier{GUID: make([]byte, 16)},
+		},
+	}
+	_, err := fetch(context.Background(), op)
+	require.NoError(t, err)
+
+	mock.fetchResp = &cli_service.TFetchResultsResp{
+		Status: &cli_service.TStatus{StatusCode: cli_service.TStatusCode_ERROR_STATUS},
+	}
+	_, err = fetch(context.Background(), op)
+	require.Error(t, err)
+
+	queryID := "00000000-0000-0000-0000-000000000000"
+	require.Equal(t, []Event{
+		{Phase: PhaseFetch, QueryID: queryID, Rows: 2},
+		{Phase: PhaseFetch, QueryID: queryID, Err: err},
+	}, events)
+}
+
+func TestFetch_MaxWait(t *testing.T) {
+	newOp := func(mock *thriftClient, opts *Options) *Operation {
+		return &Operation{
+			hive: &Client{client: mock, opts: opts, log: NewLogger(log.Default(), LogLevelError)},
+			h: &cli_service.TOperationHandle{
+				OperationId: &cli_service.THandleIdentifier{GUID: make([]byte, 16)},
+			},
+		}
+	}
+
+	t.Run("stalled", func(t *testing.T) {
+		mock := &thriftClient{fetchStalls: true}
+		op := newOp(mock, &Options{FetchMaxWait: 300 * time.Millisecond})
+		_, err := fetch(context.Background(), op)
+		require.ErrorIs(t, err, ErrFetchTimeout)
+		require.ErrorContains(t, err, "00000000-0000-0000-0000-000000000000")
+		require.Equal(t, 1, mock.cancelCalls)
+	})
+
+	t.Run("context deadline takes precedence", func(t *testing.T) {
+		mock := &thriftClient{fetchStalls: true}
+		op := newOp(mock, &Options{FetchMaxWait: time.Nanosecond})
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		_, err := fetch(ctx, op)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.NotErrorIs(t, err, ErrFetchTimeout)
+	})
+
+	t.Run("limit", func(t *testing.T) {
+		ctx := context.Background()
+		require.Equal(t, time.Duration(0), newOp(nil, &Options{}).fetchMaxWait(ctx))
+		require.Equal(t, time.Duration(0), newOp(nil, &Options{QueryTimeout: 30}).fetchMaxWait(ctx), "no default limit")
+		require.Equal(t, time.Second, newOp(nil, &Options{QueryTimeout: 30, FetchMaxWait: time.Second}).fetchMaxWait(ctx))
+		require.Equal(t, time.Duration(0), newOp(nil, &Options{QueryTimeout: 30, FetchMaxWait: -1}).fetchMaxWait(ctx))
+
+		deadlineCtx, cancel := context.WithTimeout(ctx, time.Hour)
+		defer cancel()
+		require.Equal(t, time.Duration(0), newOp(nil, &Options{QueryTimeout: 30}).fetchMaxWait(deadlineCtx))
+	})
+}
+
+func TestQueryState(t *testing.T) {
+	for state := cli_service.TOperationState_INITIALIZED_STATE; state <= cli_service.TOperationState_PENDING_STATE; state++ {
+		if state == cli_service.TOperationState_UKNOWN_STATE {
+			require.Equal(t, QueryStateUnknown, queryState(state))
+			continue
+		}
+		require.Equal(t, state.String(), queryState(state).String())
+	}
+	require.Equal(t, QueryStateUnknown, queryState(cli_service.TOperationState(100)))
 }
@@ -1,13 +1,41 @@
 package hive
 
 import (
+	"bytes"
 	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/fnv"
 	"io"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/sclgo/impala-go/internal/generated/cli_service"
 )
 
+// ErrResultSizeExceeded means the result set grew beyond Options.MaxResultBytes
+var ErrResultSizeExceeded = errors.New("impala: result size limit exceeded")
+
+// ErrMissingSchema means the server returned rows for an operation, whose result set metadata had no schema
+var ErrMissingSchema = errors.New("impala: malformed result: missing result set schema")
+
+// ErrRowBasedResults means the server returned results in the row-based encoding of HiveServer2 protocols
+// before V6, which the driver doesn't decode. Impala 2.x and later always use the column-based encoding.
+var ErrRowBasedResults = errors.New("impala: row-based results are not supported; " +
+	"the server must support HiveServer2 protocol V6 or later, which uses column-based results")
+
+// ErrRowLimitExceeded means the result set has more rows than Options.MaxRowsReturned
+var ErrRowLimitExceeded = errors.New("impala: row limit exceeded")
+
+// ErrCellTooLarge means a string value is larger than Options.MaxCellBytes
+var ErrCellTooLarge = errors.New("impala: value too large")
+
 // ResultSet ...
 type ResultSet struct {
 	idx     int
@@ -17,19 +45,108 @@ type ResultSet struct {
 
 	result *cli_service.TRowSet
 	more   bool
+
+	// maxBytes is the limit for totalBytes; 0 or negative means no limit
+	maxBytes   int64
+	totalBytes int64
+
+	// fetched is the number of rows returned by Next so far
+	fetched int64
+	// maxRows is the limit for fetched; 0 or negative means no limit
+	maxRows int64
+	// cancelfn cancels the operation when maxRows is exceeded
+	cancelfn func() error
+	// rewindfn fetches the first batch again; nil if the operation doesn't support it
+	rewindfn func() (*cli_service.TFetchResultsResp, error)
+	// rewind means the next fetch must use rewindfn
+	rewind bool
+	// err is the error of a failed fetch; Next keeps returning it instead of io.EOF
+	err error
+	// checksum accumulates the values returned by Next if Options.ResultChecksum is enabled; nil otherwise
+	checksum hash.Hash64
+}
+
+// Rewind restarts the result set from the first row. The next call to Next fetches the first batch again.
+// It fails with ErrRewindNotSupported if the result cache is not enabled with Options.ResultCacheSize.
+// The server may still reject restarting, e.g. if the result exceeded the cache, which Next reports as
+// ErrRewindNotSupported.
+func (rs *ResultSet) Rewind() error {
+	if rs.rewindfn == nil {
+		return fmt.Errorf("%w: enable the result cache with the result-cache-size parameter", ErrRewindNotSupported)
+	}
+	rs.rewind = true
+	rs.more = true
+	rs.err = nil
+	rs.result = nil
+	rs.idx = 0
+	rs.length = 0
+	rs.fetched = 0
+	rs.totalBytes = 0
+	if rs.checksum != nil {
+		rs.checksum.Reset()
+	}
+	return nil
+}
+
+// Checksum returns the hash of the values returned by Next so far, in order. ok is false if
+// Options.ResultChecksum is not enabled.
+func (rs *ResultSet) Checksum() (sum uint64, ok bool) {
+	if rs.checksum == nil {
+		return 0, false
+	}
+	return rs.checksum.Sum64(), true
+}
+
+// RowsFetched returns the number of rows returned by Next so far
+func (rs *ResultSet) RowsFetched() int64 {
+	return rs.fetched
+}
+
+// BatchHasNulls reports, for each column, whether the batch that contains the row returned by the last call
+// to Next has any NULL values. A batch is the set of rows received in a single fetch from the server.
+// The result is nil before the first batch is fetched.
+func (rs *ResultSet) BatchHasNulls() []bool {
+	if rs.result == nil {
+		return nil
+	}
+	res := make([]bool, len(rs.result.Columns))
+	for i, col := range rs.result.Columns {
+		res[i] = hasNulls(nulls(col), rs.length)
+	}
+	return res
 }
 
 // Next ...
 func (rs *ResultSet) Next(dest []driver.Value) error {
+	if rs.err != nil {
+		return rs.err
+	}
 	for rs.idx >= rs.length && rs.more {
 		// We don't sleep intentionally between loops following the example from impala-shell
 		// https://github.com/apache/impala/blob/1f35747/shell/impala_client.py#L958
-		resp, err := rs.fetchfn()
+		fetchfn := rs.fetchfn
+		if rs.rewind {
+			fetchfn = rs.rewindfn
+		}
+		resp, err := fetchfn()
 		if err != nil {
+			// the query may fail on the server after some rows were returned, e.g. with an ERROR status
+			// in a later fetch. The remaining rows are lost so the result set must not look complete.
+			rs.err = err
 			return err
 		}
+		rs.rewind = false
+		if isRowBased(resp.Results) {
+			rs.err = ErrRowBasedResults
+			return rs.err
+		}
 		rs.result = resp.Results
 		rs.more = resp.GetHasMoreRows()
+		if err = rs.trackSize(); err != nil {
+			// like fetch errors, the limit must not make the truncated result look complete
+			rs.err = err
+			return err
+		}
 		rs.idx = 0
 		rs.length = length(rs.result)
 		// It is possible for rs.more to be true, but length(rs.result) to be 0.
@@ -42,6 +159,21 @@ func (rs *ResultSet) Next(dest []driver.Value) error {
 		return io.EOF
 	}
 
+	if len(rs.schema.Columns) == 0 {
+		// statements without columns, e.g. some DDL, legitimately have no schema but then they have no rows either
+		return fmt.Errorf("%w: the server returned %d rows without a result set schema", ErrMissingSchema, rs.length)
+	}
+
+	if rs.maxRows > 0 && rs.fetched >= rs.maxRows {
+		return rs.rowLimitExceeded()
+	}
+
+	if len(dest) > len(rs.result.Columns) {
+		return fmt.Errorf("impala: malformed result: expected %d columns, got %d", len(dest), len(rs.result.Columns))
+	}
+	if len(dest) > len(rs.schema.Columns) {
+		return fmt.Errorf("impala: malformed result: expected %d columns, the schema has %d", len(dest), len(rs.schema.Columns))
+	}
 	for i := range dest {
 		val, err := value(rs.result.Columns[i], rs.schema.Columns[i], rs.idx)
 		if err != nil {
@@ -49,43 +181,258 @@ func (rs *ResultSet) Next(dest []driver.Value) error {
 		}
 		dest[i] = val
 	}
+	if rs.checksum != nil {
+		writeChecksum(rs.checksum, dest)
+	}
 	rs.idx++
+	rs.fetched++
+	return nil
+}
+
+// writeChecksum adds a row to the checksum. The type is included so e.g. int64(1) and "1" differ.
+func writeChecksum(h hash.Hash64, row []driver.Value) {
+	for _, val := range row {
+		_, _ = fmt.Fprintf(h, "%T\x1f%v\x1e", val, val)
+	}
+	_, _ = h.Write([]byte{'\n'})
+}
+
+func newChecksum(enabled bool) hash.Hash64 {
+	if !enabled {
+		return nil
+	}
+	return fnv.New64a()
+}
+
+// rowLimitExceeded stops fetching and cancels the operation so the server stops producing rows.
+// The error is kept in rs.err so later calls to Next don't report the truncated result as complete.
+func (rs *ResultSet) rowLimitExceeded() error {
+	rs.more = false
+	rs.idx = rs.length
+	err := fmt.Errorf("%w: the result has more than %d rows", ErrRowLimitExceeded, rs.maxRows)
+	if rs.cancelfn != nil {
+		if cancelErr := rs.cancelfn(); cancelErr != nil {
+			err = fmt.Errorf("%w; failed to cancel the query: %w", err, cancelErr)
+		}
+	}
+	rs.err = err
+	return err
+}
+
+// trackSize adds the size of the current batch to the running total and checks it against the limit
+func (rs *ResultSet) trackSize() error {
+	rs.totalBytes += size(rs.result)
+	if rs.maxBytes > 0 && rs.totalBytes > rs.maxBytes {
+		rs.more = false
+		return fmt.Errorf("%w: fetched %d bytes, limit is %d bytes", ErrResultSizeExceeded, rs.totalBytes, rs.maxBytes)
+	}
 	return nil
 }
 
 // isSet checks if the i-th member of the provided bitmap is set. Each byte contains 8 bit flags.
+// Servers may omit trailing zero bytes so bits beyond the end of bitmap are not set.
 func isSet(bitmap []byte, i int) bool {
+	if i/8 >= len(bitmap) {
+		return false
+	}
 	return bitmap[i/8]&(1<<(uint(i)%8)) != 0
 }
 
+// hasNulls checks if any of the first n members of bitmap is set. It checks whole bytes, except the last one.
+func hasNulls(bitmap []byte, n int) bool {
+	full := min(n/8, len(bitmap))
+	for _, b := range bitmap[:full] {
+		if b != 0 {
+			return true
+		}
+	}
+	for i := full * 8; i < n; i++ {
+		if isSet(bitmap, i) {
+			return true
+		}
+	}
+	return false
+}
+
+// value returns the i-th value in col, converted according to cd.
+// Malformed columns, which don't match the schema, cause an error rather than a panic.
 func value(col *cli_service.TColumn, cd *ColDesc, i int) (any, error) {
+	if cd.convertRaw != nil {
+		if raw, ok := rawValue(col, i); ok && raw != nil {
+			// the size limit applies to the values passed to converters too
+			if s, isString := raw.(string); isString {
+				var err error
+				if raw, err = limitCell(s, cd); err != nil {
+					return nil, err
+				}
+			}
+			v, handled, err := cd.convertRaw(raw)
+			if err != nil {
+				return nil, fmt.Errorf("impala: failed to convert value of column %s: %w", cd.Name, err)
+			}
+			if handled {
+				return v, nil
+			}
+		}
+	}
+	v, err := decode(col, cd, i)
+	if err == nil && v == nil && cd.nullZero != nil {
+		v = cd.nullZero()
+	}
+	if err != nil || v == nil || cd.convert == nil {
+		return v, err
+	}
+	v, err = cd.convert(v)
+	if err != nil {
+		return nil, fmt.Errorf("impala: failed to convert value of column %s: %w", cd.Name, err)
+	}
+	return v, nil
+}
+
+// rawValue returns the i-th value in col as sent by the server, or nil for NULL.
+// ok is false if col has no such value, which decode reports as a malformed result.
+func rawValue(col *cli_service.TColumn, i int) (v any, ok bool) {
+	switch {
+	case col == nil:
+		return nil, false
+	case col.BoolVal != nil:
+		return rawAt(col.BoolVal.Values, col.BoolVal.Nulls, i)
+	case col.ByteVal != nil:
+		return rawAt(col.ByteVal.Values, col.ByteVal.Nulls, i)
+	case col.I16Val != nil:
+		return rawAt(col.I16Val.Values, col.I16Val.Nulls, i)
+	case col.I32Val != nil:
+		return rawAt(col.I32Val.Values, col.I32Val.Nulls, i)
+	case col.I64Val != nil:
+		return rawAt(col.I64Val.Values, col.I64Val.Nulls, i)
+	case col.DoubleVal != nil:
+		return rawAt(col.DoubleVal.Values, col.DoubleVal.Nulls, i)
+	case col.StringVal != nil:
+		return rawAt(col.StringVal.Values, col.StringVal.Nulls, i)
+	case col.BinaryVal != nil:
+		return rawAt(col.BinaryVal.Values, col.BinaryVal.Nulls, i)
+	default:
+		return nil, false
+	}
+}
+
+func rawAt[T any](values []T, nulls []byte, i int) (any, bool) {
+	if i >= len(values) {
+		return nil, false
+	}
+	if isSet(nulls, i) {
+		return nil, true
+	}
+	return values[i], true
+}
+
+// intBoolValue decodes a BOOLEAN value, which some server versions send as an integer column variant.
+// Nonzero values are true.
+func intBoolValue(col *cli_service.TColumn, cd *ColDesc, i int) (any, error) {
+	var v any
+	var ok bool
+	if col.ByteVal != nil || col.I16Val != nil || col.I32Val != nil || col.I64Val != nil {
+		v, ok = rawValue(col, i)
+	}
+	if !ok {
+		return nil, malformedError(col, cd, "bool")
+	}
+	switch n := v.(type) {
+	case nil:
+		return nil, nil
+	case int8:
+		return n != 0, nil
+	case int16:
+		return n != 0, nil
+	case int32:
+		return n != 0, nil
+	default:
+		return n.(int64) != 0, nil
+	}
+}
+
+// limitCell applies cd.maxCellBytes, which is set only for string columns: it fails with ErrCellTooLarge or
+// truncates s, if cd.truncateCells is enabled.
+func limitCell(s string, cd *ColDesc) (string, error) {
+	if cd.maxCellBytes <= 0 || len(s) <= cd.maxCellBytes {
+		return s, nil
+	}
+	if !cd.truncateCells {
+		return "", fmt.Errorf("%w: a value of column %s has %d bytes, more than the limit of %d",
+			ErrCellTooLarge, cd.Name, len(s), cd.maxCellBytes)
+	}
+	return truncateCell(s, cd.maxCellBytes), nil
+}
+
+// truncateCell returns the longest prefix of s, which has at most n bytes and doesn't split a UTF-8 character.
+// The prefix is copied so that the large value can be garbage-collected with the rest of the batch.
+func truncateCell(s string, n int) string {
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return strings.Clone(s[:n])
+}
+
+// decode returns the i-th value in col as the Go type of the column ScanType, before any ValueConverter
+func decode(col *cli_service.TColumn, cd *ColDesc, i int) (any, error) {
+	if col == nil {
+		return nil, malformedError(col, cd, "any")
+	}
 	switch cd.DatabaseTypeName {
 	case "STRING", "CHAR", "VARCHAR":
+		if col.StringVal == nil || i >= len(col.StringVal.Values) {
+			return nil, malformedError(col, cd, "string")
+		}
 		if isSet(col.StringVal.Nulls, i) {
 			return nil, nil
 		}
-		return col.StringVal.Values[i], nil
+		s, err := limitCell(col.StringVal.Values[i], cd)
+		if err != nil {
+			return nil, err
+		}
+		if cd.trimChar && cd.DatabaseTypeName == "CHAR" {
+			return strings.TrimRight(s, " "), nil
+		}
+		return s, nil
 	case "TINYINT":
+		if col.ByteVal == nil || i >= len(col.ByteVal.Values) {
+			return nil, malformedError(col, cd, "byte")
+		}
 		if isSet(col.ByteVal.Nulls, i) {
 			return nil, nil
 		}
 		return col.ByteVal.Values[i], nil
 	case "SMALLINT":
+		if col.I16Val == nil || i >= len(col.I16Val.Values) {
+			return nil, malformedError(col, cd, "i16")
+		}
 		if isSet(col.I16Val.Nulls, i) {
 			return nil, nil
 		}
 		return col.I16Val.Values[i], nil
 	case "INT":
+		if col.I32Val == nil || i >= len(col.I32Val.Values) {
+			return nil, malformedError(col, cd, "i32")
+		}
 		if isSet(col.I32Val.Nulls, i) {
 			return nil, nil
 		}
 		return col.I32Val.Values[i], nil
 	case "BIGINT":
+		if col.I64Val == nil || i >= len(col.I64Val.Values) {
+			return nil, malformedError(col, cd, "i64")
+		}
 		if isSet(col.I64Val.Nulls, i) {
 			return nil, nil
 		}
 		return col.I64Val.Values[i], nil
 	case "BOOLEAN":
+		if col.BoolVal == nil {
+			return intBoolValue(col, cd, i)
+		}
+		if i >= len(col.BoolVal.Values) {
+			return nil, malformedError(col, cd, "bool")
+		}
 		if isSet(col.BoolVal.Nulls, i) {
 			return nil, nil
 		}
@@ -93,27 +440,157 @@ func value(col *cli_service.TColumn, cd *ColDesc, i int) (any, error) {
 	case "FLOAT", "DOUBLE":
 		// we could return float values as float32(col.DoubleVal.Values[i])
 		// but it is not worth the complexity
+		if col.DoubleVal == nil || i >= len(col.DoubleVal.Values) {
+			return nil, malformedError(col, cd, "double")
+		}
 		if isSet(col.DoubleVal.Nulls, i) {
 			return nil, nil
 		}
 		return col.DoubleVal.Values[i], nil
-	case "TIMESTAMP", "DATETIME":
-		if isSet(col.StringVal.Nulls, i) {
+	}
+
+	if cd.DatabaseTypeName == "BINARY" && col.BinaryVal != nil {
+		if i >= len(col.BinaryVal.Values) {
+			return nil, malformedError(col, cd, "binary")
+		}
+		if isSet(col.BinaryVal.Nulls, i) {
 			return nil, nil
 		}
+		return col.BinaryVal.Values[i], nil
+	}
+
+	// all other types are sent as strings
+	if col.StringVal == nil || i >= len(col.StringVal.Values) {
+		return nil, malformedError(col, cd, "string")
+	}
+	if isSet(col.StringVal.Nulls, i) {
+		return nil, nil
+	}
+	switch cd.DatabaseTypeName {
+	case "DECIMAL":
+		return decimalValue(col.StringVal.Values[i], cd.ScanType)
+	case "TIMESTAMP", "DATETIME":
+		// the 9s in TimestampFormat accept 0 to 9 fractional digits, as well as no fraction at all,
+		// so the single layout handles all precisions, which Impala emits
 		t, err := time.Parse(TimestampFormat, col.StringVal.Values[i])
 		if err != nil {
 			return nil, err
 		}
 		return t, nil
-	default:
-		if isSet(col.StringVal.Nulls, i) {
-			return nil, nil
+	case "DATE":
+		if cd.ScanType != dataTypeDateTime {
+			return col.StringVal.Values[i], nil
 		}
+		// time.Time at midnight UTC, see Options.DateAs
+		return time.Parse(DateFormat, col.StringVal.Values[i])
+	case "BINARY":
+		return []byte(col.StringVal.Values[i]), nil
+	case "ARRAY", "MAP", "STRUCT":
+		return formatJSON(col.StringVal.Values[i], cd)
+	default:
 		return col.StringVal.Values[i], nil
 	}
 }
 
+// formatJSON reformats the JSON encoding of a complex type value, which Impala sends as a string,
+// according to cd.jsonFormat
+func formatJSON(s string, cd *ColDesc) (any, error) {
+	var buf bytes.Buffer
+	var err error
+	switch cd.jsonFormat {
+	case ComplexJSONCompact:
+		err = json.Compact(&buf, []byte(s))
+	case ComplexJSONIndent:
+		err = json.Indent(&buf, []byte(s), "", "  ")
+	default:
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("impala: malformed result: column %s of type %s has invalid JSON value: %w",
+			cd.Name, cd.DatabaseTypeName, err)
+	}
+	return buf.String(), nil
+}
+
+// malformedError describes a column that doesn't contain the values its type in the result schema requires
+func malformedError(col *cli_service.TColumn, cd *ColDesc, expected string) error {
+	return fmt.Errorf("impala: malformed result: column %s of type %s has no %s value at the current row; got %s column",
+		cd.Name, cd.DatabaseTypeName, expected, columnVariant(col))
+}
+
+// columnVariant returns the name of the set TColumn field
+func columnVariant(col *cli_service.TColumn) string {
+	switch {
+	case col == nil:
+		return "nil"
+	case col.BoolVal != nil:
+		return "bool"
+	case col.ByteVal != nil:
+		return "byte"
+	case col.I16Val != nil:
+		return "i16"
+	case col.I32Val != nil:
+		return "i32"
+	case col.I64Val != nil:
+		return "i64"
+	case col.DoubleVal != nil:
+		return "double"
+	case col.StringVal != nil:
+		return "string"
+	case col.BinaryVal != nil:
+		return "binary"
+	default:
+		return "empty"
+	}
+}
+
+// nulls returns the null bitmap of col
+func nulls(col *cli_service.TColumn) []byte {
+	switch {
+	case col == nil:
+		return nil
+	case col.BoolVal != nil:
+		return col.BoolVal.Nulls
+	case col.ByteVal != nil:
+		return col.ByteVal.Nulls
+	case col.I16Val != nil:
+		return col.I16Val.Nulls
+	case col.I32Val != nil:
+		return col.I32Val.Nulls
+	case col.I64Val != nil:
+		return col.I64Val.Nulls
+	case col.DoubleVal != nil:
+		return col.DoubleVal.Nulls
+	case col.StringVal != nil:
+		return col.StringVal.Nulls
+	case col.BinaryVal != nil:
+		return col.BinaryVal.Nulls
+	default:
+		return nil
+	}
+}
+
+// decimalValue converts the string representation of a DECIMAL value to scanType, chosen by decimalScanType
+func decimalValue(s string, scanType reflect.Type) (any, error) {
+	switch scanType {
+	case dataTypeFloat64:
+		return strconv.ParseFloat(s, 64)
+	case dataTypeRat:
+		r, ok := new(big.Rat).SetString(s)
+		if !ok {
+			return nil, fmt.Errorf("invalid decimal value: %s", s)
+		}
+		return r, nil
+	default:
+		return s, nil
+	}
+}
+
+// isRowBased reports if the server used the row-based encoding, which length and value don't support
+func isRowBased(rs *cli_service.TRowSet) bool {
+	return rs != nil && len(rs.Columns) == 0 && len(rs.Rows) > 0
+}
+
 func length(rs *cli_service.TRowSet) int {
 	if rs == nil {
 		return 0
@@ -131,9 +608,6 @@ func length(rs *cli_service.TRowSet) int {
 		if col.I32Val != nil {
 			return len(col.I32Val.Values)
 		}
-		if col.I32Val != nil {
-			return len(col.I32Val.Values)
-		}
 		if col.I64Val != nil {
 			return len(col.I64Val.Values)
 		}
@@ -143,6 +617,43 @@ func length(rs *cli_service.TRowSet) int {
 		if col.DoubleVal != nil {
 			return len(col.DoubleVal.Values)
 		}
+		if col.BinaryVal != nil {
+			return len(col.BinaryVal.Values)
+		}
 	}
 	return 0
 }
+
+// size estimates the in-memory size of the decoded values in rs.
+// Null bitmaps and fixed-size overhead are not counted.
+func size(rs *cli_service.TRowSet) int64 {
+	if rs == nil {
+		return 0
+	}
+	var result int64
+	for _, col := range rs.Columns {
+		switch {
+		case col.BoolVal != nil:
+			result += int64(len(col.BoolVal.Values))
+		case col.ByteVal != nil:
+			result += int64(len(col.ByteVal.Values))
+		case col.I16Val != nil:
+			result += 2 * int64(len(col.I16Val.Values))
+		case col.I32Val != nil:
+			result += 4 * int64(len(col.I32Val.Values))
+		case col.I64Val != nil:
+			result += 8 * int64(len(col.I64Val.Values))
+		case col.DoubleVal != nil:
+			result += 8 * int64(len(col.DoubleVal.Values))
+		case col.StringVal != nil:
+			for _, v := range col.StringVal.Values {
+				result += int64(len(v))
+			}
+		case col.BinaryVal != nil:
+			for _, v := range col.BinaryVal.Values {
+				result += int64(len(v))
+			}
+		}
+	}
+	return result
+}
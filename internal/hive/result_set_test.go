@@ -2,9 +2,14 @@ package hive
 
 import (
 	"database/sql/driver"
+	"errors"
 	"fmt"
 	"io"
+	"math/big"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/samber/lo"
 	"github.com/sclgo/impala-go/internal/generated/cli_service"
@@ -45,6 +50,111 @@ func TestResultSet(t *testing.T) {
 		require.EqualValues(t, "hello", data[0])
 		err = rs.Next(data)
 		require.Equal(t, io.EOF, err)
+		require.EqualValues(t, 1, rs.RowsFetched())
+	})
+
+	t.Run("max bytes exceeded", func(t *testing.T) {
+		batch := []*cli_service.TColumn{
+			{
+				StringVal: &cli_service.TStringColumn{
+					Nulls:  []byte{0},
+					Values: []string{"hello"},
+				},
+			},
+		}
+		r := &results{
+			data: []any{batch, batch},
+		}
+		rs := ResultSet{
+			fetchfn:  r.fetch,
+			more:     true,
+			maxBytes: 8,
+			schema: &TableSchema{
+				Columns: []*ColDesc{
+					{
+						DatabaseTypeName: "STRING",
+					},
+				},
+			},
+		}
+		data := make([]driver.Value, 1)
+		require.NoError(t, rs.Next(data))
+		err := rs.Next(data)
+		require.ErrorIs(t, err, ErrResultSizeExceeded)
+		require.Equal(t, err, rs.Next(data), "the error sticks")
+	})
+
+	t.Run("error status after rows", func(t *testing.T) {
+		batch := []*cli_service.TColumn{
+			{
+				StringVal: &cli_service.TStringColumn{
+					Nulls:  []byte{0},
+					Values: []string{"hello"},
+				},
+			},
+		}
+		failed := checkStatus(&cli_service.TFetchResultsResp{
+			Status: &cli_service.TStatus{
+				StatusCode:   cli_service.TStatusCode_ERROR_STATUS,
+				ErrorMessage: lo.ToPtr("Memory limit exceeded"),
+			},
+		})
+		r := &results{
+			data: []any{batch, failed},
+		}
+		rs := ResultSet{
+			fetchfn: r.fetch,
+			more:    true,
+			schema: &TableSchema{
+				Columns: []*ColDesc{
+					{
+						DatabaseTypeName: "STRING",
+					},
+				},
+			},
+		}
+		data := make([]driver.Value, 1)
+		require.NoError(t, rs.Next(data))
+		require.EqualValues(t, "hello", data[0])
+		err := rs.Next(data)
+		var statusErr *StatusError
+		require.ErrorAs(t, err, &statusErr)
+		require.ErrorContains(t, err, "Memory limit exceeded")
+		// the error sticks without fetching again - the mock would panic on a third fetch
+		require.Equal(t, err, rs.Next(data))
+		require.EqualValues(t, 1, rs.RowsFetched())
+	})
+
+	t.Run("binary columns only", func(t *testing.T) {
+		r := &results{
+			data: []any{
+				[]*cli_service.TColumn{
+					{
+						BinaryVal: &cli_service.TBinaryColumn{
+							Nulls:  []byte{0b10},
+							Values: [][]byte{{0xca, 0xfe}, nil},
+						},
+					},
+				},
+			},
+		}
+		rs := ResultSet{
+			fetchfn: r.fetch,
+			more:    true,
+			schema: &TableSchema{
+				Columns: []*ColDesc{
+					{
+						DatabaseTypeName: "BINARY",
+					},
+				},
+			},
+		}
+		data := make([]driver.Value, 1)
+		require.NoError(t, rs.Next(data))
+		require.Equal(t, []byte{0xca, 0xfe}, data[0])
+		require.NoError(t, rs.Next(data))
+		require.Nil(t, data[0])
+		require.Equal(t, io.EOF, rs.Next(data))
 	})
 }
 
@@ -75,3 +185,611 @@ func (r *results) fetch() (*cli_service.TFetchResultsResp, error) {
 	}
 
 }
+
+func TestValue_Decimal(t *testing.T) {
+	col := &cli_service.TColumn{
+		StringVal: &cli_service.TStringColumn{
+			Nulls:  []byte{0b100},
+			Values: []string{"-12.345", "123456789012345678901234567890.12345678", ""},
+		},
+	}
+	highPrecision, _ := new(big.Rat).SetString("123456789012345678901234567890.12345678")
+	tests := []struct {
+		mode     string
+		scanType reflect.Type
+		expected []any
+	}{
+		{"", dataTypeString, []any{"-12.345", "123456789012345678901234567890.12345678"}},
+		{DecimalAsString, dataTypeString, []any{"-12.345", "123456789012345678901234567890.12345678"}},
+		{DecimalAsFloat64, dataTypeFloat64, []any{-12.345, 1.2345678901234568e+29}},
+		{DecimalAsRat, dataTypeRat, []any{big.NewRat(-12345, 1000), highPrecision}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			cd := &ColDesc{DatabaseTypeName: "DECIMAL", ScanType: decimalScanType(tt.mode)}
+			require.Equal(t, tt.scanType, cd.ScanType)
+			for i, expected := range tt.expected {
+				val, err := value(col, cd, i)
+				require.NoError(t, err)
+				require.Equal(t, expected, val)
+				require.Equal(t, tt.scanType, reflect.TypeOf(val))
+			}
+			val, err := value(col, cd, 2)
+			require.NoError(t, err)
+			require.Nil(t, val)
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := decimalValue("abc", dataTypeRat)
+		require.Error(t, err)
+		_, err = decimalValue("abc", dataTypeFloat64)
+		require.Error(t, err)
+	})
+}
+
+// TestValue_ScanType checks that the values of every type match the ScanType of their column,
+// which ORMs rely on to allocate scan destinations
+func TestValue_ScanType(t *testing.T) {
+	str := func(s string) *cli_service.TColumn {
+		return &cli_service.TColumn{StringVal: &cli_service.TStringColumn{Values: []string{s}, Nulls: []byte{0}}}
+	}
+	columns := map[cli_service.TTypeId]*cli_service.TColumn{
+		cli_service.TTypeId_BOOLEAN_TYPE:      {BoolVal: &cli_service.TBoolColumn{Values: []bool{true}, Nulls: []byte{0}}},
+		cli_service.TTypeId_TINYINT_TYPE:      {ByteVal: &cli_service.TByteColumn{Values: []int8{1}, Nulls: []byte{0}}},
+		cli_service.TTypeId_SMALLINT_TYPE:     {I16Val: &cli_service.TI16Column{Values: []int16{1}, Nulls: []byte{0}}},
+		cli_service.TTypeId_INT_TYPE:          {I32Val: &cli_service.TI32Column{Values: []int32{1}, Nulls: []byte{0}}},
+		cli_service.TTypeId_BIGINT_TYPE:       {I64Val: &cli_service.TI64Column{Values: []int64{1}, Nulls: []byte{0}}},
+		cli_service.TTypeId_FLOAT_TYPE:        {DoubleVal: &cli_service.TDoubleColumn{Values: []float64{1.5}, Nulls: []byte{0}}},
+		cli_service.TTypeId_DOUBLE_TYPE:       {DoubleVal: &cli_service.TDoubleColumn{Values: []float64{1.5}, Nulls: []byte{0}}},
+		cli_service.TTypeId_STRING_TYPE:       str("s"),
+		cli_service.TTypeId_TIMESTAMP_TYPE:    str("2024-01-02 03:04:05"),
+		cli_service.TTypeId_BINARY_TYPE:       {BinaryVal: &cli_service.TBinaryColumn{Values: [][]byte{{0xff}}, Nulls: []byte{0}}},
+		cli_service.TTypeId_ARRAY_TYPE:        str("[1,2]"),
+		cli_service.TTypeId_MAP_TYPE:          str(`{"a":1}`),
+		cli_service.TTypeId_STRUCT_TYPE:       str(`{"a":1}`),
+		cli_service.TTypeId_UNION_TYPE:        str("u"),
+		cli_service.TTypeId_USER_DEFINED_TYPE: str("u"),
+		cli_service.TTypeId_DECIMAL_TYPE:      str("1.25"),
+		cli_service.TTypeId_NULL_TYPE:         {StringVal: &cli_service.TStringColumn{Values: []string{""}, Nulls: []byte{1}}},
+		cli_service.TTypeId_DATE_TYPE:         str("2024-01-02"),
+		cli_service.TTypeId_VARCHAR_TYPE:      str("s"),
+		cli_service.TTypeId_CHAR_TYPE:         str("s "),
+	}
+	for typeId := cli_service.TTypeId_BOOLEAN_TYPE; typeId <= cli_service.TTypeId_CHAR_TYPE; typeId++ {
+		require.Contains(t, columns, typeId)
+	}
+
+	for typeId, col := range columns {
+		t.Run(typeId.String(), func(t *testing.T) {
+			cd, err := NewColDesc("c", typeId.String())
+			require.NoError(t, err)
+			require.NotNil(t, cd.ScanType)
+			v, err := value(col, cd, 0)
+			require.NoError(t, err)
+			if typeId == cli_service.TTypeId_NULL_TYPE {
+				require.Nil(t, v)
+				return
+			}
+			require.Equal(t, cd.ScanType, reflect.TypeOf(v))
+		})
+	}
+
+	t.Run("binary sent as string", func(t *testing.T) {
+		cd, err := NewColDesc("c", "BINARY")
+		require.NoError(t, err)
+		v, err := value(str("ab"), cd, 0)
+		require.NoError(t, err)
+		require.Equal(t, []byte("ab"), v)
+	})
+}
+
+func TestValue_Malformed(t *testing.T) {
+	// the server sends I64 for a column that the schema calls STRING
+	col := &cli_service.TColumn{
+		I64Val: &cli_service.TI64Column{
+			Nulls:  []byte{0},
+			Values: []int64{1},
+		},
+	}
+	cd := &ColDesc{Name: "a", DatabaseTypeName: "STRING"}
+	_, err := value(col, cd, 0)
+	require.ErrorContains(t, err, "column a of type STRING has no string value")
+	require.ErrorContains(t, err, "got i64 column")
+
+	cd = &ColDesc{Name: "a", DatabaseTypeName: "BIGINT"}
+	_, err = value(col, cd, 1)
+	require.ErrorContains(t, err, "malformed result")
+
+	_, err = value(nil, cd, 0)
+	require.ErrorContains(t, err, "got nil column")
+
+	t.Run("missing columns", func(t *testing.T) {
+		r := &results{
+			data: []any{
+				[]*cli_service.TColumn{col},
+			},
+		}
+		rs := ResultSet{
+			fetchfn: r.fetch,
+			more:    true,
+			schema:  &TableSchema{Columns: []*ColDesc{cd, cd}},
+		}
+		err := rs.Next(make([]driver.Value, 2))
+		require.ErrorContains(t, err, "expected 2 columns, got 1")
+	})
+}
+
+func TestValue_Boolean(t *testing.T) {
+	cd := &ColDesc{Name: "b", DatabaseTypeName: "BOOLEAN"}
+	columns := map[string]*cli_service.TColumn{
+		"bool": {BoolVal: &cli_service.TBoolColumn{Values: []bool{true, false, false}, Nulls: []byte{0b100}}},
+		"byte": {ByteVal: &cli_service.TByteColumn{Values: []int8{1, 0, 0}, Nulls: []byte{0b100}}},
+		"i32":  {I32Val: &cli_service.TI32Column{Values: []int32{-1, 0, 0}, Nulls: []byte{0b100}}},
+		"i64":  {I64Val: &cli_service.TI64Column{Values: []int64{2, 0, 0}, Nulls: []byte{0b100}}},
+	}
+	for name, col := range columns {
+		t.Run(name, func(t *testing.T) {
+			var values []any
+			for i := range 3 {
+				v, err := value(col, cd, i)
+				require.NoError(t, err)
+				values = append(values, v)
+			}
+			require.Equal(t, []any{true, false, nil}, values)
+		})
+	}
+
+	t.Run("unsupported variant", func(t *testing.T) {
+		col := &cli_service.TColumn{StringVal: &cli_service.TStringColumn{Values: []string{"true"}}}
+		_, err := value(col, cd, 0)
+		require.ErrorContains(t, err, "column b of type BOOLEAN has no bool value")
+	})
+}
+
+func TestValue_Timestamp(t *testing.T) {
+	cd := &ColDesc{Name: "ts", DatabaseTypeName: "TIMESTAMP"}
+	tests := map[string]time.Time{
+		"2024-01-02 03:04:05":           time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		"2024-01-02 03:04:05.0":         time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		"2024-01-02 03:04:05.123":       time.Date(2024, 1, 2, 3, 4, 5, 123000000, time.UTC),
+		"2024-01-02 03:04:05.123456":    time.Date(2024, 1, 2, 3, 4, 5, 123456000, time.UTC),
+		"2024-01-02 03:04:05.123456789": time.Date(2024, 1, 2, 3, 4, 5, 123456789, time.UTC),
+	}
+	for s, expected := range tests {
+		col := &cli_service.TColumn{StringVal: &cli_service.TStringColumn{Values: []string{s}, Nulls: []byte{0}}}
+		v, err := value(col, cd, 0)
+		require.NoError(t, err, s)
+		require.Equal(t, expected, v, s)
+	}
+
+	col := &cli_service.TColumn{StringVal: &cli_service.TStringColumn{Values: []string{"2024-01-02"}, Nulls: []byte{0}}}
+	_, err := value(col, cd, 0)
+	require.Error(t, err)
+}
+
+func TestValue_NullAsZero(t *testing.T) {
+	null := &cli_service.TColumn{I64Val: &cli_service.TI64Column{Values: []int64{0}, Nulls: []byte{1}}}
+	nullString := &cli_service.TColumn{StringVal: &cli_service.TStringColumn{Values: []string{""}, Nulls: []byte{1}}}
+	nullDouble := &cli_service.TColumn{DoubleVal: &cli_service.TDoubleColumn{Values: []float64{0}, Nulls: []byte{1}}}
+
+	t.Run("disabled", func(t *testing.T) {
+		cd := &ColDesc{Name: "n", DatabaseTypeName: "BIGINT", ScanType: dataTypeInt64}
+		v, err := value(null, cd, 0)
+		require.NoError(t, err)
+		require.Nil(t, v)
+	})
+
+	tests := []struct {
+		typeName  string
+		decimalAs string
+		col       *cli_service.TColumn
+		expected  any
+	}{
+		{"BIGINT", "", null, int64(0)},
+		{"DOUBLE", "", nullDouble, float64(0)},
+		{"DECIMAL", DecimalAsString, nullString, "0"},
+		{"DECIMAL", DecimalAsFloat64, nullString, float64(0)},
+		{"DECIMAL", DecimalAsRat, nullString, big.NewRat(0, 1)},
+		{"STRING", "", nullString, nil},
+		{"TIMESTAMP", "", nullString, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.typeName+" "+tt.decimalAs, func(t *testing.T) {
+			cd, err := NewColDesc("n", tt.typeName)
+			require.NoError(t, err)
+			if tt.decimalAs != "" {
+				cd.ScanType = decimalScanType(tt.decimalAs)
+			}
+			cd.nullZero = zeroFor(cd)
+			v, err := value(tt.col, cd, 0)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, v)
+			if v != nil {
+				require.Equal(t, cd.ScanType, reflect.TypeOf(v))
+			}
+		})
+	}
+
+	t.Run("boolean", func(t *testing.T) {
+		cd, err := NewColDesc("b", "BOOLEAN")
+		require.NoError(t, err)
+		cd.nullZero = zeroFor(cd)
+		col := &cli_service.TColumn{BoolVal: &cli_service.TBoolColumn{Values: []bool{true, false}, Nulls: []byte{0b10}}}
+		v, err := value(col, cd, 0)
+		require.NoError(t, err)
+		require.Equal(t, true, v)
+		v, err = value(col, cd, 1)
+		require.NoError(t, err)
+		require.Equal(t, false, v)
+	})
+}
+
+func TestValue_Date(t *testing.T) {
+	col := &cli_service.TColumn{StringVal: &cli_service.TStringColumn{Values: []string{"2024-01-02", "0001-01-01"}, Nulls: []byte{0}}}
+
+	t.Run("string", func(t *testing.T) {
+		cd := &ColDesc{Name: "d", DatabaseTypeName: "DATE", ScanType: dateScanType("")}
+		v, err := value(col, cd, 0)
+		require.NoError(t, err)
+		require.Equal(t, "2024-01-02", v)
+	})
+
+	t.Run("time", func(t *testing.T) {
+		cd := &ColDesc{Name: "d", DatabaseTypeName: "DATE", ScanType: dateScanType(DateAsTime)}
+		v, err := value(col, cd, 0)
+		require.NoError(t, err)
+		require.Equal(t, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), v)
+		v, err = value(col, cd, 1)
+		require.NoError(t, err)
+		require.Equal(t, time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC), v)
+	})
+}
+
+func TestResultSet_RowBased(t *testing.T) {
+	r := &results{
+		data: []any{
+			&cli_service.TFetchResultsResp{
+				Status:      &cli_service.TStatus{},
+				HasMoreRows: lo.ToPtr(false),
+				Results: &cli_service.TRowSet{
+					Rows: []*cli_service.TRow{{
+						ColVals: []*cli_service.TColumnValue{{I32Val: &cli_service.TI32Value{Value: lo.ToPtr(int32(1))}}},
+					}},
+				},
+			},
+		},
+	}
+	rs := ResultSet{
+		fetchfn: r.fetch,
+		more:    true,
+		schema:  &TableSchema{Columns: []*ColDesc{{Name: "a", DatabaseTypeName: "INT"}}},
+	}
+	err := rs.Next(make([]driver.Value, 1))
+	require.ErrorIs(t, err, ErrRowBasedResults)
+	// the error sticks without fetching again - the mock would panic on a second fetch
+	require.ErrorIs(t, rs.Next(make([]driver.Value, 1)), ErrRowBasedResults)
+}
+
+func TestIsSet_ShortBitmap(t *testing.T) {
+	require.True(t, isSet([]byte{0b10}, 1))
+	require.False(t, isSet([]byte{0xff}, 8))
+	require.False(t, isSet(nil, 0))
+}
+
+func TestValue_CharTrim(t *testing.T) {
+	col := &cli_service.TColumn{
+		StringVal: &cli_service.TStringColumn{
+			Nulls:  []byte{0},
+			Values: []string{"str       "},
+		},
+	}
+	val, err := value(col, &ColDesc{DatabaseTypeName: "CHAR"}, 0)
+	require.NoError(t, err)
+	require.Equal(t, "str       ", val)
+	val, err = value(col, &ColDesc{DatabaseTypeName: "CHAR", trimChar: true}, 0)
+	require.NoError(t, err)
+	require.Equal(t, "str", val)
+	val, err = value(col, &ColDesc{DatabaseTypeName: "VARCHAR", trimChar: true}, 0)
+	require.NoError(t, err)
+	require.Equal(t, "str       ", val)
+}
+
+func TestValue_MaxCellBytes(t *testing.T) {
+	large := strings.Repeat("x", 1<<20)
+	col := &cli_service.TColumn{
+		StringVal: &cli_service.TStringColumn{
+			Nulls:  []byte{0},
+			Values: []string{"small", large, "żółw"},
+		},
+	}
+
+	t.Run("error", func(t *testing.T) {
+		cd := &ColDesc{Name: "doc", DatabaseTypeName: "STRING", maxCellBytes: 1024}
+		val, err := value(col, cd, 0)
+		require.NoError(t, err)
+		require.Equal(t, "small", val)
+		_, err = value(col, cd, 1)
+		require.ErrorIs(t, err, ErrCellTooLarge)
+		require.ErrorContains(t, err, "column doc has 1048576 bytes")
+	})
+
+	t.Run("truncate", func(t *testing.T) {
+		cd := &ColDesc{Name: "doc", DatabaseTypeName: "STRING", maxCellBytes: 1024, truncateCells: true}
+		val, err := value(col, cd, 1)
+		require.NoError(t, err)
+		require.Equal(t, large[:1024], val)
+		// each of "żół" takes 2 bytes so the first 5 bytes would split "ł"
+		cd.maxCellBytes = 5
+		val, err = value(col, cd, 2)
+		require.NoError(t, err)
+		require.Equal(t, "żó", val)
+		cd.maxCellBytes = 3
+		val, err = value(col, cd, 2)
+		require.NoError(t, err)
+		require.Equal(t, "ż", val)
+	})
+
+	t.Run("converter", func(t *testing.T) {
+		cd := &ColDesc{Name: "doc", DatabaseTypeName: "STRING", maxCellBytes: 1024}
+		applyConverter(cd, []ValueConverter{{
+			ConvertRaw: func(raw any) (any, bool, error) { return len(raw.(string)), true, nil },
+		}})
+		_, err := value(col, cd, 1)
+		require.ErrorIs(t, err, ErrCellTooLarge, "the limit applies before converters")
+		cd.truncateCells = true
+		val, err := value(col, cd, 1)
+		require.NoError(t, err)
+		require.Equal(t, 1024, val)
+	})
+}
+
+func TestResultSet_MaxRows(t *testing.T) {
+	batch := []*cli_service.TColumn{
+		{
+			I32Val: &cli_service.TI32Column{
+				Nulls:  []byte{0},
+				Values: []int32{1, 2, 3},
+			},
+		},
+	}
+	r := &results{
+		data: []any{batch, batch},
+	}
+	var cancelled int
+	rs := ResultSet{
+		fetchfn: r.fetch,
+		more:    true,
+		maxRows: 4,
+		cancelfn: func() error {
+			cancelled++
+			return nil
+		},
+		schema: &TableSchema{
+			Columns: []*ColDesc{
+				{
+					DatabaseTypeName: "INT",
+				},
+			},
+		},
+	}
+	data := make([]driver.Value, 1)
+	for range 4 {
+		require.NoError(t, rs.Next(data))
+	}
+	require.Zero(t, cancelled)
+	err := rs.Next(data)
+	require.ErrorIs(t, err, ErrRowLimitExceeded)
+	require.Equal(t, 1, cancelled)
+	require.Equal(t, err, rs.Next(data), "the error sticks")
+	require.Equal(t, err, rs.Next(data))
+	require.Equal(t, 1, cancelled, "the query is cancelled once")
+}
+
+func TestResultSet_Rewind(t *testing.T) {
+	batch := []*cli_service.TColumn{
+		{I32Val: &cli_service.TI32Column{Nulls: []byte{0}, Values: []int32{1, 2}}},
+	}
+	r := &results{
+		data: []any{batch, batch},
+	}
+	rs := ResultSet{
+		fetchfn: r.fetch,
+		more:    true,
+		schema: &TableSchema{
+			Columns: []*ColDesc{{DatabaseTypeName: "INT"}},
+		},
+	}
+	data := make([]driver.Value, 1)
+	require.NoError(t, rs.Next(data))
+	require.ErrorIs(t, rs.Rewind(), ErrRewindNotSupported)
+
+	var rewound int
+	rs.rewindfn = func() (*cli_service.TFetchResultsResp, error) {
+		rewound++
+		// the server restarts from the first batch
+		r.idx = 0
+		return r.fetch()
+	}
+	require.NoError(t, rs.Next(data))
+	require.NoError(t, rs.Rewind())
+	require.Zero(t, rs.RowsFetched())
+	var values []driver.Value
+	for rs.Next(data) == nil {
+		values = append(values, data[0])
+	}
+	require.Equal(t, []driver.Value{int32(1), int32(2), int32(1), int32(2)}, values)
+	require.Equal(t, 1, rewound)
+	require.Equal(t, int64(4), rs.RowsFetched())
+
+	rs.rewindfn = func() (*cli_service.TFetchResultsResp, error) {
+		return nil, ErrRewindNotSupported
+	}
+	require.NoError(t, rs.Rewind())
+	require.ErrorIs(t, rs.Next(data), ErrRewindNotSupported)
+}
+
+func TestResultSet_BatchHasNulls(t *testing.T) {
+	r := &results{
+		data: []any{
+			[]*cli_service.TColumn{
+				{I32Val: &cli_service.TI32Column{Nulls: []byte{0b10}, Values: []int32{1, 0, 3}}},
+				{StringVal: &cli_service.TStringColumn{Nulls: []byte{0}, Values: []string{"a", "b", "c"}}},
+			},
+			[]*cli_service.TColumn{
+				// bits beyond the batch length are ignored
+				{I32Val: &cli_service.TI32Column{Nulls: []byte{0b1000}, Values: []int32{1, 2, 3}}},
+				{StringVal: &cli_service.TStringColumn{Values: []string{"a", "", "c"}, Nulls: []byte{0b100}}},
+			},
+		},
+	}
+	rs := ResultSet{
+		fetchfn: r.fetch,
+		more:    true,
+		schema: &TableSchema{
+			Columns: []*ColDesc{
+				{DatabaseTypeName: "INT"},
+				{DatabaseTypeName: "STRING"},
+			},
+		},
+	}
+	require.Nil(t, rs.BatchHasNulls())
+	data := make([]driver.Value, 2)
+	for range 3 {
+		require.NoError(t, rs.Next(data))
+		require.Equal(t, []bool{true, false}, rs.BatchHasNulls())
+	}
+	require.NoError(t, rs.Next(data))
+	require.Equal(t, []bool{false, true}, rs.BatchHasNulls())
+}
+
+func TestHasNulls(t *testing.T) {
+	require.False(t, hasNulls(nil, 10))
+	require.False(t, hasNulls([]byte{0, 0}, 16))
+	require.True(t, hasNulls([]byte{0, 1}, 9))
+	require.False(t, hasNulls([]byte{0, 0b10}, 9))
+	require.True(t, hasNulls([]byte{0, 0b10}, 10))
+}
+
+func TestValue_Converter(t *testing.T) {
+	col := &cli_service.TColumn{
+		I64Val: &cli_service.TI64Column{
+			Nulls:  []byte{0b10},
+			Values: []int64{1500, 0},
+		},
+	}
+	converters := []ValueConverter{
+		{DatabaseTypeName: "string", Convert: func(v any) (any, error) { return nil, errors.New("unexpected") }},
+		{
+			Column:   "ELAPSED_MS",
+			Convert:  func(v any) (any, error) { return time.Duration(v.(int64)) * time.Millisecond, nil },
+			ScanType: reflect.TypeFor[time.Duration](),
+		},
+		{DatabaseTypeName: "BIGINT", Convert: func(v any) (any, error) { return nil, errors.New("not reached") }},
+	}
+
+	cd := &ColDesc{Name: "elapsed_ms", DatabaseTypeName: "BIGINT", ScanType: dataTypeInt64}
+	applyConverter(cd, converters)
+	require.Equal(t, reflect.TypeFor[time.Duration](), cd.ScanType)
+	val, err := value(col, cd, 0)
+	require.NoError(t, err)
+	require.Equal(t, 1500*time.Millisecond, val)
+	val, err = value(col, cd, 1)
+	require.NoError(t, err)
+	require.Nil(t, val, "NULL is not converted")
+
+	cd = &ColDesc{Name: "other", DatabaseTypeName: "BIGINT", ScanType: dataTypeInt64}
+	applyConverter(cd, converters)
+	require.Equal(t, dataTypeInt64, cd.ScanType)
+	_, err = value(col, cd, 0)
+	require.ErrorContains(t, err, "failed to convert value of column other: not reached")
+
+	cd = &ColDesc{Name: "other", DatabaseTypeName: "INT"}
+	applyConverter(cd, converters)
+	require.Nil(t, cd.convert)
+}
+
+func TestValue_ConvertRaw(t *testing.T) {
+	col := &cli_service.TColumn{
+		StringVal: &cli_service.TStringColumn{
+			Nulls:  []byte{0b10},
+			Values: []string{"1.25", "", "passthrough", "bad"},
+		},
+	}
+	converters := []ValueConverter{{
+		DatabaseTypeName: "DECIMAL",
+		ConvertRaw: func(raw any) (any, bool, error) {
+			s := raw.(string)
+			switch s {
+			case "passthrough":
+				return nil, false, nil
+			case "bad":
+				return nil, false, errors.New("bad decimal")
+			}
+			r, ok := new(big.Rat).SetString(s)
+			if !ok {
+				return nil, false, fmt.Errorf("invalid decimal %s", s)
+			}
+			return r, true, nil
+		},
+		Convert: func(v any) (any, error) { return "decoded " + v.(string), nil },
+	}}
+	cd := &ColDesc{Name: "d", DatabaseTypeName: "DECIMAL", ScanType: dataTypeString}
+	applyConverter(cd, converters)
+
+	val, err := value(col, cd, 0)
+	require.NoError(t, err)
+	require.Equal(t, big.NewRat(5, 4), val)
+
+	val, err = value(col, cd, 1)
+	require.NoError(t, err)
+	require.Nil(t, val, "NULL is not converted")
+
+	val, err = value(col, cd, 2)
+	require.NoError(t, err)
+	require.Equal(t, "decoded passthrough", val, "not handled values are decoded and passed to Convert")
+
+	_, err = value(col, cd, 3)
+	require.ErrorContains(t, err, "failed to convert value of column d: bad decimal")
+
+	_, err = value(col, cd, 4)
+	require.ErrorContains(t, err, "malformed result")
+
+	cd = &ColDesc{Name: "s", DatabaseTypeName: "STRING"}
+	applyConverter(cd, converters)
+	require.Nil(t, cd.convertRaw)
+}
+
+func TestValue_ComplexJSON(t *testing.T) {
+	col := &cli_service.TColumn{
+		StringVal: &cli_service.TStringColumn{
+			Nulls:  []byte{0},
+			Values: []string{`{"a": [1, 2], "b": null}`, `{"a":`},
+		},
+	}
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{"", `{"a": [1, 2], "b": null}`},
+		{ComplexJSONCompact, `{"a":[1,2],"b":null}`},
+		{ComplexJSONIndent, "{\n  \"a\": [\n    1,\n    2\n  ],\n  \"b\": null\n}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			cd := &ColDesc{Name: "s", DatabaseTypeName: "STRUCT", jsonFormat: tt.format}
+			val, err := value(col, cd, 0)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, val)
+		})
+	}
+
+	cd := &ColDesc{Name: "s", DatabaseTypeName: "MAP", jsonFormat: ComplexJSONCompact}
+	_, err := value(col, cd, 1)
+	require.ErrorContains(t, err, "column s of type MAP has invalid JSON value")
+
+	cd = &ColDesc{Name: "s", DatabaseTypeName: "STRING", jsonFormat: ComplexJSONCompact}
+	val, err := value(col, cd, 1)
+	require.NoError(t, err)
+	require.Equal(t, `{"a":`, val)
+}
@@ -2,6 +2,8 @@ package hive
 
 import (
 	"context"
+	"maps"
+	"strconv"
 
 	"github.com/sclgo/impala-go/internal/generated/cli_service"
 )
@@ -27,27 +29,44 @@ func (s *Session) Ping(ctx context.Context) error {
 		return err
 	}
 
-	s.hive.log.Printf("ping. server name: %s", resp.InfoValue.GetStringValue())
+	s.hive.log.Infof("ping. server name: %s", resp.InfoValue.GetStringValue())
 	return nil
 }
 
+// resultCacheSizeOption enables the result cache, which Impala requires for FETCH_FIRST.
+// See IMPALA_RESULT_CACHING_OPT in be/src/service/impala-hs2-server.cc in the Impala source.
+const resultCacheSizeOption = "impala.resultset.cache.size"
+
 // ExecuteStatement returns hive operation
-func (s *Session) ExecuteStatement(ctx context.Context, stmt string) (*Operation, error) {
+// queryOptions, if not empty, apply only to this statement, unlike SET, which changes the session.
+func (s *Session) ExecuteStatement(ctx context.Context, stmt string, queryOptions map[string]string) (*Operation, error) {
+	if s.hive.opts.ResultCacheSize > 0 {
+		queryOptions = maps.Clone(queryOptions)
+		if queryOptions == nil {
+			queryOptions = map[string]string{}
+		}
+		queryOptions[resultCacheSizeOption] = strconv.FormatInt(s.hive.opts.ResultCacheSize, 10)
+	}
 	req := cli_service.TExecuteStatementReq{
 		SessionHandle: s.h,
 		Statement:     stmt,
+		ConfOverlay:   queryOptions,
 	}
 	resp, err := s.hive.client.ExecuteStatement(ctx, &req)
-
-	if err != nil {
-		return nil, err
+	if err == nil {
+		err = s.checkStatus(resp)
 	}
-	if err = s.checkStatus(resp); err != nil {
+	if err != nil {
+		s.hive.emit(Event{Phase: PhaseExecute, Err: err})
 		return nil, err
 	}
-	s.hive.log.Printf("execute operation: %s; stmt: %s; status code: %s", guid(resp.OperationHandle.OperationId.GUID), stmt, resp.GetStatus().GetStatusCode())
-	s.hive.log.Printf("operation. has resultset: %v", resp.OperationHandle.GetHasResultSet())
-	s.hive.log.Printf("operation. modified row count: %f", resp.OperationHandle.GetModifiedRowCount())
+	opID := guid(resp.OperationHandle.OperationId.GUID)
+	s.hive.log.Infof("execute operation: %s; status code: %s", opID, resp.GetStatus().GetStatusCode())
+	s.hive.emit(Event{Phase: PhaseExecute, QueryID: opID})
+	// the statement may include literal values, which may be sensitive, like the results
+	s.hive.log.Debugf("operation %s stmt: %s", opID, stmt)
+	s.hive.log.Infof("operation. has resultset: %v", resp.OperationHandle.GetHasResultSet())
+	s.hive.log.Infof("operation. modified row count: %f", resp.OperationHandle.GetModifiedRowCount())
 	return &Operation{h: resp.OperationHandle, hive: s.hive}, nil
 }
 
@@ -58,7 +77,7 @@ func (s *Session) checkStatus(resp rpcResponse) error {
 	}
 	if resp.GetStatus().IsSetInfoMessages() {
 		for _, msg := range resp.GetStatus().GetInfoMessages() {
-			s.hive.log.Printf("info message: %s", msg)
+			s.hive.log.Infof("info message: %s", msg)
 		}
 	}
 	return nil
@@ -73,7 +92,7 @@ func (s *Session) DBMetadata() DBMetadata {
 
 // Close session
 func (s *Session) Close(ctx context.Context) error {
-	s.hive.log.Printf("close session: %v", guid(s.h.GetSessionId().GUID))
+	s.hive.log.Infof("close session: %v", guid(s.h.GetSessionId().GUID))
 	req := cli_service.TCloseSessionReq{
 		SessionHandle: s.h,
 	}
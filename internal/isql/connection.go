@@ -5,7 +5,8 @@ import (
 	"database/sql/driver"
 	"errors"
 	"fmt"
-	"log"
+	"io"
+	"sync"
 	"time"
 
 	"github.com/apache/thrift/lib/go/thrift"
@@ -15,19 +16,80 @@ import (
 var (
 	// ErrNotSupported means this operation is not supported by impala driver
 	ErrNotSupported = errors.New("impala: not supported")
+	// ErrConnBusy means a statement was started on a connection while another one was still in progress on it
+	ErrConnBusy = errors.New("impala: connection busy: another statement is in progress on this connection")
 )
 
 type Options struct {
 	ReuseSession bool
+
+	// CancelClient opens a separate connection to the same server, used to cancel operations
+	// while the primary connection is blocked in a request. Cancel is not supported if nil.
+	CancelClient func(ctx context.Context) (*hive.Client, io.Closer, error)
+
+	// AcceptsContext reports if the connection may serve a request with the given context,
+	// e.g. because the context carries the same credentials as the connection. If it returns false,
+	// ResetSession and statements report driver.ErrBadConn so database/sql opens a new connection instead.
+	// All contexts are accepted if nil.
+	AcceptsContext func(ctx context.Context) bool
+
+	// AuthMechanism is the SASL mechanism negotiated when the connection was opened, reported by Conn.AuthMechanism
+	AuthMechanism string
+
+	// ServerAddr is the address of the server, which accepted the connection, reported by Conn.ServerAddr
+	ServerAddr string
+
+	// InterpretFirstEOF, if not nil, interprets the error when the server closes the connection during
+	// the first request on it, like sasl.Client.InterpretReceiveEOF does during SASL negotiation.
+	// This allows explaining a mismatch in the authentication mode instead of reporting a bare EOF.
+	InterpretFirstEOF func(transportError error) error
+
+	// OnSession, if not nil, is called each time a session is opened, before it is used by the statement that
+	// required it. It may run statements on the connection. If it fails, the session is closed and the statement
+	// fails with the error as is, without driver.ErrBadConn, so database/sql doesn't retry a deterministic failure
+	// on other connections. OnSession is called again when the connection opens the next session.
+	OnSession func(ctx context.Context, conn *Conn) error
+
+	// BytesRead, if not nil, returns the total number of bytes read from the server on the connection so far.
+	// Conn uses it to report the bytes read by each statement.
+	BytesRead func() int64
+
+	// ReadOnly rejects statements, which may modify data or metadata, with ErrReadOnly before they are sent
+	ReadOnly bool
+
+	// StatementLog enables getting the operation log of each statement before it is closed, for Conn.LastLog.
+	// This costs a GetLog request per statement.
+	StatementLog bool
 }
 
-// Conn to impala. It should not be used concurrently by multiple goroutines.
+// Conn to impala. It should not be used concurrently by multiple goroutines, except for Cancel.
 type Conn struct {
 	transport thrift.TTransport // we use two methods: Close and IsOpen atm, make a dedicated iface if needed
 	session   *hive.Session
 	client    *hive.Client
-	log       *log.Logger
+	log       *hive.Logger
 	opts      Options
+
+	// sessionOpened is set once a session was opened successfully on this connection
+	sessionOpened bool
+	// lastLog is the operation log of the last statement, if Options.StatementLog is enabled
+	lastLog string
+	// lastChecksum is the result checksum of the last statement, whose rows were closed; see LastResultChecksum
+	lastChecksum    uint64
+	hasLastChecksum bool
+
+	// openRows are the rows of the last query, if they are not closed yet. Unlike the fields guarded by mu,
+	// it is accessed only by Conn and Rows methods, which database/sql doesn't call concurrently for a connection,
+	// so it doesn't need locking. Cancel must not use it.
+	openRows *Rows
+
+	mu      sync.Mutex // guards the fields below
+	busy    bool
+	running *hive.Operation
+	// startBytes is the value of Options.BytesRead when the current statement started
+	startBytes int64
+	// lastBytes is the number of bytes read by the last statement that completed
+	lastBytes int64
 }
 
 // This declaration lists and verifies driver interfaces implemented by *Conn
@@ -53,6 +115,19 @@ func (c *Conn) Ping(ctx context.Context) error {
 	return mapErr(session.Ping(ctx))
 }
 
+// PingWithLatency pings the server like Ping and returns the duration of the round-trip.
+// Opening the session, if needed, is not included in the duration.
+func (c *Conn) PingWithLatency(ctx context.Context) (time.Duration, error) {
+	session, err := c.OpenSession(ctx) // also validates transport; err has driver.ErrBadConn in chain
+	if err != nil {
+		return 0, err
+	}
+
+	start := time.Now()
+	err = session.Ping(ctx)
+	return time.Since(start), mapErr(err)
+}
+
 // isTransportOpen checks if the underlying connection is open without doing a roundtrip
 // and without blocking on IO. It can be used in cases where Ping will be too slow or unnecessary.
 func (c *Conn) isTransportOpen() bool {
@@ -68,12 +143,25 @@ func (c *Conn) isTransportOpen() bool {
 // validation and conversion as appropriate for the driver.
 // Implements driver.NamedValueChecker
 func (c *Conn) CheckNamedValue(val *driver.NamedValue) error {
-	t, ok := val.Value.(time.Time)
-	if ok {
+	if typed, ok := val.Value.(TypedValue); ok {
+		lit, err := typed.literal()
+		val.Value = lit
+		return err
+	}
+	// The default converter calls driver.Valuer so values of custom types are formatted below, like other values
+	v, err := driver.DefaultParameterConverter.ConvertValue(val.Value)
+	if err != nil {
+		return err
+	}
+	switch t := v.(type) {
+	case time.Time:
 		val.Value = t.Format(hive.TimestampFormat)
-		return nil
+	case []byte:
+		val.Value = string(t)
+	default:
+		val.Value = v
 	}
-	return driver.ErrSkip
+	return nil
 }
 
 // Prepare returns prepared statement
@@ -86,14 +174,48 @@ func (c *Conn) Prepare(query string) (driver.Stmt, error) {
 // Implements driver.ConnPrepareContext
 func (c *Conn) PrepareContext(_ context.Context, query string) (driver.Stmt, error) {
 	return &Stmt{
-		conn: c,
-		stmt: query,
+		conn:   c,
+		stmt:   query,
+		parsed: parsePlaceholders(template(query)),
 	}, nil
 }
 
 // QueryContext executes a query that may return rows
 // Implements driver.QueryerContext
 func (c *Conn) QueryContext(ctx context.Context, q string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.queryParsed(ctx, parsePlaceholders(template(q)), args)
+}
+
+func (c *Conn) queryParsed(ctx context.Context, ps *parsedStatement, args []driver.NamedValue) (driver.Rows, error) {
+	session, err := c.OpenSession(ctx) // also validates transport; err has driver.ErrBadConn in chain
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := ps.bind(args)
+	rows, err := c.query(ctx, session, stmt, nil)
+	return rows, mapErr(err)
+}
+
+// Outcome is the outcome of a statement executed with RunContext. Exactly one of Rows and Result is set.
+type Outcome struct {
+	// Rows is set if the statement has a result set. Rows must be closed.
+	Rows driver.Rows
+	// Result is set if the statement has no result set. The statement has completed.
+	Result driver.Result
+}
+
+// RunContext executes a statement that may or may not return rows. If the statement has a result set,
+// RunContext returns rows like QueryContext. Otherwise, it waits for the statement to complete like ExecContext.
+// Note that Impala reports a result set, containing a summary, for most DDL statements.
+// Unlike QueryContext, RunContext converts args itself since it is not called by database/sql.
+func (c *Conn) RunContext(ctx context.Context, q string, args []driver.NamedValue) (*Outcome, error) {
+	for i := range args {
+		if err := c.CheckNamedValue(&args[i]); err != nil {
+			return nil, err
+		}
+	}
+
 	session, err := c.OpenSession(ctx) // also validates transport; err has driver.ErrBadConn in chain
 	if err != nil {
 		return nil, err
@@ -101,24 +223,181 @@ func (c *Conn) QueryContext(ctx context.Context, q string, args []driver.NamedVa
 
 	tmpl := template(q)
 	stmt := statement(tmpl, args)
-	rows, err := query(ctx, session, stmt)
+	res, err := c.run(ctx, session, stmt)
+	return res, mapErr(err)
+}
+
+// QueryWithOptions executes a query that may return rows, like QueryContext without arguments.
+// The given query options apply only to this statement, unlike SET, which changes them for the session.
+func (c *Conn) QueryWithOptions(ctx context.Context, q string, queryOptions map[string]string) (driver.Rows, error) {
+	session, err := c.OpenSession(ctx) // also validates transport; err has driver.ErrBadConn in chain
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := c.query(ctx, session, q, queryOptions)
 	return rows, mapErr(err)
 }
 
+// QuerySchema starts a query, returns the columns of its result without fetching any rows, and closes it.
+// Closing the query right away stops the server from computing more of the result.
+func (c *Conn) QuerySchema(ctx context.Context, q string) ([]*hive.ColDesc, error) {
+	session, err := c.OpenSession(ctx) // also validates transport; err has driver.ErrBadConn in chain
+	if err != nil {
+		return nil, err
+	}
+
+	operation, err := c.start(ctx, session, q, nil)
+	if err != nil {
+		return nil, mapErr(err)
+	}
+	defer c.endOp()
+	schema, err := operation.GetResultSetMetadata(ctx)
+	_, closeErr := operation.Close(ctx)
+	if err != nil {
+		return nil, mapErr(err)
+	}
+	if closeErr != nil {
+		return nil, mapErr(closeErr)
+	}
+	return schema.Columns, nil
+}
+
 // ExecContext executes a query that doesn't return rows
 // Implements driver.ExecerContext
 func (c *Conn) ExecContext(ctx context.Context, q string, args []driver.NamedValue) (driver.Result, error) {
+	return c.execParsed(ctx, parsePlaceholders(template(q)), args)
+}
+
+func (c *Conn) execParsed(ctx context.Context, ps *parsedStatement, args []driver.NamedValue) (driver.Result, error) {
 	session, err := c.OpenSession(ctx) // also validates transport; err has driver.ErrBadConn in chain
 	if err != nil {
 		return nil, err
 	}
 
-	tmpl := template(q)
-	stmt := statement(tmpl, args)
-	res, err := exec(ctx, session, stmt)
+	stmt := ps.bind(args)
+	res, err := c.exec(ctx, session, stmt)
 	return res, mapErr(err)
 }
 
+// OpenOperation returns the operation identified by a handle from Rows.Detach.
+// It fails with hive.ErrOperationNotFound if the server doesn't have the operation anymore.
+func (c *Conn) OpenOperation(ctx context.Context, handle string) (*hive.Operation, error) {
+	if _, err := c.OpenSession(ctx); err != nil { // validates transport; err has driver.ErrBadConn in chain
+		return nil, err
+	}
+	operation, err := c.client.AttachOperation(handle)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.startOp(); err != nil {
+		return nil, err
+	}
+	defer c.endOp()
+	return operation, mapErr(operation.CheckAttached(ctx))
+}
+
+// OperationRows returns rows, which continue fetching the results of an operation from OpenOperation
+func (c *Conn) OperationRows(ctx context.Context, operation *hive.Operation) (driver.Rows, error) {
+	if err := c.startOp(); err != nil {
+		return nil, err
+	}
+	c.setRunning(operation)
+	rows, err := c.rows(ctx, operation)
+	return rows, mapErr(err)
+}
+
+// CloseOperation closes an operation from OpenOperation without fetching its results
+func (c *Conn) CloseOperation(ctx context.Context, operation *hive.Operation) error {
+	if err := c.startOp(); err != nil {
+		return err
+	}
+	defer c.endOp()
+	_, err := operation.Close(ctx)
+	return mapErr(err)
+}
+
+// AttachOperation returns rows, which continue fetching the results of the operation identified by a handle
+// from Rows.Detach. It fails with hive.ErrOperationNotFound if the server doesn't have the operation anymore.
+func (c *Conn) AttachOperation(ctx context.Context, handle string) (driver.Rows, error) {
+	operation, err := c.OpenOperation(ctx, handle)
+	if err != nil {
+		return nil, err
+	}
+	return c.OperationRows(ctx, operation)
+}
+
+// Cancel cancels the operation currently running on the connection, if any.
+// Unlike other methods, Cancel can be called concurrently with a running query.
+// The cancel request is sent over a separate connection since the Thrift protocol
+// doesn't allow a second request on a connection that is waiting for a response.
+func (c *Conn) Cancel(ctx context.Context) error {
+	c.mu.Lock()
+	op := c.running
+	c.mu.Unlock()
+	if op == nil {
+		return nil
+	}
+	if c.opts.CancelClient == nil {
+		return ErrNotSupported
+	}
+
+	client, closer, err := c.opts.CancelClient(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open connection for cancel: %w", err)
+	}
+	defer func() {
+		_ = closer.Close()
+	}()
+	return mapErr(client.CancelOperation(ctx, op))
+}
+
+// startOp marks the connection busy until endOp is called.
+// Concurrent requests would corrupt the Thrift protocol stream so they fail with ErrConnBusy instead.
+func (c *Conn) startOp() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.busy {
+		return ErrConnBusy
+	}
+	c.busy = true
+	c.startBytes = c.bytesRead()
+	return nil
+}
+
+func (c *Conn) setRunning(op *hive.Operation) {
+	c.mu.Lock()
+	c.running = op
+	c.mu.Unlock()
+}
+
+func (c *Conn) endOp() {
+	c.mu.Lock()
+	c.busy = false
+	c.running = nil
+	c.lastBytes = c.bytesRead() - c.startBytes
+	c.mu.Unlock()
+}
+
+func (c *Conn) bytesRead() int64 {
+	if c.opts.BytesRead == nil {
+		return 0
+	}
+	return c.opts.BytesRead()
+}
+
+// LastBytesRead returns the number of bytes read from the server by the last statement, e.g. to account
+// for network egress. If a statement is still in progress, e.g. its rows are open, it returns the bytes read
+// by that statement so far. The count includes the Thrift protocol overhead but not SASL, TLS or HTTP framing.
+func (c *Conn) LastBytesRead() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.busy {
+		return c.bytesRead() - c.startBytes
+	}
+	return c.lastBytes
+}
+
 // Begin is not supported
 // Implements driver.Conn
 func (c *Conn) Begin() (driver.Tx, error) {
@@ -126,16 +405,33 @@ func (c *Conn) Begin() (driver.Tx, error) {
 }
 
 // OpenSession ensures opened session and live transport connection
-// Any returned errors have driver.ErrBadConn in the chain
+// Any returned errors have driver.ErrBadConn in the chain, except for failures of Options.OnSession
 func (c *Conn) OpenSession(ctx context.Context) (*hive.Session, error) {
 	if c.session == nil {
 		session, err := c.client.OpenSession(ctx)
 		if err != nil {
-			err = fmt.Errorf("%w: failed to open session: %v", driver.ErrBadConn, err)
-			c.log.Println(err)
+			if !c.sessionOpened && c.opts.InterpretFirstEOF != nil && isClosedByServer(err) {
+				err = fmt.Errorf("%w: failed to open session: %w", driver.ErrBadConn, c.opts.InterpretFirstEOF(err))
+			} else if errors.Is(err, hive.ErrSessionLimit) {
+				err = fmt.Errorf("%w: failed to open session: %w", driver.ErrBadConn, err)
+			} else {
+				err = fmt.Errorf("%w: failed to open session: %v", driver.ErrBadConn, err)
+			}
+			c.log.Errorf("%v", err)
 			return nil, err
 		}
 		c.session = session
+		c.sessionOpened = true
+		if c.opts.OnSession != nil {
+			if err = c.opts.OnSession(ctx, c); err != nil {
+				err = fmt.Errorf("connection setup failed: %w", err)
+				c.log.Errorf("%v", err)
+				// the session may be partially set up so it must not be used, even if the connection is
+				_ = session.Close(ctx)
+				c.session = nil
+				return nil, err
+			}
+		}
 	} else {
 		// since we are just about to reuse the existing session, quickly check if the transport is still open,
 		// so we can return an error that database/sql/DB.retry can handle
@@ -150,6 +446,10 @@ func (c *Conn) OpenSession(ctx context.Context) (*hive.Session, error) {
 // ResetSession closes hive session
 // Implements driver.SessionResetter
 func (c *Conn) ResetSession(ctx context.Context) (err error) {
+	if c.opts.AcceptsContext != nil && !c.opts.AcceptsContext(ctx) {
+		return fmt.Errorf("%w: connection can't be reused with the given context", driver.ErrBadConn)
+	}
+
 	if c.session != nil && !c.opts.ReuseSession {
 		err = mapErr(c.session.Close(ctx))
 		if err == nil {
@@ -175,7 +475,10 @@ func (c *Conn) ResetSession(ctx context.Context) (err error) {
 // Close connection
 // Implements driver.Conn
 func (c *Conn) Close() error {
-	c.log.Printf("close connection")
+	c.log.Infof("close connection")
+	if c.openRows != nil {
+		c.closeOpenRows()
+	}
 	if c.session != nil {
 		err := c.session.Close(context.Background())
 		if err != nil {
@@ -189,6 +492,47 @@ func (c *Conn) Close() error {
 	return nil
 }
 
+// closeOpenRows cancels and closes the operation of rows, which the application didn't close before closing
+// the connection, so the query doesn't keep running on the server until the session is closed or it times out.
+// Detached operations are left open. Failures are only logged since the connection is closed anyway.
+func (c *Conn) closeOpenRows() {
+	rows := c.openRows
+	if !rows.detached {
+		c.log.Infof("cancel operation, whose rows were not closed before closing the connection")
+		if err := rows.op.Cancel(context.Background()); err != nil {
+			c.log.Errorf("failed to cancel operation while closing connection: %v", err)
+		}
+	}
+	if err := rows.Close(); err != nil {
+		c.log.Errorf("failed to close operation while closing connection: %v", err)
+	}
+}
+
+// AuthMechanism returns the SASL mechanism negotiated when the connection was opened
+func (c *Conn) AuthMechanism() string {
+	return c.opts.AuthMechanism
+}
+
+// ServerAddr returns the address of the server, which accepted the connection when it was opened
+func (c *Conn) ServerAddr() string {
+	return c.opts.ServerAddr
+}
+
+// LastResultChecksum returns the checksum of the values returned by the rows of the last statement, once they
+// are closed. ok is false if the checksum is not enabled, the last statement had no result set, or its rows
+// are still open. If the rows were closed before all rows were read, the checksum covers only the rows read.
+func (c *Conn) LastResultChecksum() (sum uint64, ok bool) {
+	return c.lastChecksum, c.hasLastChecksum
+}
+
+// LastLog returns the operation log, which Impala reported for the last statement, e.g. warnings about missing
+// statistics. The log of a statement with a result set is available after its rows are closed.
+// The log is cleared when the next statement starts. It is truncated to maxLogSize bytes.
+// ok is false if Options.StatementLog is not enabled.
+func (c *Conn) LastLog() (log string, ok bool) {
+	return c.lastLog, c.opts.StatementLog
+}
+
 // IsValid checks that the connection is valid for use in database/sql
 // Implements driver.Validator
 // database/sql calls this before the connection is returned to the pool, after it has just been used.
@@ -199,7 +543,7 @@ func (c *Conn) IsValid() bool {
 	return c.isTransportOpen()
 }
 
-func NewConn(client *hive.Client, transport thrift.TTransport, logger *log.Logger, opts Options) *Conn {
+func NewConn(client *hive.Client, transport thrift.TTransport, logger *hive.Logger, opts Options) *Conn {
 	return &Conn{
 		transport: transport,
 		client:    client,
@@ -0,0 +1,341 @@
+package isql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	"github.com/sclgo/impala-go/internal/generated/cli_service"
+	"github.com/sclgo/impala-go/internal/generated/impalaservice"
+	"github.com/sclgo/impala-go/internal/hive"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConn_Busy(t *testing.T) {
+	db := sql.OpenDB(fakeConnector{})
+	defer func() {
+		require.NoError(t, db.Close())
+	}()
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, conn.Close())
+	}()
+
+	rows, err := conn.QueryContext(ctx, "SELECT 1")
+	require.NoError(t, err)
+
+	errCh := make(chan error)
+	go func() {
+		_, err := conn.QueryContext(ctx, "SELECT 2")
+		errCh <- err
+	}()
+	require.ErrorIs(t, <-errCh, ErrConnBusy)
+
+	_, err = conn.ExecContext(ctx, "INSERT INTO t VALUES (1)")
+	require.ErrorIs(t, err, ErrConnBusy)
+
+	require.NoError(t, rows.Close())
+	rows, err = conn.QueryContext(ctx, "SELECT 3")
+	require.NoError(t, err)
+	require.NoError(t, rows.Close())
+}
+
+func TestConn_DirectQuery(t *testing.T) {
+	// database/sql uses QueryerContext and ExecerContext, when implemented, instead of
+	// preparing a statement and then executing it
+	var calls []string
+	db := sql.OpenDB(fakeConnector{calls: &calls})
+	defer func() {
+		require.NoError(t, db.Close())
+	}()
+	ctx := context.Background()
+
+	rows, err := db.QueryContext(ctx, "SELECT ?", 1)
+	require.NoError(t, err)
+	require.NoError(t, rows.Close())
+	require.Equal(t, []string{"OpenSession", "PingImpalaHS2Service", "ExecuteStatement", "GetResultSetMetadata", "CloseImpalaOperation"}, calls)
+
+	calls = nil
+	_, err = db.ExecContext(ctx, "INSERT INTO t VALUES (?)", 1)
+	require.NoError(t, err)
+	require.Equal(t, 1, lo.Count(calls, "ExecuteStatement"))
+}
+
+func TestConn_RunContext(t *testing.T) {
+	conn, err := fakeConnector{}.Connect(context.Background())
+	require.NoError(t, err)
+	impalaConn := conn.(*Conn)
+
+	outcome, err := impalaConn.RunContext(context.Background(), "SELECT ?", []driver.NamedValue{{Ordinal: 1, Value: 1}})
+	require.NoError(t, err)
+	require.NotNil(t, outcome.Rows)
+	require.Nil(t, outcome.Result)
+	require.NoError(t, outcome.Rows.Close())
+
+	outcome, err = impalaConn.RunContext(context.Background(), "INSERT INTO t VALUES (1)", nil)
+	require.NoError(t, err)
+	require.Nil(t, outcome.Rows)
+	require.NotNil(t, outcome.Result)
+
+	// the connection is idle after both statements
+	_, err = impalaConn.ExecContext(context.Background(), "INSERT INTO t VALUES (1)", nil)
+	require.NoError(t, err)
+}
+
+func TestConn_PingWithLatency(t *testing.T) {
+	conn, err := fakeConnector{}.Connect(context.Background())
+	require.NoError(t, err)
+	latency, err := conn.(*Conn).PingWithLatency(context.Background())
+	require.NoError(t, err)
+	require.Positive(t, latency)
+}
+
+type timeValuer struct {
+	t time.Time
+}
+
+func (v timeValuer) Value() (driver.Value, error) {
+	return v.t, nil
+}
+
+type bytesValuer string
+
+func (v bytesValuer) Value() (driver.Value, error) {
+	return []byte(v), nil
+}
+
+func TestConn_CheckNamedValue(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name     string
+		value    any
+		expected any
+	}{
+		{"time", ts, "2024-01-02 03:04:05"},
+		{"valuer returning time", timeValuer{ts}, "2024-01-02 03:04:05"},
+		{"valuer returning bytes", bytesValuer("abc"), "abc"},
+		{"int kind", int8(1), int64(1)},
+		{"string", "abc", "abc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			val := driver.NamedValue{Ordinal: 1, Value: tt.value}
+			require.NoError(t, (&Conn{}).CheckNamedValue(&val))
+			require.Equal(t, tt.expected, val.Value)
+		})
+	}
+
+	t.Run("unsupported", func(t *testing.T) {
+		val := driver.NamedValue{Ordinal: 1, Value: struct{}{}}
+		require.Error(t, (&Conn{}).CheckNamedValue(&val))
+	})
+}
+
+func TestConn_AcceptsContext(t *testing.T) {
+	type userKey struct{}
+	var calls []string
+	connects := 0
+	db := sql.OpenDB(fakeConnector{
+		calls: &calls,
+		acceptsContext: func(connectCtx context.Context) func(ctx context.Context) bool {
+			connects++
+			return func(ctx context.Context) bool {
+				return ctx.Value(userKey{}) == connectCtx.Value(userKey{})
+			}
+		},
+	})
+	defer func() { _ = db.Close() }()
+	db.SetMaxOpenConns(1)
+
+	fry := context.WithValue(context.Background(), userKey{}, "fry")
+	leela := context.WithValue(context.Background(), userKey{}, "leela")
+
+	_, err := db.ExecContext(fry, "INSERT INTO t VALUES (1)")
+	require.NoError(t, err)
+	_, err = db.ExecContext(fry, "INSERT INTO t VALUES (1)")
+	require.NoError(t, err)
+	require.Equal(t, 1, connects, "the pooled connection should be reused for the same user")
+
+	_, err = db.ExecContext(leela, "INSERT INTO t VALUES (1)")
+	require.NoError(t, err)
+	require.Equal(t, 2, connects, "a new connection should be opened for another user")
+
+	conn, err := db.Conn(leela)
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+	_, err = conn.ExecContext(fry, "INSERT INTO t VALUES (1)")
+	require.ErrorIs(t, err, driver.ErrBadConn)
+}
+
+func TestConn_LastLog(t *testing.T) {
+	lastLog := func(conn *Conn) string {
+		opLog, ok := conn.LastLog()
+		require.True(t, ok)
+		return opLog
+	}
+	conn, err := fakeConnector{log: "WARNINGS: Table t has no statistics\n"}.Connect(context.Background())
+	require.NoError(t, err)
+	impalaConn := conn.(*Conn)
+	require.Empty(t, lastLog(impalaConn))
+
+	_, err = impalaConn.ExecContext(context.Background(), "INSERT INTO t VALUES (1)", nil)
+	require.NoError(t, err)
+	require.Equal(t, "WARNINGS: Table t has no statistics\n", lastLog(impalaConn))
+
+	rows, err := impalaConn.QueryContext(context.Background(), "SELECT 1", nil)
+	require.NoError(t, err)
+	require.Empty(t, lastLog(impalaConn), "cleared by the next statement")
+	require.NoError(t, rows.Close())
+	require.Equal(t, "WARNINGS: Table t has no statistics\n", lastLog(impalaConn), "set when the rows are closed")
+
+	conn, err = fakeConnector{log: strings.Repeat("é", maxLogSize)}.Connect(context.Background())
+	require.NoError(t, err)
+	impalaConn = conn.(*Conn)
+	_, err = impalaConn.ExecContext(context.Background(), "INSERT INTO t VALUES (1)", nil)
+	require.NoError(t, err)
+	require.Len(t, lastLog(impalaConn), maxLogSize)
+
+	t.Run("disabled", func(t *testing.T) {
+		var calls []string
+		conn, err := fakeConnector{calls: &calls}.Connect(context.Background())
+		require.NoError(t, err)
+		_, err = conn.(*Conn).ExecContext(context.Background(), "INSERT INTO t VALUES (1)", nil)
+		require.NoError(t, err)
+		require.NotContains(t, calls, "GetLog")
+		_, ok := conn.(*Conn).LastLog()
+		require.False(t, ok)
+	})
+}
+
+var discardLogger = hive.NewLogger(log.New(io.Discard, "", 0), "")
+
+func TestConn_Events(t *testing.T) {
+	var events []hive.Event
+	conn, err := fakeConnector{onEvent: func(e hive.Event) {
+		events = append(events, e)
+	}}.Connect(context.Background())
+	require.NoError(t, err)
+	_, err = conn.(*Conn).ExecContext(context.Background(), "INSERT INTO t VALUES (1)", nil)
+	require.NoError(t, err)
+
+	phases := lo.Map(events, func(e hive.Event, _ int) string { return e.Phase })
+	require.Equal(t, []string{hive.PhaseOpen, hive.PhaseExecute, hive.PhaseExecute, hive.PhaseClose}, phases)
+	require.Empty(t, events[0].QueryID)
+	require.NotEmpty(t, events[1].QueryID)
+	require.Equal(t, events[1].QueryID, events[3].QueryID)
+	require.Equal(t, hive.QueryStateFinished, events[2].State)
+	for _, e := range events {
+		require.NoError(t, e.Err)
+	}
+}
+
+func TestConn_CloseWithOpenRows(t *testing.T) {
+	ctx := context.Background()
+	query := func(t *testing.T, calls *[]string) (*Conn, *Rows) {
+		conn, err := fakeConnector{calls: calls}.Connect(ctx)
+		require.NoError(t, err)
+		rows, err := conn.(*Conn).QueryContext(ctx, "SELECT * FROM large", nil)
+		require.NoError(t, err)
+		return conn.(*Conn), rows.(*Rows)
+	}
+
+	t.Run("not drained", func(t *testing.T) {
+		var calls []string
+		conn, _ := query(t, &calls)
+		calls = nil
+		require.NoError(t, conn.Close())
+		require.Equal(t, []string{"CancelOperation", "CloseImpalaOperation", "CloseSession"}, calls)
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		var calls []string
+		conn, rows := query(t, &calls)
+		require.NoError(t, rows.Close())
+		calls = nil
+		require.NoError(t, conn.Close())
+		require.Equal(t, []string{"CloseSession"}, calls)
+	})
+
+	t.Run("detached", func(t *testing.T) {
+		var calls []string
+		conn, rows := query(t, &calls)
+		rows.Detach()
+		calls = nil
+		require.NoError(t, conn.Close())
+		require.Equal(t, []string{"CloseSession"}, calls, "the detached operation is left open")
+	})
+}
+
+type fakeConnector struct {
+	calls *[]string // if not nil, records the names of the Thrift methods called
+	// acceptsContext, if not nil, builds Options.AcceptsContext from the context passed to Connect
+	acceptsContext func(connectCtx context.Context) func(ctx context.Context) bool
+	log            string // the operation log returned by GetLog; Options.StatementLog is enabled if set
+	onEvent        func(hive.Event)
+}
+
+func (c fakeConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	client := hive.NewClient(fakeTClient{calls: c.calls, log: c.log}, discardLogger, &hive.Options{OnEvent: c.onEvent})
+	opts := Options{StatementLog: c.log != ""}
+	if c.acceptsContext != nil {
+		opts.AcceptsContext = c.acceptsContext(ctx)
+	}
+	return NewConn(client, thrift.NewTMemoryBuffer(), discardLogger, opts), nil
+}
+
+func (fakeConnector) Driver() driver.Driver {
+	return nil
+}
+
+// fakeTClient responds successfully to the Thrift calls needed to run a statement that returns no rows
+type fakeTClient struct {
+	calls *[]string
+	log   string // the operation log returned by GetLog
+}
+
+func (c fakeTClient) Call(_ context.Context, method string, args, result thrift.TStruct) (thrift.ResponseMeta, error) {
+	if c.calls != nil {
+		*c.calls = append(*c.calls, method)
+	}
+	status := &cli_service.TStatus{StatusCode: cli_service.TStatusCode_SUCCESS_STATUS}
+	id := uuid.New()
+	handle := &cli_service.THandleIdentifier{GUID: id[:], Secret: id[:]}
+	switch res := result.(type) {
+	case *cli_service.TCLIServiceOpenSessionResult:
+		res.Success = &cli_service.TOpenSessionResp{Status: status, SessionHandle: &cli_service.TSessionHandle{SessionId: handle}}
+	case *cli_service.TCLIServiceCloseSessionResult:
+		res.Success = &cli_service.TCloseSessionResp{Status: status}
+	case *cli_service.TCLIServiceGetInfoResult:
+		res.Success = &cli_service.TGetInfoResp{Status: status, InfoValue: &cli_service.TGetInfoValue{StringValue: lo.ToPtr("Impala")}}
+	case *cli_service.TCLIServiceExecuteStatementResult:
+		// statements starting with SELECT have a result set, like in Impala
+		hasResultSet := strings.HasPrefix(args.(*cli_service.TCLIServiceExecuteStatementArgs).Req.Statement, "SELECT")
+		res.Success = &cli_service.TExecuteStatementResp{Status: status, OperationHandle: &cli_service.TOperationHandle{OperationId: handle, HasResultSet: hasResultSet}}
+	case *cli_service.TCLIServiceGetOperationStatusResult:
+		res.Success = &cli_service.TGetOperationStatusResp{Status: status, OperationState: cli_service.TOperationStatePtr(cli_service.TOperationState_FINISHED_STATE)}
+	case *cli_service.TCLIServiceGetResultSetMetadataResult:
+		res.Success = &cli_service.TGetResultSetMetadataResp{Status: status}
+	case *cli_service.TCLIServiceGetLogResult:
+		res.Success = &cli_service.TGetLogResp{Status: status, Log: c.log}
+	case *impalaservice.ImpalaHiveServer2ServicePingImpalaHS2ServiceResult:
+		res.Success = &impalaservice.TPingImpalaHS2ServiceResp{Status: status}
+	case *cli_service.TCLIServiceCancelOperationResult:
+		res.Success = &cli_service.TCancelOperationResp{Status: status}
+	case *impalaservice.ImpalaHiveServer2ServiceCloseImpalaOperationResult:
+		res.Success = &impalaservice.TCloseImpalaOperationResp{Status: status}
+	default:
+		return thrift.ResponseMeta{}, fmt.Errorf("unexpected call: %s", method)
+	}
+	return thrift.ResponseMeta{}, nil
+}
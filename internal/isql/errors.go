@@ -11,6 +11,16 @@ import (
 	"github.com/sclgo/impala-go/internal/hive"
 )
 
+// sessionGoneMessages are the parts of the errors, which Impala and Hive report when the session of the request no
+// longer exists on the server, e.g. because it expired or the coordinator restarted while the TCP connection stayed
+// half-open. The connection can't be used anymore, but a new connection opens a new session.
+var sessionGoneMessages = []string{
+	"client session expired",
+	"invalid session id",
+	"invalid sessionhandle",
+	"session closed",
+}
+
 func mapErr(err error) error {
 	if err == nil {
 		return nil
@@ -26,7 +36,8 @@ func mapErr(err error) error {
 	var hiveStatusErr *hive.StatusError
 	if errors.As(err, &hiveStatusErr) {
 		// StatusCode, SqlState, and ErrorCode are not informative. SqlState = HY000 means "general error"
-		if strings.Contains(lo.FromPtr(hiveStatusErr.Status().ErrorMessage), "Client session expired") {
+		msg := strings.ToLower(lo.FromPtr(hiveStatusErr.Status().ErrorMessage))
+		if lo.SomeBy(sessionGoneMessages, func(m string) bool { return strings.Contains(msg, m) }) {
 			return wrapBadConn(err)
 		}
 	}
@@ -38,6 +49,15 @@ func mapErr(err error) error {
 	return fmt.Errorf("impala: %w", err)
 }
 
+// isClosedByServer reports if err means that the server closed the connection
+func isClosedByServer(err error) bool {
+	var tErr thrift.TTransportException
+	if errors.As(err, &tErr) && tErr.TypeId() == thrift.END_OF_FILE {
+		return true
+	}
+	return isOSBadConn(err)
+}
+
 func wrapBadConn(err error) error {
 	// the input error is intentionally not wrapped to avoid exposing internals
 	// guideline: https://go.dev/blog/go1.13-errors
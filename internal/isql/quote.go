@@ -0,0 +1,22 @@
+package isql
+
+import "strings"
+
+// identifierEscaper escapes the characters, which would end a quoted identifier early
+var identifierEscaper = strings.NewReplacer(`\`, `\\`, "`", "\\`")
+
+// stringEscaper escapes the characters, which would end a string literal early, like the Hive JDBC driver
+var stringEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+// QuoteIdentifier encloses name in backticks, escaping backslashes and backticks in it.
+// Impala doesn't unescape quoted identifiers so names with these characters don't match any existing object,
+// but the result is always a single identifier, which can't alter the surrounding statement.
+func QuoteIdentifier(name string) string {
+	return "`" + identifierEscaper.Replace(name) + "`"
+}
+
+// QuoteString encloses value in single quotes, escaping backslashes and single quotes in it
+// https://impala.apache.org/docs/build/html/topics/impala_literals.html#string_literals
+func QuoteString(value string) string {
+	return "'" + stringEscaper.Replace(value) + "'"
+}
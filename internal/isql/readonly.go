@@ -0,0 +1,116 @@
+package isql
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ErrReadOnly means that a statement was rejected because the connection is read-only
+var ErrReadOnly = errors.New("impala: read-only connection: only queries are allowed")
+
+// readOnlyKeywords are the leading keywords of statements that don't modify data or metadata.
+// USE and SET change only the session.
+var readOnlyKeywords = map[string]bool{
+	"SELECT":   true,
+	"VALUES":   true,
+	"WITH":     true,
+	"SHOW":     true,
+	"DESCRIBE": true,
+	"DESC":     true,
+	"EXPLAIN":  true,
+	"USE":      true,
+	"SET":      true,
+}
+
+// checkReadOnly returns ErrReadOnly unless stmt starts with a read-only keyword, ignoring comments and
+// opening parentheses. Impala allows a WITH clause before INSERT and UPSERT, so WITH statements must not contain
+// these keywords. The check is a best-effort guardrail against mistakes and is not a security boundary.
+func checkReadOnly(stmt string) error {
+	words := keywords(stmt)
+	if len(words) == 0 {
+		return fmt.Errorf("%w: the statement has no keywords", ErrReadOnly)
+	}
+	if !readOnlyKeywords[words[0]] {
+		return fmt.Errorf("%w: %s statement rejected", ErrReadOnly, words[0])
+	}
+	if words[0] == "WITH" {
+		for _, w := range words {
+			if w == "INSERT" || w == "UPSERT" {
+				return fmt.Errorf("%w: WITH ... %s statement rejected", ErrReadOnly, w)
+			}
+		}
+	}
+	return nil
+}
+
+// keywords returns the upper-cased words of stmt, skipping comments, string literals and quoted identifiers
+func keywords(stmt string) []string {
+	var words []string
+	for i := 0; i < len(stmt); {
+		c := stmt[i]
+		switch {
+		case strings.HasPrefix(stmt[i:], "--"):
+			end := strings.IndexByte(stmt[i:], '\n')
+			if end < 0 {
+				return words
+			}
+			i += end + 1
+		case strings.HasPrefix(stmt[i:], "/*"):
+			end := strings.Index(stmt[i+2:], "*/")
+			if end < 0 {
+				return words
+			}
+			i += 2 + end + 2
+		case c == '\'' || c == '"' || c == '`':
+			i = skipQuoted(stmt, i)
+		case isWordChar(c):
+			start := i
+			for i < len(stmt) && isWordChar(stmt[i]) {
+				i++
+			}
+			words = append(words, strings.ToUpper(stmt[start:i]))
+		default:
+			i++
+		}
+	}
+	return words
+}
+
+// trimLeadingComments returns stmt without the leading whitespace and comments, which keywords skips
+func trimLeadingComments(stmt string) string {
+	for {
+		stmt = strings.TrimLeftFunc(stmt, unicode.IsSpace)
+		switch {
+		case strings.HasPrefix(stmt, "--"):
+			end := strings.IndexByte(stmt, '\n')
+			if end < 0 {
+				return ""
+			}
+			stmt = stmt[end+1:]
+		case strings.HasPrefix(stmt, "/*"):
+			end := strings.Index(stmt[2:], "*/")
+			if end < 0 {
+				return ""
+			}
+			stmt = stmt[2+end+2:]
+		default:
+			return stmt
+		}
+	}
+}
+
+// skipQuoted returns the position after the quoted text starting at stmt[start], honoring backslash escapes
+func skipQuoted(stmt string, start int) int {
+	quote := stmt[start]
+	for i := start + 1; i < len(stmt); i++ {
+		switch stmt[i] {
+		case '\\':
+			i++
+		case quote:
+			return i + 1
+		}
+	}
+	return len(stmt)
+}
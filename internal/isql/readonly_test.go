@@ -0,0 +1,44 @@
+package isql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckReadOnly(t *testing.T) {
+	allowed := []string{
+		"SELECT 1",
+		"  select * from t",
+		"-- comment\nSELECT 1",
+		"/* DROP TABLE t */ SELECT 1",
+		"(SELECT 1) UNION ALL (SELECT 2)",
+		"WITH t AS (SELECT 1) SELECT * FROM t",
+		"WITH t AS (SELECT 'INSERT') SELECT * FROM t",
+		"VALUES (1, 2)",
+		"SHOW TABLES",
+		"DESCRIBE t",
+		"EXPLAIN INSERT INTO t VALUES (1)",
+		"USE analytics",
+		"SET MT_DOP=4",
+	}
+	for _, stmt := range allowed {
+		require.NoError(t, checkReadOnly(stmt), stmt)
+	}
+
+	rejected := []string{
+		"INSERT INTO t VALUES (1)",
+		"-- SELECT\nDROP TABLE t",
+		"/* SELECT */ CREATE TABLE t (a INT)",
+		"WITH t AS (SELECT 1) INSERT INTO u SELECT * FROM t",
+		"with t as (select 1) upsert into u select * from t",
+		"TRUNCATE t",
+		"COMPUTE STATS t",
+		"INVALIDATE METADATA",
+		"",
+		"-- only a comment",
+	}
+	for _, stmt := range rejected {
+		require.ErrorIs(t, checkReadOnly(stmt), ErrReadOnly, stmt)
+	}
+}
@@ -12,6 +12,10 @@ type Rows struct {
 	rs      *hive.ResultSet
 	schema  *hive.TableSchema
 	closefn func() error
+
+	op *hive.Operation
+	// detached means that Close must leave the operation open on the server
+	detached bool
 }
 
 // Close closes rows iterator. Implements [driver.Rows].
@@ -37,7 +41,7 @@ func (r *Rows) ColumnTypeScanType(index int) reflect.Type {
 // ColumnTypeDatabaseTypeName returns column's database type name.
 // Implements [driver.RowsColumnTypeDatabaseTypeName]
 func (r *Rows) ColumnTypeDatabaseTypeName(index int) string {
-	return r.schema.Columns[index].DatabaseTypeName
+	return r.schema.Columns[index].ColumnTypeDatabaseTypeName()
 }
 
 // ColumnTypeNullable implements [driver.RowsColumnTypeNullable]
@@ -57,7 +61,28 @@ func (r *Rows) ColumnTypeLength(index int) (length int64, ok bool) {
 	return colDesc.Length, colDesc.HasLength
 }
 
+// RowsFetched returns the number of rows returned by Next so far
+func (r *Rows) RowsFetched() int64 {
+	return r.rs.RowsFetched()
+}
+
+// BatchHasNulls reports, for each column, whether the current batch of rows has any NULL values
+func (r *Rows) BatchHasNulls() []bool {
+	return r.rs.BatchHasNulls()
+}
+
 // Next prepares next row for scanning. Implements [driver.Rows].
 func (r *Rows) Next(dest []driver.Value) error {
 	return r.rs.Next(dest)
 }
+
+// Detach makes Close leave the operation open on the server and returns its handle for Conn.AttachOperation
+func (r *Rows) Detach() string {
+	r.detached = true
+	return r.op.Handle()
+}
+
+// Rewind restarts the rows from the first row, if the result cache is enabled
+func (r *Rows) Rewind() error {
+	return r.rs.Rewind()
+}
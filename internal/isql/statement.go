@@ -3,8 +3,11 @@ package isql
 import (
 	"context"
 	"database/sql/driver"
+	"errors"
 	"fmt"
+	"maps"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/sclgo/impala-go/internal/hive"
@@ -13,6 +16,8 @@ import (
 // Stmt is statement
 type Stmt struct {
 	stmt string
+	// parsed caches the placeholder positions of stmt, so repeated executions don't scan it again
+	parsed *parsedStatement
 
 	conn *Conn
 }
@@ -50,12 +55,12 @@ func (s *Stmt) Query([]driver.Value) (driver.Rows, error) {
 
 // QueryContext executes a query that may return rows
 func (s *Stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
-	return s.conn.QueryContext(ctx, s.stmt, args)
+	return s.conn.queryParsed(ctx, s.parsed, args)
 }
 
 // ExecContext executes a query that doesn't return rows
 func (s *Stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
-	return s.conn.ExecContext(ctx, s.stmt, args)
+	return s.conn.execParsed(ctx, s.parsed, args)
 }
 
 // template replaces all ? placeholders with ordinal placeholders
@@ -118,63 +123,216 @@ func template(query string) string {
 }
 
 func statement(tmpl string, args []driver.NamedValue) string {
-	stmt := tmpl
+	return parsePlaceholders(tmpl).bind(args)
+}
+
+// parsedStatement is a statement template split at its @ placeholders, so that parameters can be
+// substituted repeatedly without scanning the SQL again
+type parsedStatement struct {
+	// parts holds the text between placeholders; len(parts) == len(names)+1
+	parts []string
+	// names holds the placeholder names without @, e.g. p1 for the first ? or the name of a named parameter
+	names []string
+}
+
+// parsePlaceholders splits a template, produced by template(), at its placeholders.
+// Like the ordinal placeholders, named placeholders are recognized everywhere, including in string literals.
+func parsePlaceholders(tmpl string) *parsedStatement {
+	ps := &parsedStatement{}
+	start := 0
+	for i := 0; i < len(tmpl); i++ {
+		if tmpl[i] != '@' {
+			continue
+		}
+		end := i + 1
+		for end < len(tmpl) && isWordChar(tmpl[end]) {
+			end++
+		}
+		if end == i+1 {
+			continue
+		}
+		ps.parts = append(ps.parts, tmpl[start:i])
+		ps.names = append(ps.names, tmpl[i+1:end])
+		start = end
+		i = end - 1
+	}
+	ps.parts = append(ps.parts, tmpl[start:])
+	return ps
+}
+
+// bind substitutes args for the placeholders. Placeholders without a matching arg are kept as they are.
+func (ps *parsedStatement) bind(args []driver.NamedValue) string {
+	if len(ps.names) == 0 {
+		return ps.parts[0]
+	}
+	values := make(map[string]string, len(args))
 	for _, arg := range args {
-		var re *regexp.Regexp
-		if arg.Name != "" {
-			re = regexp.MustCompile(fmt.Sprintf("@%s%s", arg.Name, `\b`))
+		name := arg.Name
+		if name == "" {
+			name = "p" + strconv.Itoa(arg.Ordinal)
+		}
+		if _, ok := values[name]; ok {
+			continue
+		}
+		switch v := arg.Value.(type) {
+		case string:
+			values[name] = QuoteString(v)
+		case literal:
+			values[name] = string(v)
+		default:
+			values[name] = fmt.Sprint(v)
+		}
+	}
+
+	var sb strings.Builder
+	for i, name := range ps.names {
+		sb.WriteString(ps.parts[i])
+		if val, ok := values[name]; ok {
+			sb.WriteString(val)
 		} else {
-			re = regexp.MustCompile(fmt.Sprintf("@p%d%s", arg.Ordinal, `\b`))
+			sb.WriteByte('@')
+			sb.WriteString(name)
 		}
-		formatStr := "%v"
-		if _, ok := arg.Value.(string); ok {
-			formatStr = "'%v'"
+	}
+	sb.WriteString(ps.parts[len(ps.parts)-1])
+	return sb.String()
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func (c *Conn) query(ctx context.Context, session *hive.Session, stmt string, queryOptions map[string]string) (driver.Rows, error) {
+	operation, err := c.start(ctx, session, stmt, queryOptions)
+	if err != nil {
+		return nil, err
+	}
+	return c.rows(ctx, operation)
+}
+
+func (c *Conn) exec(ctx context.Context, session *hive.Session, stmt string) (driver.Result, error) {
+	operation, err := c.start(ctx, session, stmt, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.finish(ctx, operation, stmt)
+}
+
+// run returns rows if the statement has a result set, or waits for it to finish otherwise
+func (c *Conn) run(ctx context.Context, session *hive.Session, stmt string) (*Outcome, error) {
+	operation, err := c.start(ctx, session, stmt, nil)
+	if err != nil {
+		return nil, err
+	}
+	if operation.HasResultSet() {
+		rows, err := c.rows(ctx, operation)
+		if err != nil {
+			return nil, err
 		}
-		val := fmt.Sprintf(formatStr, arg.Value)
-		stmt = re.ReplaceAllString(stmt, val)
+		return &Outcome{Rows: rows}, nil
+	}
+	res, err := c.finish(ctx, operation, stmt)
+	if err != nil {
+		return nil, err
 	}
-	return stmt
+	return &Outcome{Result: res}, nil
 }
 
-func query(ctx context.Context, session *hive.Session, stmt string) (driver.Rows, error) {
-	operation, err := session.ExecuteStatement(ctx, stmt)
+// start marks the connection busy and starts executing stmt.
+// The caller must pass the returned operation to either rows or finish, which mark the connection idle again.
+func (c *Conn) start(ctx context.Context, session *hive.Session, stmt string, queryOptions map[string]string) (*hive.Operation, error) {
+	if c.opts.AcceptsContext != nil && !c.opts.AcceptsContext(ctx) {
+		// database/sql retries statements, which fail with ErrBadConn, on another connection.
+		// It may hand out a new connection without calling ResetSession, so we check here as well.
+		return nil, fmt.Errorf("%w: connection can't run statements with the given context", driver.ErrBadConn)
+	}
+	if c.opts.ReadOnly {
+		if err := checkReadOnly(stmt); err != nil {
+			return nil, err
+		}
+	}
+	queryOptions, err := statementOptions(ctx, queryOptions)
 	if err != nil {
 		return nil, err
 	}
+	if err := c.startOp(); err != nil {
+		return nil, err
+	}
+	c.lastLog = ""
+	c.lastChecksum, c.hasLastChecksum = 0, false
+	operation, err := session.ExecuteStatement(ctx, stmt, queryOptions)
+	if err != nil {
+		c.endOp()
+		return nil, err
+	}
+	c.setRunning(operation)
+	return operation, nil
+}
 
+// rows returns the rows iterator for the operation. The connection is idle again when the rows are closed.
+func (c *Conn) rows(ctx context.Context, operation *hive.Operation) (driver.Rows, error) {
 	schema, err := operation.GetResultSetMetadata(ctx)
 	if err != nil {
+		c.endOp()
 		return nil, err
 	}
 
 	rs, err := operation.FetchResults(ctx, schema)
 	if err != nil {
+		c.endOp()
 		return nil, err
 	}
 
-	return &Rows{
+	rows := &Rows{
 		rs:     rs,
 		schema: schema,
-		// TODO align context handling with database/sql practices (Github #14)
-		closefn: func() error {
-			_, err := operation.Close(ctx)
-			return err
-		},
-	}, nil
+		op:     operation,
+	}
+	// TODO align context handling with database/sql practices (Github #14)
+	rows.closefn = func() error {
+		defer c.endOp()
+		// database/sql serializes Rows.Close with the other calls to the connection, so no locking is needed
+		c.openRows = nil
+		c.lastChecksum, c.hasLastChecksum = rs.Checksum()
+		if rows.detached {
+			// the handle contains the secret of the operation so it is not logged
+			c.log.Infof("detached operation left open on the server")
+			return nil
+		}
+		c.lastLog = c.operationLog(ctx, operation)
+		_, err := operation.Close(ctx)
+		return err
+	}
+	c.openRows = rows
+	return rows, nil
 }
 
-func exec(ctx context.Context, session *hive.Session, stmt string) (driver.Result, error) {
-	operation, err := session.ExecuteStatement(ctx, stmt)
-	if err != nil {
-		return nil, err
-	}
+// ctasRegex matches CREATE TABLE AS SELECT statements without leading comments; false positives are harmless
+var ctasRegex = regexp.MustCompile(`(?is)^CREATE\s+.*\bAS\b`)
+
+// isCTAS reports if stmt is a CREATE TABLE AS SELECT statement, ignoring leading comments
+func isCTAS(stmt string) bool {
+	return ctasRegex.MatchString(trimLeadingComments(stmt))
+}
+
+// finish waits for the operation to complete, closes it, and returns the number of affected rows
+func (c *Conn) finish(ctx context.Context, operation *hive.Operation, stmt string) (driver.Result, error) {
+	defer c.endOp()
 
 	// wait for DDL/DML to finish like impala-shell :
 	// https://github.com/apache/impala/blob/aac375e/shell/impala_shell.py#L1412
-	err = operation.WaitToFinish(ctx)
+	err := operation.WaitToFinish(ctx)
 	if err != nil {
 		return nil, err
 	}
+	c.lastLog = c.operationLog(ctx, operation)
+
+	if operation.HasResultSet() && isCTAS(stmt) {
+		err = operation.FetchInsertedRows(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	rowsAffected, err := operation.Close(ctx)
 	if err != nil {
@@ -183,3 +341,60 @@ func exec(ctx context.Context, session *hive.Session, stmt string) (driver.Resul
 
 	return driver.RowsAffected(rowsAffected), nil
 }
+
+// maxLogSize limits the size of the operation log kept by Conn
+const maxLogSize = 64 * 1024
+
+// operationLog returns the log of an operation, truncated to maxLogSize, if Options.StatementLog is enabled.
+// The log is informational so failing to get it is logged but not reported.
+func (c *Conn) operationLog(ctx context.Context, operation *hive.Operation) string {
+	if !c.opts.StatementLog {
+		return ""
+	}
+	opLog, err := operation.GetLog(ctx)
+	if err != nil {
+		c.log.Errorf("failed to get operation log: %v", err)
+		return ""
+	}
+	if len(opLog) > maxLogSize {
+		opLog = strings.ToValidUTF8(opLog[:maxLogSize], "")
+	}
+	return opLog
+}
+
+// ErrInvalidQueryOption means that a query option in the context has an empty key or value
+var ErrInvalidQueryOption = errors.New("impala: invalid query option: keys and values must not be empty")
+
+type queryOptionsKey struct{}
+
+// WithQueryOptions returns a context that applies the given query options to each statement executed with it.
+// Query options from previous WithQueryOptions calls on the parent context are kept, unless overridden.
+func WithQueryOptions(ctx context.Context, queryOptions map[string]string) context.Context {
+	merged := maps.Clone(queryOptionsFromContext(ctx))
+	if merged == nil {
+		merged = make(map[string]string, len(queryOptions))
+	}
+	maps.Copy(merged, queryOptions)
+	return context.WithValue(ctx, queryOptionsKey{}, merged)
+}
+
+func queryOptionsFromContext(ctx context.Context) map[string]string {
+	queryOptions, _ := ctx.Value(queryOptionsKey{}).(map[string]string)
+	return queryOptions
+}
+
+// statementOptions merges the query options from ctx with the given ones, which take precedence
+func statementOptions(ctx context.Context, queryOptions map[string]string) (map[string]string, error) {
+	ctxOptions := queryOptionsFromContext(ctx)
+	if len(ctxOptions) == 0 {
+		return queryOptions, nil
+	}
+	merged := maps.Clone(ctxOptions)
+	maps.Copy(merged, queryOptions)
+	for k, v := range merged {
+		if k == "" || v == "" {
+			return nil, fmt.Errorf("%w: %q=%q", ErrInvalidQueryOption, k, v)
+		}
+	}
+	return merged, nil
+}
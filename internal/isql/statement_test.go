@@ -1,6 +1,7 @@
 package isql
 
 import (
+	"context"
 	"database/sql/driver"
 	"testing"
 
@@ -37,6 +38,13 @@ func TestStatement_ValueReplacement(t *testing.T) {
 			},
 			target: "'1' 2",
 		},
+		{
+			stmt: "@p1",
+			args: []driver.NamedValue{
+				driver.NamedValue{Ordinal: 1, Value: `it's C:\`},
+			},
+			target: `'it\'s C:\\'`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -45,6 +53,48 @@ func TestStatement_ValueReplacement(t *testing.T) {
 	}
 }
 
+func TestParsePlaceholders(t *testing.T) {
+	ps := parsePlaceholders("SELECT @p1, @named, 'a@b.com', @ @p2")
+	require.Equal(t, []string{"p1", "named", "b", "p2"}, ps.names)
+
+	args := []driver.NamedValue{
+		{Ordinal: 1, Value: "@p2"},
+		{Ordinal: 2, Value: 2},
+	}
+	// values are substituted once, so placeholders inside them are not replaced
+	require.Equal(t, "SELECT '@p2', @named, 'a@b.com', @ 2", ps.bind(args))
+	require.Equal(t, "SELECT @p1, @named, 'a@b.com', @ @p2", ps.bind(nil), "unbound placeholders are kept")
+	require.Equal(t, "SELECT 1", parsePlaceholders("SELECT 1").bind(args))
+}
+
+func BenchmarkStmt_Bind(b *testing.B) {
+	query := "INSERT INTO t VALUES (?, ?, ?, 'what?')"
+	args := []driver.NamedValue{
+		{Ordinal: 1, Value: int64(1)},
+		{Ordinal: 2, Value: "name"},
+		{Ordinal: 3, Value: 3.5},
+	}
+	// each iteration runs a loop of 10k statements, like a tight INSERT loop
+	const loop = 10_000
+
+	b.Run("parse each time", func(b *testing.B) {
+		for b.Loop() {
+			for range loop {
+				_ = parsePlaceholders(template(query)).bind(args)
+			}
+		}
+	})
+
+	b.Run("prepared", func(b *testing.B) {
+		ps := parsePlaceholders(template(query))
+		for b.Loop() {
+			for range loop {
+				_ = ps.bind(args)
+			}
+		}
+	})
+}
+
 func TestTemplate(t *testing.T) {
 	tests := []struct {
 		stmt   string
@@ -85,3 +135,39 @@ func TestTemplate(t *testing.T) {
 		require.Equal(t, tt.target, result)
 	}
 }
+
+func TestStatementOptions(t *testing.T) {
+	ctx := context.Background()
+	res, err := statementOptions(ctx, nil)
+	require.NoError(t, err)
+	require.Nil(t, res)
+
+	ctx = WithQueryOptions(ctx, map[string]string{"REQUEST_POOL": "etl", "CLIENT_IDENTIFIER": "job1"})
+	ctx = WithQueryOptions(ctx, map[string]string{"CLIENT_IDENTIFIER": "job2"})
+	res, err = statementOptions(ctx, map[string]string{"EXPLAIN_LEVEL": "3"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"REQUEST_POOL": "etl", "CLIENT_IDENTIFIER": "job2", "EXPLAIN_LEVEL": "3"}, res)
+
+	ctx = WithQueryOptions(ctx, map[string]string{"REQUEST_POOL": ""})
+	_, err = statementOptions(ctx, nil)
+	require.ErrorIs(t, err, ErrInvalidQueryOption)
+}
+
+func TestIsCTAS(t *testing.T) {
+	tests := []struct {
+		stmt string
+		want bool
+	}{
+		{stmt: "CREATE TABLE t AS SELECT 1", want: true},
+		{stmt: "  create table t\nas select 1", want: true},
+		{stmt: "-- copy\nCREATE TABLE t AS SELECT 1", want: true},
+		{stmt: "/* copy */ /* again */\n-- and again\nCREATE TABLE t AS SELECT 1", want: true},
+		{stmt: "CREATE TABLE t (a INT)", want: false},
+		{stmt: "INSERT INTO t SELECT 1 AS a", want: false},
+		{stmt: "-- CREATE TABLE t AS SELECT 1\nSELECT 1 AS a", want: false},
+		{stmt: "/* CREATE TABLE t AS SELECT 1", want: false},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, isCTAS(tt.stmt), tt.stmt)
+	}
+}
@@ -0,0 +1,82 @@
+package isql
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/sclgo/impala-go/internal/hive"
+)
+
+// TypedValue is a parameter with the SQL type of its destination, e.g. a table column. It is substituted
+// as CAST(<literal> AS <type>) so Impala doesn't reject it for a possible loss of precision or infer another type.
+type TypedValue struct {
+	value any
+	// sqlType is the type as written in Impala DDL e.g. DECIMAL(10,2). It is empty if the destination is unknown.
+	sqlType string
+	// baseType is sqlType without qualifiers e.g. DECIMAL
+	baseType string
+	// scale applies to DECIMAL
+	scale int
+	// err reports why sqlType is unknown. It is returned when the statement is run.
+	err error
+}
+
+// NewTypedValue returns value typed as col
+func NewTypedValue(col hive.ColumnName, value any) TypedValue {
+	return TypedValue{value: value, sqlType: col.SQLTypeName(), baseType: col.DatabaseTypeName, scale: int(col.Scale)}
+}
+
+// NewUntypedValue returns a TypedValue, which fails the statement with err, so callers can report errors
+// in the argument list
+func NewUntypedValue(value any, err error) TypedValue {
+	return TypedValue{value: value, err: err}
+}
+
+// literal is a parameter already formatted as SQL, which bind substitutes as is
+type literal string
+
+// literal formats v as CAST(<literal> AS <type>)
+func (v TypedValue) literal() (literal, error) {
+	if v.err != nil {
+		return "", v.err
+	}
+	val := v.value
+	if _, ok := val.(*big.Rat); !ok {
+		// The default converter calls driver.Valuer, like for other parameters
+		var err error
+		if val, err = driver.DefaultParameterConverter.ConvertValue(val); err != nil {
+			return "", err
+		}
+	}
+	var lit string
+	switch t := val.(type) {
+	case nil:
+		lit = "NULL"
+	case string:
+		lit = QuoteString(t)
+	case []byte:
+		lit = QuoteString(string(t))
+	case time.Time:
+		if v.baseType == "DATE" {
+			lit = QuoteString(t.Format(hive.DateFormat))
+		} else {
+			lit = QuoteString(t.Format(hive.TimestampFormat))
+		}
+	case *big.Rat:
+		lit = t.FloatString(v.scale)
+	case float64:
+		lit = strconv.FormatFloat(t, 'g', -1, 64)
+		if math.IsNaN(t) || math.IsInf(t, 0) {
+			// Impala has no literals for these, but casts them from strings
+			lit = QuoteString(lit)
+		}
+	default:
+		// int64 and bool
+		lit = fmt.Sprint(t)
+	}
+	return literal(fmt.Sprintf("CAST(%s AS %s)", lit, v.sqlType)), nil
+}
@@ -0,0 +1,56 @@
+package isql
+
+import (
+	"database/sql/driver"
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/sclgo/impala-go/internal/hive"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTypedValue_Literal(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	decimal := hive.ColumnName{DatabaseTypeName: "DECIMAL", Precision: 10, Scale: 2, HasPrecisionScale: true}
+	varchar := hive.ColumnName{DatabaseTypeName: "VARCHAR", Length: 20, HasLength: true}
+	timestamp := hive.ColumnName{DatabaseTypeName: "TIMESTAMP"}
+	date := hive.ColumnName{DatabaseTypeName: "DATE"}
+	binary := hive.ColumnName{DatabaseTypeName: "BINARY"}
+	double := hive.ColumnName{DatabaseTypeName: "DOUBLE"}
+	tests := []struct {
+		col    hive.ColumnName
+		value  any
+		target literal
+	}{
+		{decimal, 1.5, "CAST(1.5 AS DECIMAL(10,2))"},
+		{decimal, big.NewRat(1, 3), "CAST(0.33 AS DECIMAL(10,2))"},
+		{decimal, "12.34", "CAST('12.34' AS DECIMAL(10,2))"},
+		{decimal, nil, "CAST(NULL AS DECIMAL(10,2))"},
+		{varchar, "it's", `CAST('it\'s' AS VARCHAR(20))`},
+		{timestamp, ts, "CAST('2024-01-02 03:04:05' AS TIMESTAMP)"},
+		{date, ts, "CAST('2024-01-02' AS DATE)"},
+		{binary, []byte("ab"), "CAST('ab' AS BINARY)"},
+		{double, math.Inf(1), "CAST('+Inf' AS DOUBLE)"},
+		{hive.ColumnName{DatabaseTypeName: "BOOLEAN"}, true, "CAST(true AS BOOLEAN)"},
+		{hive.ColumnName{DatabaseTypeName: "TINYINT"}, 7, "CAST(7 AS TINYINT)"},
+	}
+	for _, tt := range tests {
+		lit, err := NewTypedValue(tt.col, tt.value).literal()
+		require.NoError(t, err)
+		require.Equal(t, tt.target, lit)
+	}
+
+	_, err := NewUntypedValue(1, errors.New("unknown column")).literal()
+	require.ErrorContains(t, err, "unknown column")
+	_, err = NewTypedValue(double, struct{}{}).literal()
+	require.Error(t, err)
+}
+
+func TestConn_CheckNamedValue_Typed(t *testing.T) {
+	val := driver.NamedValue{Ordinal: 1, Value: NewTypedValue(hive.ColumnName{DatabaseTypeName: "FLOAT"}, float32(0.5))}
+	require.NoError(t, (&Conn{}).CheckNamedValue(&val))
+	require.Equal(t, "CAST(0.5 AS FLOAT) p1", statement("@p1 p1", []driver.NamedValue{val}))
+}
@@ -36,6 +36,7 @@ type TSaslTransport struct {
 
 	trans thrift.TTransport
 	sasl  Client
+	mech  string
 }
 
 // Status is SASL negotiation status
@@ -109,10 +110,16 @@ return fmt.Errorf("sasl: negotiation failed for mech %s. %w", mech, err)
 		}
 
 	}
+	t.mech = mech
 	return nil
 
 }
 
+// Mechanism returns the negotiated SASL mechanism e.g. PLAIN. It is empty until Open succeeds.
+func (t *TSaslTransport) Mechanism() string {
+	return t.mech
+}
+
 func (t *TSaslTransport) Read(buf []byte) (int, error) {
 	n, err := t.rbuf.Read(buf)
 	if err != nil && err != io.EOF {
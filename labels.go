@@ -0,0 +1,26 @@
+package impala
+
+import (
+	"context"
+
+	"github.com/sclgo/impala-go/internal/isql"
+)
+
+// ErrInvalidQueryLabel means that a label attached with WithQueryLabels has an empty key or value.
+// Statements executed with such a context fail with this error before reaching the server.
+var ErrInvalidQueryLabel = isql.ErrInvalidQueryOption
+
+// WithQueryLabels returns a context that attaches the given labels to each statement executed with it
+// e.g. with QueryContext or ExecContext. Labels from previous WithQueryLabels calls on the parent context
+// are kept, unless overridden. Keys and values must not be empty.
+//
+// Labels are sent as query options for the individual statement, so they don't change the session.
+// Impala accepts only the query options it recognizes and fails statements with unknown keys.
+// Labels useful for grouping queries by job include:
+//   - REQUEST_POOL - the admission control pool, shown in the query list in the Impala Web UI
+//   - CLIENT_IDENTIFIER - shown in the query profile (Impala 4.x). See also Options.ClientIdentifier.
+//
+// See https://impala.apache.org/docs/build/html/topics/impala_query_options.html for all query options.
+func WithQueryLabels(ctx context.Context, labels map[string]string) context.Context {
+	return isql.WithQueryOptions(ctx, labels)
+}
@@ -1,11 +1,14 @@
 package impala
 
 import (
+	"cmp"
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"iter"
 	"slices"
+	"strings"
 
 	"github.com/sclgo/impala-go/internal/hive"
 	"github.com/sclgo/impala-go/internal/isql"
@@ -13,8 +16,10 @@ import (
 
 // Metadata exposes the schema and other metadata in an Impala instance
 type Metadata struct {
-	db   *sql.DB
-	conn ConnRawAccess
+	db      *sql.DB
+	conn    ConnRawAccess
+	literal bool
+	sorted  bool
 }
 
 // ConnRawAccess exposes the Raw method of sql.Conn
@@ -28,6 +33,9 @@ type TableName = hive.TableName
 // ColumnName contains all attributes that identify a columns
 type ColumnName = hive.ColumnName
 
+// TypeInfo describes a data type supported by Impala
+type TypeInfo = hive.TypeInfo
+
 // It is questionable if it is appropriate to have a type alias to internal package
 // in a public package. Will change if it becomes an issue.
 
@@ -43,25 +51,129 @@ func NewMetadataFromConn(conn ConnRawAccess) *Metadata {
 	return &Metadata{conn: conn}
 }
 
+// WithLiteralNames returns a copy of m, which treats the name arguments of its methods
+// as literal names rather than LIKE patterns, by escaping them with EscapePattern.
+// For example, GetTables(ctx, "default", "my_table") won't match "myxtable".
+func (m Metadata) WithLiteralNames() *Metadata {
+	m.literal = true
+	return &m
+}
+
+// WithSortedResults returns a copy of m, which sorts the results of GetSchemas, GetTables, and GetColumns
+// before returning them, so they don't depend on the order of the server, which is not guaranteed to be stable.
+// Schemas are sorted by name, tables by schema and name, and columns by schema, table, and position.
+func (m Metadata) WithSortedResults() *Metadata {
+	m.sorted = true
+	return &m
+}
+
+// EscapePattern escapes the LIKE wildcards % and _, as well as the escape character \, in name
+// so the result matches only name when used as a pattern in Metadata methods
+func EscapePattern(name string) string {
+	return patternEscaper.Replace(name)
+}
+
+var patternEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (m Metadata) pattern(arg string) string {
+	if m.literal {
+		return EscapePattern(arg)
+	}
+	return arg
+}
+
 // GetColumns retrieves columns that match the provided LIKE patterns
 func (m Metadata) GetColumns(ctx context.Context, schemaPattern string, tableNamePattern string, columnNamePattern string) ([]ColumnName, error) {
-	return raw(ctx, m.db, m.conn, func(dbm hive.DBMetadata) (iter.Seq[hive.ColumnName], *error) {
+	schemaPattern, tableNamePattern, columnNamePattern = m.pattern(schemaPattern), m.pattern(tableNamePattern), m.pattern(columnNamePattern)
+	res, err := raw(ctx, m.db, m.conn, func(dbm hive.DBMetadata) (iter.Seq[hive.ColumnName], *error) {
 		return dbm.GetColumnsSeq(ctx, schemaPattern, tableNamePattern, columnNamePattern)
 	})
+	if m.sorted {
+		slices.SortStableFunc(res, compareColumns)
+	}
+	return res, err
+}
+
+// ColumnTypes maps the lower-case names of the columns of a table to their types, see Metadata.GetColumnTypes
+type ColumnTypes map[string]ColumnName
+
+// GetColumnTypes retrieves the columns of the table schema.table with GetColumns. schema and table are literal
+// names, not LIKE patterns. If the table doesn't exist, the error wraps ErrObjectNotFound.
+// Pass the values of parameters through ColumnTypes.Arg to format them for the destination columns.
+func (m Metadata) GetColumnTypes(ctx context.Context, schema string, table string) (ColumnTypes, error) {
+	// only schema and table are literal; "%" must stay a wildcard to match all columns
+	m.literal = false
+	cols, err := m.GetColumns(ctx, EscapePattern(schema), EscapePattern(table), "%")
+	if err != nil {
+		return nil, err
+	}
+	if len(cols) == 0 {
+		return nil, fmt.Errorf("%w: table %s.%s has no columns", ErrObjectNotFound, schema, table)
+	}
+	res := make(ColumnTypes, len(cols))
+	for _, col := range cols {
+		res[strings.ToLower(col.ColumnName)] = col
+	}
+	return res, nil
+}
+
+// Arg returns value as a statement parameter for the given column, e.g. in an INSERT. The driver substitutes it
+// as CAST(<value> AS <column type>), so Impala doesn't reject it for a possible loss of precision, e.g. a float64
+// for a DECIMAL(10,2) or a string for a VARCHAR(20). time.Time values are formatted as dates for DATE columns,
+// and *big.Rat values are supported with the scale of the column. column is matched case-insensitively.
+// If ct has no such column, the statement fails.
+func (ct ColumnTypes) Arg(column string, value any) any {
+	col, ok := ct[strings.ToLower(column)]
+	if !ok {
+		return isql.NewUntypedValue(value, fmt.Errorf("unknown column %q", column))
+	}
+	return isql.NewTypedValue(col, value)
 }
 
 // GetTables retrieves tables and views that match the provided LIKE patterns
 func (m Metadata) GetTables(ctx context.Context, schemaPattern string, tableNamePattern string) ([]TableName, error) {
-	return raw(ctx, m.db, m.conn, func(dbm hive.DBMetadata) (iter.Seq[hive.TableName], *error) {
+	schemaPattern, tableNamePattern = m.pattern(schemaPattern), m.pattern(tableNamePattern)
+	res, err := raw(ctx, m.db, m.conn, func(dbm hive.DBMetadata) (iter.Seq[hive.TableName], *error) {
 		return dbm.GetTablesSeq(ctx, schemaPattern, tableNamePattern)
 	})
+	if m.sorted {
+		slices.SortStableFunc(res, compareTables)
+	}
+	return res, err
 }
 
 // GetSchemas retrieves schemas that match the provided LIKE pattern
 func (m Metadata) GetSchemas(ctx context.Context, schemaPattern string) ([]string, error) {
-	return raw(ctx, m.db, m.conn, func(dbm hive.DBMetadata) (iter.Seq[string], *error) {
+	schemaPattern = m.pattern(schemaPattern)
+	res, err := raw(ctx, m.db, m.conn, func(dbm hive.DBMetadata) (iter.Seq[string], *error) {
 		return dbm.GetSchemasSeq(ctx, schemaPattern)
 	})
+	if m.sorted {
+		slices.Sort(res)
+	}
+	return res, err
+}
+
+// GetTypeInfo retrieves the data types supported by the server, following JDBC DatabaseMetaData.getTypeInfo
+func (m Metadata) GetTypeInfo(ctx context.Context) ([]TypeInfo, error) {
+	return raw(ctx, m.db, m.conn, func(dbm hive.DBMetadata) (iter.Seq[hive.TypeInfo], *error) {
+		return dbm.GetTypeInfoSeq(ctx)
+	})
+}
+
+func compareTables(a, b TableName) int {
+	return cmp.Or(
+		strings.Compare(a.Schema, b.Schema),
+		strings.Compare(a.Name, b.Name),
+	)
+}
+
+func compareColumns(a, b ColumnName) int {
+	return cmp.Or(
+		strings.Compare(a.Schema, b.Schema),
+		strings.Compare(a.TableName, b.TableName),
+		cmp.Compare(a.Position, b.Position),
+	)
 }
 
 // raw executes the given sequence-producing function over a HiveSession derived from a raw connection produced by db
@@ -0,0 +1,59 @@
+package impala
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareTables(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b TableName
+		want int
+	}{
+		{name: "equal", a: TableName{Schema: "s", Name: "t"}, b: TableName{Schema: "s", Name: "t"}, want: 0},
+		{name: "schema first", a: TableName{Schema: "a", Name: "z"}, b: TableName{Schema: "b", Name: "a"}, want: -1},
+		{name: "then name", a: TableName{Schema: "s", Name: "b"}, b: TableName{Schema: "s", Name: "a"}, want: 1},
+		{name: "type ignored", a: TableName{Schema: "s", Name: "t", Type: "VIEW"}, b: TableName{Schema: "s", Name: "t", Type: "TABLE"}, want: 0},
+		{name: "case sensitive", a: TableName{Schema: "s", Name: "T"}, b: TableName{Schema: "s", Name: "t"}, want: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, compareTables(tt.a, tt.b))
+			require.Equal(t, -tt.want, compareTables(tt.b, tt.a))
+		})
+	}
+}
+
+func TestCompareColumns(t *testing.T) {
+	col := func(schema, table string, pos int, name string) ColumnName {
+		return ColumnName{Schema: schema, TableName: table, Position: pos, ColumnName: name}
+	}
+	tests := []struct {
+		name string
+		a, b ColumnName
+		want int
+	}{
+		{name: "equal", a: col("s", "t", 1, "c"), b: col("s", "t", 1, "c"), want: 0},
+		{name: "schema first", a: col("a", "z", 9, "c"), b: col("b", "a", 1, "c"), want: -1},
+		{name: "then table", a: col("s", "b", 1, "c"), b: col("s", "a", 9, "c"), want: 1},
+		{name: "then position", a: col("s", "t", 2, "a"), b: col("s", "t", 10, "b"), want: -1},
+		{name: "name ignored", a: col("s", "t", 1, "a"), b: col("s", "t", 1, "b"), want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, compareColumns(tt.a, tt.b))
+			require.Equal(t, -tt.want, compareColumns(tt.b, tt.a))
+		})
+	}
+
+	t.Run("sort", func(t *testing.T) {
+		cols := []ColumnName{col("s", "u", 1, "x"), col("s", "t", 10, "b"), col("r", "t", 1, "y"), col("s", "t", 2, "a")}
+		slices.SortStableFunc(cols, compareColumns)
+		require.Equal(t, []ColumnName{
+			col("r", "t", 1, "y"), col("s", "t", 2, "a"), col("s", "t", 10, "b"), col("s", "u", 1, "x"),
+		}, cols)
+	})
+}
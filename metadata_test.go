@@ -3,8 +3,10 @@ package impala_test
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/sclgo/impala-go"
+	"github.com/sclgo/impala-go/impalatest"
 	"github.com/stretchr/testify/require"
 )
 
@@ -27,3 +29,50 @@ func TestMetadata_GetTables(t *testing.T) {
 		require.Error(t, err)
 	})
 }
+
+func TestEscapePattern(t *testing.T) {
+	require.Equal(t, "default", impala.EscapePattern("default"))
+	require.Equal(t, `my\_table\%`, impala.EscapePattern("my_table%"))
+	require.Equal(t, `a\\b`, impala.EscapePattern(`a\b`))
+}
+
+func TestMetadata_GetColumnTypes(t *testing.T) {
+	srv, db := impalatest.OpenDB(t)
+	require.NoError(t, srv.AddTable("my_db", "my_table",
+		impalatest.Column{Name: "ID", Type: "BIGINT"}, impalatest.Column{Name: "name", Type: "STRING"}))
+	require.NoError(t, srv.AddTable("my_db", "myxtable", impalatest.Column{Name: "other", Type: "STRING"}))
+	ctx := context.Background()
+
+	for _, meta := range []*impala.Metadata{impala.NewMetadata(db), impala.NewMetadata(db).WithLiteralNames()} {
+		ct, err := meta.GetColumnTypes(ctx, "my_db", "my_table")
+		require.NoError(t, err)
+		require.Equal(t, impala.ColumnTypes{
+			"id":   {Schema: "my_db", TableName: "my_table", ColumnName: "ID", Position: 1, DatabaseTypeName: "BIGINT"},
+			"name": {Schema: "my_db", TableName: "my_table", ColumnName: "name", Position: 2, DatabaseTypeName: "STRING"},
+		}, ct)
+	}
+	for _, req := range srv.ColumnsRequests() {
+		require.Equal(t, impalatest.ColumnsRequest{Schema: `my\_db`, Table: `my\_table`, Column: "%"}, req)
+	}
+
+	_, err := impala.NewMetadata(db).GetColumnTypes(ctx, "my_db", "missing")
+	require.ErrorIs(t, err, impala.ErrObjectNotFound)
+}
+
+func TestColumnTypes_Arg(t *testing.T) {
+	srv := impalatest.Start(t)
+	db := srv.OpenDB(t)
+
+	ct := impala.ColumnTypes{
+		"ts":     {ColumnName: "ts", DatabaseTypeName: "TIMESTAMP"},
+		"amount": {ColumnName: "amount", DatabaseTypeName: "DECIMAL", Precision: 10, Scale: 2, HasPrecisionScale: true},
+	}
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	_, err := db.Exec("INSERT INTO t VALUES (?, ?)", ct.Arg("TS", ts), ct.Arg("amount", 9.99))
+	require.NoError(t, err)
+	require.Contains(t, srv.Statements(),
+		"INSERT INTO t VALUES (CAST('2024-01-02 03:04:05' AS TIMESTAMP), CAST(9.99 AS DECIMAL(10,2)))")
+
+	_, err = db.Exec("INSERT INTO t VALUES (?)", ct.Arg("missing", 1))
+	require.ErrorContains(t, err, `unknown column "missing"`)
+}
@@ -0,0 +1,66 @@
+package impala
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+
+	"github.com/sclgo/impala-go/internal/hive"
+	"github.com/sclgo/impala-go/internal/isql"
+)
+
+// Operation is a query on the server, reconstructed with OpenOperation from a handle, which Detacher returned.
+// Its results can be fetched with Rows, or it can be closed without fetching them with Close.
+// Operation is not safe for concurrent use.
+type Operation struct {
+	conn ConnRawAccess
+	op   *hive.Operation
+}
+
+// OpenOperation returns the query identified by a handle from Detacher, after checking that the server still
+// has it. If the server doesn't have the query anymore, e.g. because it was closed, it expired, or its session
+// was closed, OpenOperation fails with ErrOperationNotFound. Malformed handles fail with ErrInvalidHandle.
+// Only the connection, which detached the query, can open it - see Detacher.
+// *sql.Conn implements ConnRawAccess.
+func OpenOperation(ctx context.Context, conn ConnRawAccess, handle string) (*Operation, error) {
+	var op *hive.Operation
+	err := conn.Raw(func(driverConn any) error {
+		impalaConn, ok := driverConn.(*isql.Conn)
+		if !ok {
+			return errors.New("operations can be opened only with Impala drivers")
+		}
+		var err error
+		op, err = impalaConn.OpenOperation(ctx, handle)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Operation{conn: conn, op: op}, nil
+}
+
+// Handle returns the handle of the query, which OpenOperation accepts
+func (o *Operation) Handle() string {
+	return o.op.Handle()
+}
+
+// Rows returns rows, which continue fetching the results of the query. Closing the rows closes the query.
+func (o *Operation) Rows(ctx context.Context) (*AttachedRows, error) {
+	var rows driver.Rows
+	err := o.conn.Raw(func(driverConn any) error {
+		var err error
+		rows, err = driverConn.(*isql.Conn).OperationRows(ctx, o.op)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &AttachedRows{conn: o.conn, rows: rows}, nil
+}
+
+// Close closes the query on the server without fetching its results
+func (o *Operation) Close(ctx context.Context) error {
+	return o.conn.Raw(func(driverConn any) error {
+		return driverConn.(*isql.Conn).CloseOperation(ctx, o.op)
+	})
+}
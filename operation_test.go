@@ -0,0 +1,67 @@
+package impala_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/sclgo/impala-go"
+	"github.com/sclgo/impala-go/impalatest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenOperation(t *testing.T) {
+	srv, db := impalatest.OpenDB(t)
+	require.NoError(t, srv.AddResult("SELECT id FROM t", impalatest.Result{
+		Columns: []impalatest.Column{{Name: "id", Type: "BIGINT"}},
+		Rows:    [][]any{{1}},
+	}))
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, conn.Close())
+	}()
+
+	detach := func(t *testing.T) string {
+		var handle string
+		err := conn.Raw(func(driverConn any) error {
+			rows, err := driverConn.(driver.QueryerContext).QueryContext(ctx, "SELECT id FROM t", nil)
+			if err != nil {
+				return err
+			}
+			handle = rows.(impala.Detacher).Detach()
+			return rows.Close()
+		})
+		require.NoError(t, err)
+		return handle
+	}
+
+	t.Run("rows", func(t *testing.T) {
+		op, err := impala.OpenOperation(ctx, conn, detach(t))
+		require.NoError(t, err)
+		require.NoError(t, conn.PingContext(ctx), "the connection is idle until Rows is called")
+		rows, err := op.Rows(ctx)
+		require.NoError(t, err)
+		dest := make([]driver.Value, 1)
+		require.NoError(t, rows.Next(dest))
+		require.Equal(t, int64(1), dest[0])
+		require.NoError(t, rows.Close())
+	})
+
+	t.Run("close without fetching", func(t *testing.T) {
+		handle := detach(t)
+		op, err := impala.OpenOperation(ctx, conn, handle)
+		require.NoError(t, err)
+		require.Equal(t, handle, op.Handle())
+		require.NoError(t, op.Close(ctx))
+
+		_, err = impala.OpenOperation(ctx, conn, handle)
+		require.ErrorIs(t, err, impala.ErrOperationNotFound)
+	})
+
+	t.Run("raw conn is not impala", func(t *testing.T) {
+		_, err := impala.OpenOperation(ctx, myConn{1}, "00:00")
+		require.Error(t, err)
+	})
+}
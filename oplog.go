@@ -0,0 +1,27 @@
+package impala
+
+import (
+	"errors"
+
+	"github.com/sclgo/impala-go/internal/isql"
+)
+
+// LastStatementLog returns the log, which Impala reported for the last statement executed on conn, after
+// ExecContext returns or the rows of QueryContext are closed. The log of successful statements may contain
+// warnings, like missing table statistics, which are worth showing to users. The log is cleared when the next
+// statement starts and is truncated to 64 KiB. It fails if Options.StatementLog is not enabled.
+// *sql.Conn implements ConnRawAccess.
+func LastStatementLog(conn ConnRawAccess) (string, error) {
+	var res string
+	err := conn.Raw(func(driverConn any) error {
+		impalaConn, ok := driverConn.(*isql.Conn)
+		if !ok {
+			return errors.New("statement log can be retrieved only for Impala drivers")
+		}
+		if res, ok = impalaConn.LastLog(); !ok {
+			return errors.New("no statement log: enable the statement-log parameter")
+		}
+		return nil
+	})
+	return res, err
+}
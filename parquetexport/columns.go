@@ -0,0 +1,237 @@
+package parquetexport
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// maxInt64Precision is the max DECIMAL precision, whose unscaled values fit in INT64
+const maxInt64Precision = 18
+
+// column is a result column and its Parquet representation
+type column struct {
+	name     string
+	typeName string
+	// precision and scale apply to DECIMAL columns
+	precision int
+	scale     int
+	// index is the position of the column in the schema and in each row
+	index int
+}
+
+func newColumn(index int, name string, typeName string, precision int, scale int) column {
+	return column{index: index, name: name, typeName: typeName, precision: precision, scale: scale}
+}
+
+// node returns the Parquet schema node of the column. All columns are optional, since Impala columns are nullable.
+func (c column) node() parquet.Node {
+	var node parquet.Node
+	switch c.typeName {
+	case "BOOLEAN":
+		node = parquet.Leaf(parquet.BooleanType)
+	case "TINYINT":
+		node = parquet.Int(8)
+	case "SMALLINT":
+		node = parquet.Int(16)
+	case "INT":
+		node = parquet.Int(32)
+	case "BIGINT":
+		node = parquet.Int(64)
+	case "FLOAT":
+		node = parquet.Leaf(parquet.FloatType)
+	case "DOUBLE":
+		node = parquet.Leaf(parquet.DoubleType)
+	case "DECIMAL":
+		if c.precision <= maxInt64Precision {
+			node = parquet.Decimal(c.scale, c.precision, parquet.Int64Type)
+		} else {
+			node = parquet.Decimal(c.scale, c.precision, parquet.FixedLenByteArrayType(decimalBytes(c.precision)))
+		}
+	case "TIMESTAMP":
+		// Impala TIMESTAMP values have no time zone, so they are not adjusted to UTC
+		node = parquet.TimestampAdjusted(parquet.Microsecond, false)
+	case "DATE":
+		node = parquet.Date()
+	case "BINARY":
+		node = parquet.Leaf(parquet.ByteArrayType)
+	default:
+		// STRING, CHAR, VARCHAR, and the JSON text of ARRAY, MAP, and STRUCT values
+		node = parquet.String()
+	}
+	return parquet.Optional(node)
+}
+
+// value converts v, as scanned into an any, to a Parquet value
+func (c column) value(v any) (parquet.Value, error) {
+	if v == nil {
+		return parquet.NullValue().Level(0, 0, c.index), nil
+	}
+	res, err := c.nonNullValue(v)
+	if err != nil {
+		return parquet.Value{}, err
+	}
+	return res.Level(0, 1, c.index), nil
+}
+
+func (c column) nonNullValue(v any) (parquet.Value, error) {
+	switch c.typeName {
+	case "BOOLEAN":
+		b, ok := v.(bool)
+		if !ok {
+			return parquet.Value{}, unexpected(v)
+		}
+		return parquet.BooleanValue(b), nil
+	case "TINYINT", "SMALLINT", "INT":
+		rv := reflect.ValueOf(v)
+		if !rv.CanInt() {
+			return parquet.Value{}, unexpected(v)
+		}
+		return parquet.Int32Value(int32(rv.Int())), nil
+	case "BIGINT":
+		rv := reflect.ValueOf(v)
+		if !rv.CanInt() {
+			return parquet.Value{}, unexpected(v)
+		}
+		return parquet.Int64Value(rv.Int()), nil
+	case "FLOAT", "DOUBLE":
+		rv := reflect.ValueOf(v)
+		if !rv.CanFloat() {
+			return parquet.Value{}, unexpected(v)
+		}
+		if c.typeName == "FLOAT" {
+			return parquet.FloatValue(float32(rv.Float())), nil
+		}
+		return parquet.DoubleValue(rv.Float()), nil
+	case "DECIMAL":
+		unscaled, err := c.unscaled(v)
+		if err != nil {
+			return parquet.Value{}, err
+		}
+		if c.precision <= maxInt64Precision {
+			return parquet.Int64Value(unscaled.Int64()), nil
+		}
+		return parquet.FixedLenByteArrayValue(twosComplement(unscaled, decimalBytes(c.precision))), nil
+	case "TIMESTAMP":
+		t, ok := v.(time.Time)
+		if !ok {
+			return parquet.Value{}, unexpected(v)
+		}
+		return parquet.Int64Value(t.UnixMicro()), nil
+	case "DATE":
+		t, err := dateValue(v)
+		if err != nil {
+			return parquet.Value{}, err
+		}
+		return parquet.Int32Value(int32(t.Unix() / (24 * 60 * 60))), nil
+	}
+	switch v := v.(type) {
+	case string:
+		return parquet.ByteArrayValue([]byte(v)), nil
+	case []byte:
+		return parquet.ByteArrayValue(v), nil
+	default:
+		return parquet.Value{}, unexpected(v)
+	}
+}
+
+// unscaled returns the DECIMAL value v multiplied by 10^scale. v may be a string, float64, or *big.Rat,
+// depending on the decimal-as DSN option.
+func (c column) unscaled(v any) (*big.Int, error) {
+	var r *big.Rat
+	switch v := v.(type) {
+	case string:
+		var ok bool
+		if r, ok = new(big.Rat).SetString(v); !ok {
+			return nil, fmt.Errorf("invalid DECIMAL value %q", v)
+		}
+	case float64:
+		r = new(big.Rat).SetFloat64(v)
+		if r == nil {
+			return nil, fmt.Errorf("invalid DECIMAL value %v", v)
+		}
+	case *big.Rat:
+		r = v
+	default:
+		return nil, unexpected(v)
+	}
+	factor := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(c.scale)), nil)
+	scaled := new(big.Rat).Mul(r, new(big.Rat).SetInt(factor))
+	// FloatString rounds halves away from zero, e.g. for float64 values, which aren't exact
+	res, _ := new(big.Int).SetString(scaled.FloatString(0), 10)
+	return res, nil
+}
+
+// decimalBytes returns the minimum number of bytes, which hold the unscaled values of a DECIMAL with the given
+// precision in two's complement, as required for FIXED_LEN_BYTE_ARRAY decimals by the Parquet spec
+func decimalBytes(precision int) int {
+	limit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(precision)), nil)
+	n := 1
+	for new(big.Int).Lsh(big.NewInt(1), uint(8*n-1)).Cmp(limit) < 0 {
+		n++
+	}
+	return n
+}
+
+// twosComplement returns x as a big-endian two's complement number of n bytes
+func twosComplement(x *big.Int, n int) []byte {
+	if x.Sign() < 0 {
+		x = new(big.Int).Add(x, new(big.Int).Lsh(big.NewInt(1), uint(8*n)))
+	}
+	return x.FillBytes(make([]byte, n))
+}
+
+// dateValue returns a DATE value as time.Time. DATE values are strings, or time.Time
+// with the date-as=time DSN option.
+func dateValue(v any) (time.Time, error) {
+	switch v := v.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		return time.Parse(time.DateOnly, v)
+	default:
+		return time.Time{}, unexpected(v)
+	}
+}
+
+func unexpected(v any) error {
+	return fmt.Errorf("unexpected value type %T", v)
+}
+
+// newGroup returns the root node of the schema. Unlike parquet.Group, which sorts fields by name, it keeps
+// the columns in the order of the result, since Impala matches Parquet columns by position by default.
+func newGroup(cols []column) parquet.Node {
+	group := make(parquet.Group, len(cols))
+	fields := make([]parquet.Field, len(cols))
+	for i, col := range cols {
+		group[col.name] = col.node()
+		fields[i] = field{Node: group[col.name], name: col.name}
+	}
+	return orderedGroup{Group: group, fields: fields}
+}
+
+type orderedGroup struct {
+	parquet.Group
+	fields []parquet.Field
+}
+
+func (g orderedGroup) Fields() []parquet.Field {
+	return g.fields
+}
+
+type field struct {
+	parquet.Node
+	name string
+}
+
+func (f field) Name() string {
+	return f.name
+}
+
+// Value is required by parquet.Field for writing Go values with reflection, which write doesn't use
+func (f field) Value(reflect.Value) reflect.Value {
+	return reflect.Value{}
+}
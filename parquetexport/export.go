@@ -0,0 +1,148 @@
+// Package parquetexport writes the results of Impala queries to Parquet files, e.g. for re-ingestion.
+//
+// It is a separate module so that the driver itself doesn't depend on a Parquet library.
+// The module adds github.com/parquet-go/parquet-go and its compression libraries to the dependencies of the importer.
+//
+// The package doesn't import the driver. It accepts the values of all driver versions and DSN options, which change
+// the Go type of values, e.g. DATE values as strings or as time.Time with date-as=time.
+package parquetexport
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// DefaultRowGroupRows is the default number of rows written per row group
+const DefaultRowGroupRows = 64 * 1024
+
+// writeBatchRows is the number of rows passed to the Parquet writer at once
+const writeBatchRows = 1024
+
+// Queryer runs queries. *sql.DB, *sql.Conn, and *sql.Tx implement it.
+type Queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// Options configures the export
+type Options struct {
+	// RowGroupRows is the number of rows buffered before they are written as a row group.
+	// 0 or negative means DefaultRowGroupRows.
+	RowGroupRows int
+}
+
+// WriteFile runs query with q and writes its result to a new Parquet file at path, overwriting any existing file.
+// It returns the number of rows written. If writing fails, the incomplete file is removed.
+func WriteFile(ctx context.Context, q Queryer, query string, path string, opts Options) (int64, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	n, err := Write(ctx, q, query, f, opts)
+	err = errors.Join(err, f.Close())
+	if err != nil {
+		_ = os.Remove(path)
+		return 0, err
+	}
+	return n, nil
+}
+
+// Write runs query with q and writes its result to w in Parquet format. It returns the number of rows written.
+// The Parquet schema is built from the result columns, in the same order, and all columns are optional.
+// See the README for the mapping of Impala types. Rows are written in row groups of Options.RowGroupRows rows,
+// so memory use doesn't grow with the size of the result. Pages are compressed with Snappy.
+func Write(ctx context.Context, q Queryer, query string, w io.Writer, opts Options) (int64, error) {
+	rows, err := q.QueryContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	n, err := write(ctx, rows, w, opts)
+	return n, errors.Join(err, rows.Close())
+}
+
+func write(ctx context.Context, rows *sql.Rows, w io.Writer, opts Options) (int64, error) {
+	colTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return 0, err
+	}
+	cols, err := newColumns(colTypes)
+	if err != nil {
+		return 0, err
+	}
+	schema := parquet.NewSchema("impala", newGroup(cols))
+	writer := parquet.NewWriter(w, schema, parquet.Compression(&parquet.Snappy))
+
+	groupRows := opts.RowGroupRows
+	if groupRows <= 0 {
+		groupRows = DefaultRowGroupRows
+	}
+	values := make([]any, len(cols))
+	dest := make([]any, len(cols))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	batch := make([]parquet.Row, 0, min(groupRows, writeBatchRows))
+	var n int64
+	for rows.Next() {
+		if err = rows.Scan(dest...); err != nil {
+			return n, err
+		}
+		row := make(parquet.Row, len(cols))
+		for i, col := range cols {
+			if row[i], err = col.value(values[i]); err != nil {
+				return n, fmt.Errorf("column %s: %w", col.name, err)
+			}
+		}
+		batch = append(batch, row)
+		n++
+		endOfGroup := n%int64(groupRows) == 0
+		if len(batch) < cap(batch) && !endOfGroup {
+			continue
+		}
+		// rows.Next checks ctx only when it fetches the next batch
+		if err = ctx.Err(); err != nil {
+			return n, err
+		}
+		if _, err = writer.WriteRows(batch); err != nil {
+			return n, err
+		}
+		batch = batch[:0]
+		if endOfGroup {
+			// Flush completes the current row group
+			if err = writer.Flush(); err != nil {
+				return n, err
+			}
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return n, err
+	}
+	if _, err = writer.WriteRows(batch); err != nil {
+		return n, err
+	}
+	return n, writer.Close()
+}
+
+// newColumns maps the result columns to Parquet columns
+func newColumns(colTypes []*sql.ColumnType) ([]column, error) {
+	cols := make([]column, len(colTypes))
+	names := make(map[string]bool, len(colTypes))
+	for i, ct := range colTypes {
+		name := ct.Name()
+		if names[name] {
+			return nil, fmt.Errorf("duplicate column name %q: Parquet requires unique names - use column aliases", name)
+		}
+		names[name] = true
+		// with the type-names=sql DSN option, type names include parameters e.g. DECIMAL(10,2)
+		typeName, _, _ := strings.Cut(ct.DatabaseTypeName(), "(")
+		precision, scale, _ := ct.DecimalSize()
+		cols[i] = newColumn(i, name, typeName, int(precision), int(scale))
+	}
+	return cols, nil
+}
@@ -0,0 +1,138 @@
+package parquetexport
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	_ "github.com/sclgo/impala-go"
+	"github.com/sclgo/impala-go/impalatest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteFile(t *testing.T) {
+	srv := impalatest.Start(t)
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 123456000, time.UTC)
+	require.NoError(t, srv.AddResult("SELECT * FROM t", impalatest.Result{
+		Columns: []impalatest.Column{
+			{Name: "name", Type: "STRING"},
+			{Name: "id", Type: "BIGINT"},
+			{Name: "small", Type: "SMALLINT"},
+			{Name: "score", Type: "DOUBLE"},
+			{Name: "ok", Type: "BOOLEAN"},
+			{Name: "ts", Type: "TIMESTAMP"},
+			{Name: "day", Type: "DATE"},
+		},
+		Rows: [][]any{
+			{"a", 1, 7, 1.5, true, ts, "2024-01-02"},
+			{nil, 2, nil, nil, false, nil, nil},
+			{"c", 3, -1, 0.25, nil, ts, "1969-12-31"},
+		},
+	}))
+	db := srv.OpenDB(t)
+
+	path := filepath.Join(t.TempDir(), "t.parquet")
+	n, err := WriteFile(context.Background(), db, "SELECT * FROM t", path, Options{RowGroupRows: 2})
+	require.NoError(t, err)
+	require.EqualValues(t, 3, n)
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, f.Close())
+	}()
+	stat, err := f.Stat()
+	require.NoError(t, err)
+	file, err := parquet.OpenFile(f, stat.Size())
+	require.NoError(t, err)
+
+	// columns keep the order of the result
+	require.Equal(t, [][]string{{"name"}, {"id"}, {"small"}, {"score"}, {"ok"}, {"ts"}, {"day"}}, file.Schema().Columns())
+	require.Len(t, file.RowGroups(), 2)
+	require.EqualValues(t, 3, file.NumRows())
+
+	rows := make([]parquet.Row, 3)
+	reader := parquet.NewReader(file)
+	read, err := reader.ReadRows(rows)
+	if !errors.Is(err, io.EOF) {
+		require.NoError(t, err)
+	}
+	require.Equal(t, 3, read)
+	require.NoError(t, reader.Close())
+
+	require.Equal(t, "a", rows[0][0].String())
+	require.Equal(t, int64(1), rows[0][1].Int64())
+	require.Equal(t, int32(7), rows[0][2].Int32())
+	require.Equal(t, 1.5, rows[0][3].Double())
+	require.True(t, rows[0][4].Boolean())
+	require.Equal(t, ts.UnixMicro(), rows[0][5].Int64())
+	require.Equal(t, int32(19724), rows[0][6].Int32())
+	for i, v := range rows[1] {
+		require.Equal(t, i == 1 || i == 4, !v.IsNull(), "column %d", i)
+	}
+	require.True(t, rows[2][4].IsNull())
+	require.Equal(t, int32(-1), rows[2][6].Int32())
+}
+
+func TestWriteFile_Errors(t *testing.T) {
+	srv := impalatest.Start(t)
+	require.NoError(t, srv.AddResult("SELECT 1 a, 2 a", impalatest.Result{
+		Columns: []impalatest.Column{{Name: "a", Type: "INT"}, {Name: "a", Type: "INT"}},
+	}))
+	require.NoError(t, srv.AddResult("SELECT * FROM missing", impalatest.Result{Err: "AnalysisException: missing"}))
+	db := srv.OpenDB(t)
+
+	path := filepath.Join(t.TempDir(), "t.parquet")
+	_, err := WriteFile(context.Background(), db, "SELECT 1 a, 2 a", path, Options{})
+	require.ErrorContains(t, err, "duplicate column name")
+	require.NoFileExists(t, path)
+
+	_, err = WriteFile(context.Background(), db, "SELECT * FROM missing", path, Options{})
+	require.ErrorContains(t, err, "AnalysisException")
+	require.NoFileExists(t, path)
+}
+
+func TestColumn_Decimal(t *testing.T) {
+	small := newColumn(0, "d", "DECIMAL", 10, 2)
+	for _, v := range []any{"-12.34", -12.34, big.NewRat(-1234, 100)} {
+		value, err := small.value(v)
+		require.NoError(t, err)
+		require.Equal(t, int64(-1234), value.Int64(), "%T", v)
+	}
+
+	wide := newColumn(0, "d", "DECIMAL", 38, 0)
+	value, err := wide.value("-1")
+	require.NoError(t, err)
+	require.Equal(t, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		value.ByteArray())
+
+	_, err = small.value("abc")
+	require.ErrorContains(t, err, "invalid DECIMAL value")
+}
+
+func TestColumn_Date(t *testing.T) {
+	day := newColumn(0, "day", "DATE", 0, 0)
+	// DATE values are strings by default and time.Time with date-as=time
+	for _, v := range []any{"2024-01-02", time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)} {
+		value, err := day.value(v)
+		require.NoError(t, err)
+		require.Equal(t, int32(19724), value.Int32(), "%T", v)
+	}
+
+	_, err := day.value(int64(1))
+	require.ErrorContains(t, err, "unexpected value type int64")
+}
+
+func TestDecimalBytes(t *testing.T) {
+	require.Equal(t, 1, decimalBytes(2))
+	require.Equal(t, 2, decimalBytes(3))
+	require.Equal(t, 8, decimalBytes(18))
+	require.Equal(t, 9, decimalBytes(19))
+	require.Equal(t, 16, decimalBytes(38))
+}
@@ -0,0 +1,33 @@
+module github.com/sclgo/impala-go/parquetexport
+
+go 1.25.11
+
+require (
+	github.com/parquet-go/parquet-go v0.26.4
+	github.com/sclgo/impala-go v1.6.0
+	github.com/stretchr/testify v1.11.1
+)
+
+require (
+	github.com/andybalholm/brotli v1.1.1 // indirect
+	github.com/apache/thrift v0.23.0 // indirect
+	github.com/davecgh/go-spew v1.1.2-0.20180830191138-d8f796af33cc // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/klauspost/compress v1.17.9 // indirect
+	github.com/kr/text v0.2.0 // indirect
+	github.com/murfffi/conncheck v0.2.1 // indirect
+	github.com/murfffi/gorich v0.3.0 // indirect
+	github.com/parquet-go/bitpack v1.0.0 // indirect
+	github.com/parquet-go/jsonlite v1.0.0 // indirect
+	github.com/pierrec/lz4/v4 v4.1.21 // indirect
+	github.com/pmezard/go-difflib v1.0.1-0.20181226105442-5d4384ee4fb2 // indirect
+	github.com/samber/lo v1.53.0 // indirect
+	golang.org/x/sys v0.45.0 // indirect
+	golang.org/x/text v0.37.0 // indirect
+	google.golang.org/protobuf v1.34.2 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+// The driver is only a test dependency - the tests use impalatest from this repository.
+// The package itself doesn't import the driver, so its users don't depend on this replace.
+replace github.com/sclgo/impala-go v1.6.0 => ../
@@ -0,0 +1,26 @@
+package impala
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sclgo/impala-go/internal/isql"
+)
+
+// PingWithLatency checks the connection to the server, like sql.Conn.PingContext, and returns
+// the round-trip latency of the check. The check is a lightweight metadata call, not a query.
+// *sql.Conn implements ConnRawAccess.
+func PingWithLatency(ctx context.Context, conn ConnRawAccess) (time.Duration, error) {
+	var latency time.Duration
+	err := conn.Raw(func(driverConn any) error {
+		impalaConn, ok := driverConn.(*isql.Conn)
+		if !ok {
+			return errors.New("ping with latency can operate only on Impala drivers")
+		}
+		var pingErr error
+		latency, pingErr = impalaConn.PingWithLatency(ctx)
+		return pingErr
+	})
+	return latency, err
+}
@@ -0,0 +1,18 @@
+package impala_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sclgo/impala-go"
+	"github.com/stretchr/testify/require"
+)
+
+// Integration tests for PingWithLatency are in connection_test.go
+
+func TestPingWithLatency(t *testing.T) {
+	t.Run("raw conn is not impala", func(t *testing.T) {
+		_, err := impala.PingWithLatency(context.Background(), myConn{1})
+		require.Error(t, err)
+	})
+}
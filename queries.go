@@ -0,0 +1,104 @@
+package impala
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/sclgo/impala-go/internal/isql"
+)
+
+// RunningQuery describes a query registered on the coordinator, as reported by the sys.impala_query_live table
+type RunningQuery struct {
+	// QueryID is the id of the query, as shown in the Impala Web UI
+	QueryID string
+	// User is the effective user of the query
+	User string
+	// State is the query state e.g. RUNNING or FINISHED
+	State string
+	// Statement is the SQL text of the query
+	Statement string
+	// StartTime is the time when the query was submitted, in UTC
+	StartTime time.Time
+	// Duration is the time the query has taken so far
+	Duration time.Duration
+}
+
+// runningQueriesStmt lists queries from the system table available in Impala 4.4 and later
+// https://impala.apache.org/docs/build/html/topics/impala_workload_management.html
+const runningQueriesStmt = "SELECT query_id, db_user, query_state, sql, start_time_utc, CAST(total_time_ms AS BIGINT) " +
+	"FROM sys.impala_query_live ORDER BY start_time_utc"
+
+// ListRunningQueries returns the queries, which the coordinator tracks in the sys.impala_query_live table.
+// The list may include queries, which completed recently, with their final state.
+// The table is available in Impala 4.4 and later, when workload management is enabled on the coordinator.
+// On other servers, ListRunningQueries fails with an error that matches ErrNotSupported.
+// The results depend on the permissions of the user on the table. *sql.Conn implements ConnRawAccess.
+func ListRunningQueries(ctx context.Context, conn ConnRawAccess) ([]RunningQuery, error) {
+	var res []RunningQuery
+	err := conn.Raw(func(driverConn any) error {
+		impalaConn, ok := driverConn.(*isql.Conn)
+		if !ok {
+			return errors.New("running queries can be listed only with Impala drivers")
+		}
+		rows, err := impalaConn.QueryContext(ctx, runningQueriesStmt, nil)
+		if errors.Is(err, ErrObjectNotFound) {
+			return fmt.Errorf("%w: the server doesn't provide sys.impala_query_live: %w", ErrNotSupported, err)
+		}
+		if err != nil {
+			return err
+		}
+		if n := len(rows.Columns()); n != runningQueriesColumns {
+			return errors.Join(
+				fmt.Errorf("impala: sys.impala_query_live query returned %d columns, expected %d", n, runningQueriesColumns),
+				rows.Close(),
+			)
+		}
+		dest := make([]driver.Value, runningQueriesColumns)
+		for err = rows.Next(dest); err == nil; err = rows.Next(dest) {
+			var q RunningQuery
+			if q, err = readRunningQuery(dest); err != nil {
+				break
+			}
+			res = append(res, q)
+		}
+		closeErr := rows.Close()
+		if !errors.Is(err, io.EOF) {
+			return err
+		}
+		return closeErr
+	})
+	return res, err
+}
+
+// runningQueriesColumns is the number of columns in runningQueriesStmt
+const runningQueriesColumns = 6
+
+// readRunningQuery reads a row of runningQueriesStmt. NULL values are read as zero values. Values of
+// unexpected types, e.g. due to Options.ValueConverters, fail.
+func readRunningQuery(row []driver.Value) (q RunningQuery, err error) {
+	var ms int64
+	fields := []any{&q.QueryID, &q.User, &q.State, &q.Statement, &q.StartTime, &ms}
+	for i, v := range row {
+		if v == nil {
+			continue
+		}
+		var ok bool
+		switch f := fields[i].(type) {
+		case *string:
+			*f, ok = v.(string)
+		case *time.Time:
+			*f, ok = v.(time.Time)
+		case *int64:
+			*f, ok = v.(int64)
+		}
+		if !ok {
+			return q, fmt.Errorf("impala: unexpected value of type %T in column %d of sys.impala_query_live query", v, i+1)
+		}
+	}
+	q.Duration = time.Duration(ms) * time.Millisecond
+	return q, nil
+}
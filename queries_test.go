@@ -0,0 +1,85 @@
+package impala
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/sclgo/impala-go/impalatest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListRunningQueries(t *testing.T) {
+	ctx := context.Background()
+	open := func(t *testing.T, res impalatest.Result) *sql.Conn {
+		srv := impalatest.Start(t)
+		require.NoError(t, srv.AddResult(runningQueriesStmt, res))
+		db := srv.OpenDB(t)
+		conn, err := db.Conn(ctx)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, conn.Close())
+		})
+		return conn
+	}
+
+	t.Run("queries", func(t *testing.T) {
+		start := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+		conn := open(t, impalatest.Result{
+			Columns: []impalatest.Column{
+				{Name: "query_id", Type: "STRING"},
+				{Name: "db_user", Type: "STRING"},
+				{Name: "query_state", Type: "STRING"},
+				{Name: "sql", Type: "STRING"},
+				{Name: "start_time_utc", Type: "TIMESTAMP"},
+				{Name: "total_time_ms", Type: "BIGINT"},
+			},
+			Rows: [][]any{
+				{"a1:b2", "alice", "RUNNING", "SELECT 1", start, 1500},
+				{"c3:d4", nil, "CREATED", "SELECT 2", nil, nil},
+			},
+		})
+		queries, err := ListRunningQueries(ctx, conn)
+		require.NoError(t, err)
+		require.Equal(t, []RunningQuery{
+			{QueryID: "a1:b2", User: "alice", State: "RUNNING", Statement: "SELECT 1", StartTime: start, Duration: 1500 * time.Millisecond},
+			{QueryID: "c3:d4", State: "CREATED", Statement: "SELECT 2"},
+		}, queries)
+	})
+
+	t.Run("unexpected columns", func(t *testing.T) {
+		conn := open(t, impalatest.Result{
+			Columns: []impalatest.Column{{Name: "query_id", Type: "STRING"}},
+			Rows:    [][]any{{"a1:b2"}},
+		})
+		_, err := ListRunningQueries(ctx, conn)
+		require.ErrorContains(t, err, "returned 1 columns, expected 6")
+		require.NoError(t, conn.PingContext(ctx))
+	})
+
+	t.Run("unexpected types", func(t *testing.T) {
+		conn := open(t, impalatest.Result{
+			Columns: []impalatest.Column{
+				{Name: "query_id", Type: "STRING"},
+				{Name: "db_user", Type: "STRING"},
+				{Name: "query_state", Type: "STRING"},
+				{Name: "sql", Type: "STRING"},
+				{Name: "start_time_utc", Type: "STRING"},
+				{Name: "total_time_ms", Type: "BIGINT"},
+			},
+			Rows: [][]any{{"a1:b2", "alice", "RUNNING", "SELECT 1", "yesterday", 1500}},
+		})
+		_, err := ListRunningQueries(ctx, conn)
+		require.ErrorContains(t, err, "unexpected value of type string in column 5")
+	})
+
+	t.Run("table missing", func(t *testing.T) {
+		conn := open(t, impalatest.Result{
+			Err: "AnalysisException: Could not resolve table reference: 'sys.impala_query_live'",
+		})
+		_, err := ListRunningQueries(ctx, conn)
+		require.ErrorIs(t, err, ErrNotSupported)
+		require.ErrorIs(t, err, ErrObjectNotFound)
+	})
+}
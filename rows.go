@@ -0,0 +1,343 @@
+package impala
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/sclgo/impala-go/internal/isql"
+)
+
+// RowsFetchedCounter is implemented by the driver.Rows returned by this driver.
+//
+// database/sql doesn't expose the driver.Rows behind sql.Rows so using RowsFetchedCounter requires
+// calling the driver directly, using sql.Conn.Raw:
+//
+//	err := conn.Raw(func(driverConn any) error {
+//		rows, err := driverConn.(driver.QueryerContext).QueryContext(ctx, query, nil)
+//		if err != nil {
+//			return err
+//		}
+//		defer rows.Close()
+//		counter := rows.(impala.RowsFetchedCounter)
+//		// call rows.Next and counter.RowsFetched() ...
+//	})
+type RowsFetchedCounter interface {
+	// RowsFetched returns the number of rows returned by Next so far
+	RowsFetched() int64
+}
+
+var _ RowsFetchedCounter = (*isql.Rows)(nil)
+
+// BatchNullsReporter is implemented by the driver.Rows returned by this driver. It is used like RowsFetchedCounter.
+//
+// Impala returns results in batches of up to Options.BatchSize rows, with a null bitmap per column.
+// BatchNullsReporter exposes a summary of these bitmaps, so that callers, processing rows in bulk, can skip
+// NULL checks for columns without NULLs in the current batch. It doesn't require scanning the values.
+type BatchNullsReporter interface {
+	// BatchHasNulls returns, for each column, whether the batch that contains the row returned by the last call
+	// to Next has any NULL values. The result is nil before the first call to Next.
+	BatchHasNulls() []bool
+}
+
+var _ BatchNullsReporter = (*isql.Rows)(nil)
+
+// Rewinder is implemented by the driver.Rows returned by this driver. It is used like RowsFetchedCounter.
+//
+// Rewind restarts the rows from the first row, e.g. for a UI grid, which jumps back to the start, without running
+// the query again. Impala supports this only if the result cache is enabled with Options.ResultCacheSize and the
+// result fits in it. Otherwise, Rewind or the next call to Next fail with ErrRewindNotSupported.
+type Rewinder interface {
+	// Rewind restarts the rows so that the next call to Next returns the first row
+	Rewind() error
+}
+
+var _ Rewinder = (*isql.Rows)(nil)
+
+// Detacher is implemented by the driver.Rows returned by this driver. It is used like RowsFetchedCounter.
+//
+// Detach makes Rows.Close leave the query open on the server, so its results can be fetched later with
+// AttachOperation, using the returned handle. The handle contains the secret, which Impala requires to access
+// the query, so treat it like a credential. Rows, which the driver already fetched but Next didn't return yet,
+// are not returned after attaching, so call Detach before Next to get all rows.
+//
+// A detached query holds server resources until it is attached and closed, or until Impala discards it,
+// e.g. after the IDLE_QUERY_TIMEOUT query option expires, or when its session is closed.
+//
+// Detached queries can be attached only on the same connection, before it is closed. The driver closes the
+// session when the connection is closed, and database/sql resets pooled connections, which closes the session
+// too, unless Options.ReuseSession is enabled. So detach and attach with the same sql.Conn, which database/sql
+// doesn't reset until it is closed. Attaching from another connection or process is not supported.
+type Detacher interface {
+	// Detach returns the handle of the query and makes Close leave the query open
+	Detach() string
+}
+
+var _ Detacher = (*isql.Rows)(nil)
+
+// AttachOperation returns rows, which continue fetching the results of a query detached with Detacher on conn.
+// It is a shortcut for OpenOperation followed by Operation.Rows, and fails with the same errors as OpenOperation.
+// The rows must be closed before conn is used for other statements. Closing the rows closes the query on the server.
+// Handles are used instead of the query ids shown in the Impala Web UI, because they include the query secret,
+// which the server requires to access the query.
+// *sql.Conn implements ConnRawAccess. See Detacher for the connections, which can attach a query.
+func AttachOperation(ctx context.Context, conn ConnRawAccess, handle string) (*AttachedRows, error) {
+	op, err := OpenOperation(ctx, conn, handle)
+	if err != nil {
+		return nil, err
+	}
+	return op.Rows(ctx)
+}
+
+// AttachedRows are the rows of a query attached with AttachOperation or Operation.Rows. Unlike the driver rows, they can be used
+// outside of sql.Conn.Raw callbacks - each call uses the driver connection through ConnRawAccess.
+// AttachedRows are not safe for concurrent use.
+type AttachedRows struct {
+	conn ConnRawAccess
+	rows driver.Rows
+}
+
+// Columns returns the names of the columns
+func (r *AttachedRows) Columns() []string {
+	return r.rows.Columns()
+}
+
+// Next reads the next row into dest, which must have one element per column. Values have the same types as
+// when scanning into *any. Next returns io.EOF when there are no more rows.
+func (r *AttachedRows) Next(dest []driver.Value) error {
+	return r.conn.Raw(func(any) error {
+		return r.rows.Next(dest)
+	})
+}
+
+// Close closes the query on the server. The connection can run other statements after that.
+func (r *AttachedRows) Close() error {
+	return r.conn.Raw(func(any) error {
+		return r.rows.Close()
+	})
+}
+
+// ScanMap reads the current row into a map from column names to values. Values have the same types as when
+// scanning into *any, which depend on the column types and Options, e.g. ValueConverters.
+// NULL values are mapped to nil. ScanMap fails with ErrDuplicateColumn if column names repeat, like NewColumnIndex.
+// Call rows.Next before ScanMap, like before rows.Scan.
+func ScanMap(rows *sql.Rows) (map[string]any, error) {
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	if _, err = columnPositions(cols); err != nil {
+		return nil, err
+	}
+	values := make([]any, len(cols))
+	dest := make([]any, len(cols))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err = rows.Scan(dest...); err != nil {
+		return nil, err
+	}
+	res := make(map[string]any, len(cols))
+	for i, col := range cols {
+		res[col] = values[i]
+	}
+	return res, nil
+}
+
+// ErrDuplicateColumn means that a result has several columns with the same name, ignoring case, so they can't be
+// looked up by name. ScanMap, NewColumnIndex and ScanStruct fail with it - use aliases to make the names unique.
+var ErrDuplicateColumn = errors.New("impala: duplicate column name")
+
+// columnPositions maps the lower case names of cols to their positions. It fails with ErrDuplicateColumn
+// if names repeat.
+func columnPositions(cols []string) (map[string]int, error) {
+	positions := make(map[string]int, len(cols))
+	for i, col := range cols {
+		key := strings.ToLower(col)
+		if _, ok := positions[key]; ok {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateColumn, col)
+		}
+		positions[key] = i
+	}
+	return positions, nil
+}
+
+// ColumnIndex maps the names of the columns of a result to their positions, so rows can be scanned by column name
+// rather than position, e.g. for SELECT * queries, whose columns may change. Names are matched ignoring case,
+// since Impala reports column names in lower case.
+type ColumnIndex struct {
+	positions map[string]int
+	count     int
+}
+
+// NewColumnIndex creates a ColumnIndex for the columns of rows. It fails with ErrDuplicateColumn if
+// column names repeat, e.g. in joins - use aliases to make the names unique.
+func NewColumnIndex(rows *sql.Rows) (*ColumnIndex, error) {
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	positions, err := columnPositions(cols)
+	if err != nil {
+		return nil, err
+	}
+	return &ColumnIndex{positions: positions, count: len(cols)}, nil
+}
+
+// Index returns the position of the column with the given name, and false if there is no such column
+func (ci *ColumnIndex) Index(name string) (int, bool) {
+	i, ok := ci.positions[strings.ToLower(name)]
+	return i, ok
+}
+
+// ScanByName reads the current row of rows into dest, which maps column names to scan destinations,
+// like the arguments of rows.Scan. Columns not in dest are skipped. If a name in dest is not a column,
+// ScanByName fails without scanning. Call rows.Next before ScanByName, like before rows.Scan.
+func (ci *ColumnIndex) ScanByName(rows *sql.Rows, dest map[string]any) error {
+	args := make([]any, ci.count)
+	for name, d := range dest {
+		i, ok := ci.Index(name)
+		if !ok {
+			return fmt.Errorf("impala: no column named %s in the result", name)
+		}
+		args[i] = d
+	}
+	for i := range args {
+		if args[i] == nil {
+			args[i] = new(any)
+		}
+	}
+	return rows.Scan(args...)
+}
+
+// ScanStruct reads the current row of rows into the fields of the struct that dest points to. A column is read into
+// the field with a matching `db:"name"` tag or, if the field has no tag, the field with the same name, ignoring
+// case. Fields tagged `db:"-"` and unexported fields are skipped, and fields of embedded structs are matched like
+// the fields of dest. Values are converted to the field types like with rows.Scan, so NULL values require
+// pointer fields, which are set to nil, or types like sql.NullString. Columns without a matching field are skipped
+// and fields without a matching column are not changed. ScanStruct fails with ErrDuplicateColumn if column names
+// repeat, like NewColumnIndex. Call rows.Next before ScanStruct, like before rows.Scan.
+// The fields of each struct type are looked up once and cached.
+func ScanStruct(rows *sql.Rows, dest any) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("impala: ScanStruct requires a non-nil pointer to a struct, got %T", dest)
+	}
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	v = v.Elem()
+	fields, err := structFields(cols, v.Type())
+	if err != nil {
+		return err
+	}
+	args := make([]any, len(cols))
+	for i, index := range fields {
+		if index != nil {
+			if fv, ok := fieldByIndex(v, index); ok {
+				args[i] = fv.Addr().Interface()
+				continue
+			}
+		}
+		args[i] = new(any)
+	}
+	return rows.Scan(args...)
+}
+
+// structField is a field of a struct, which a column can be read into
+type structField struct {
+	name  string
+	index []int
+}
+
+// structFieldsCache maps struct types to the result of computeStructFields. It has an entry per struct type,
+// not per result, so it doesn't grow with the number of distinct column lists.
+var structFieldsCache sync.Map
+
+// structFields returns the index of the field of t, which each column is read into, or nil for skipped columns
+func structFields(cols []string, t reflect.Type) ([][]int, error) {
+	if _, err := columnPositions(cols); err != nil {
+		return nil, err
+	}
+	byName := fieldsByName(t)
+	fields := make([][]int, len(cols))
+	for i, col := range cols {
+		matches := byName[strings.ToLower(col)]
+		if len(matches) > 1 {
+			return nil, fmt.Errorf("impala: fields %s and %s both match column %s", matches[0].name, matches[1].name, col)
+		}
+		if len(matches) == 1 {
+			fields[i] = matches[0].index
+		}
+	}
+	return fields, nil
+}
+
+// fieldsByName returns the fields of t, which columns can be read into, by lower case column name
+func fieldsByName(t reflect.Type) map[string][]structField {
+	if fields, ok := structFieldsCache.Load(t); ok {
+		return fields.(map[string][]structField)
+	}
+	fields, _ := structFieldsCache.LoadOrStore(t, computeStructFields(t))
+	return fields.(map[string][]structField)
+}
+
+func computeStructFields(t reflect.Type) map[string][]structField {
+	res := make(map[string][]structField)
+	for _, field := range reflect.VisibleFields(t) {
+		name, ok := columnName(field)
+		if !ok {
+			continue
+		}
+		key := strings.ToLower(name)
+		res[key] = append(res[key], structField{name: field.Name, index: field.Index})
+	}
+	return res
+}
+
+// columnName returns the name of the column that field is read from, and false if the field is skipped
+func columnName(field reflect.StructField) (string, bool) {
+	tag, tagged := field.Tag.Lookup("db")
+	switch {
+	case tag == "-" || !field.IsExported():
+		return "", false
+	case tagged && tag != "":
+		return tag, true
+	case field.Anonymous && !tagged && indirect(field.Type).Kind() == reflect.Struct:
+		// the fields of embedded structs are matched instead
+		return "", false
+	default:
+		return field.Name, true
+	}
+}
+
+// fieldByIndex is like reflect.Value.FieldByIndex but allocates nil pointers to embedded structs.
+// It returns false if the field can't be set, e.g. because it is in an unexported embedded struct pointer.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, v.CanSet()
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Pointer {
+		return t.Elem()
+	}
+	return t
+}
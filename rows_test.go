@@ -0,0 +1,231 @@
+package impala
+
+import (
+	"context"
+	"database/sql/driver"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/sclgo/impala-go/impalatest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanMap(t *testing.T) {
+	srv := impalatest.Start(t)
+	require.NoError(t, srv.AddResult("SELECT id, name FROM t", impalatest.Result{
+		Columns: []impalatest.Column{{Name: "id", Type: "BIGINT"}, {Name: "name", Type: "STRING"}},
+		Rows:    [][]any{{1, "a"}, {2, nil}},
+	}))
+
+	db := srv.OpenDB(t)
+	rows, err := db.Query("SELECT id, name FROM t")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, rows.Close())
+	}()
+
+	var res []map[string]any
+	for rows.Next() {
+		row, err := ScanMap(rows)
+		require.NoError(t, err)
+		res = append(res, row)
+	}
+	require.NoError(t, rows.Err())
+	require.Equal(t, []map[string]any{
+		{"id": int64(1), "name": "a"},
+		{"id": int64(2), "name": nil},
+	}, res)
+
+	require.NoError(t, srv.AddResult("SELECT a.id, b.id FROM a JOIN b", impalatest.Result{
+		Columns: []impalatest.Column{{Name: "id", Type: "BIGINT"}, {Name: "ID", Type: "BIGINT"}},
+		Rows:    [][]any{{1, 2}},
+	}))
+	dupRows, err := db.Query("SELECT a.id, b.id FROM a JOIN b")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, dupRows.Close())
+	}()
+	require.True(t, dupRows.Next())
+	_, err = ScanMap(dupRows)
+	require.ErrorIs(t, err, ErrDuplicateColumn)
+}
+
+func TestColumnIndex(t *testing.T) {
+	srv := impalatest.Start(t)
+	require.NoError(t, srv.AddResult("SELECT * FROM t", impalatest.Result{
+		Columns: []impalatest.Column{{Name: "id", Type: "BIGINT"}, {Name: "name", Type: "STRING"}, {Name: "note", Type: "STRING"}},
+		Rows:    [][]any{{1, "a", "x"}},
+	}))
+	require.NoError(t, srv.AddResult("SELECT a.id, b.id FROM a JOIN b", impalatest.Result{
+		Columns: []impalatest.Column{{Name: "id", Type: "BIGINT"}, {Name: "id", Type: "BIGINT"}},
+	}))
+
+	db := srv.OpenDB(t)
+
+	rows, err := db.Query("SELECT * FROM t")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, rows.Close())
+	}()
+	ci, err := NewColumnIndex(rows)
+	require.NoError(t, err)
+	i, ok := ci.Index("NAME")
+	require.True(t, ok)
+	require.Equal(t, 1, i)
+	_, ok = ci.Index("missing")
+	require.False(t, ok)
+
+	require.True(t, rows.Next())
+	var name string
+	var id int64
+	require.ErrorContains(t, ci.ScanByName(rows, map[string]any{"missing": &name}), "no column named missing")
+	require.NoError(t, ci.ScanByName(rows, map[string]any{"name": &name, "ID": &id}))
+	require.Equal(t, "a", name)
+	require.Equal(t, int64(1), id)
+
+	dupRows, err := db.Query("SELECT a.id, b.id FROM a JOIN b")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, dupRows.Close())
+	}()
+	_, err = NewColumnIndex(dupRows)
+	require.ErrorIs(t, err, ErrDuplicateColumn)
+}
+
+// Audit is exported, as ScanStruct can't allocate pointers to unexported embedded structs
+type Audit struct {
+	CreatedBy *string `db:"created_by"`
+}
+
+type user struct {
+	ID      int64  `db:"id"`
+	Name    string // matched by field name
+	Email   *string
+	Ignored string `db:"-"`
+	*Audit
+	internal string
+}
+
+func TestScanStruct(t *testing.T) {
+	srv := impalatest.Start(t)
+	require.NoError(t, srv.AddResult("SELECT * FROM users", impalatest.Result{
+		Columns: []impalatest.Column{
+			{Name: "id", Type: "BIGINT"}, {Name: "name", Type: "STRING"}, {Name: "email", Type: "STRING"},
+			{Name: "created_by", Type: "STRING"}, {Name: "ignored", Type: "STRING"}, {Name: "extra", Type: "STRING"},
+		},
+		Rows: [][]any{{1, "a", "a@example.com", "admin", "x", "y"}, {2, "b", nil, nil, "x", "y"}},
+	}))
+
+	db := srv.OpenDB(t)
+
+	rows, err := db.Query("SELECT * FROM users")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, rows.Close())
+	}()
+	var users []user
+	for rows.Next() {
+		u := user{Ignored: "kept", internal: "kept"}
+		require.NoError(t, ScanStruct(rows, &u))
+		users = append(users, u)
+	}
+	require.NoError(t, rows.Err())
+	require.Len(t, users, 2)
+
+	require.Equal(t, int64(1), users[0].ID)
+	require.Equal(t, "a", users[0].Name)
+	require.Equal(t, "a@example.com", *users[0].Email)
+	require.Equal(t, "admin", *users[0].CreatedBy, "embedded struct pointer is allocated")
+	require.Equal(t, "kept", users[0].Ignored)
+	require.Equal(t, "kept", users[0].internal)
+
+	require.Equal(t, int64(2), users[1].ID)
+	require.Nil(t, users[1].Email)
+	require.Nil(t, users[1].CreatedBy)
+
+	require.ErrorContains(t, ScanStruct(rows, user{}), "requires a non-nil pointer to a struct")
+
+	require.NoError(t, srv.AddResult("SELECT a.id, b.id FROM a JOIN b", impalatest.Result{
+		Columns: []impalatest.Column{{Name: "id", Type: "BIGINT"}, {Name: "id", Type: "BIGINT"}},
+		Rows:    [][]any{{1, 2}},
+	}))
+	dupRows, err := db.Query("SELECT a.id, b.id FROM a JOIN b")
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, dupRows.Close())
+	}()
+	require.True(t, dupRows.Next())
+	require.ErrorIs(t, ScanStruct(dupRows, &user{}), ErrDuplicateColumn)
+}
+
+func TestStructFields(t *testing.T) {
+	cols := []string{"extra", "ID", "created_by"}
+	fields, err := structFields(cols, reflect.TypeFor[user]())
+	require.NoError(t, err)
+	require.Equal(t, [][]int{nil, {0}, {4, 0}}, fields)
+
+	byName := fieldsByName(reflect.TypeFor[user]())
+	require.Equal(t, reflect.ValueOf(byName).Pointer(), reflect.ValueOf(fieldsByName(reflect.TypeFor[user]())).Pointer(),
+		"the fields are cached by type")
+	_, err = structFields([]string{"name", "email"}, reflect.TypeFor[user]())
+	require.NoError(t, err)
+	require.Equal(t, reflect.ValueOf(byName).Pointer(), reflect.ValueOf(fieldsByName(reflect.TypeFor[user]())).Pointer(),
+		"other columns reuse the cache entry")
+
+	type conflict struct {
+		Name  string
+		Other string `db:"name"`
+	}
+	_, err = structFields([]string{"name"}, reflect.TypeFor[conflict]())
+	require.ErrorContains(t, err, "fields Name and Other both match column name")
+}
+
+func TestDetachAttach(t *testing.T) {
+	srv := impalatest.Start(t)
+	require.NoError(t, srv.AddResult("SELECT id FROM t", impalatest.Result{
+		Columns: []impalatest.Column{{Name: "id", Type: "BIGINT"}},
+		Rows:    [][]any{{1}, {2}},
+	}))
+
+	db := srv.OpenDB(t)
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, conn.Close())
+	}()
+
+	var handle string
+	err = conn.Raw(func(driverConn any) error {
+		rows, err := driverConn.(driver.QueryerContext).QueryContext(ctx, "SELECT id FROM t", nil)
+		if err != nil {
+			return err
+		}
+		handle = rows.(Detacher).Detach()
+		return rows.Close()
+	})
+	require.NoError(t, err)
+	require.NotContains(t, srv.Calls(), "CloseImpalaOperation")
+
+	rows, err := AttachOperation(ctx, conn, handle)
+	require.NoError(t, err)
+	require.Equal(t, []string{"id"}, rows.Columns())
+	var ids []any
+	dest := make([]driver.Value, 1)
+	for err = rows.Next(dest); err == nil; err = rows.Next(dest) {
+		ids = append(ids, dest[0])
+	}
+	require.ErrorIs(t, err, io.EOF)
+	require.NoError(t, rows.Close())
+	require.Equal(t, []any{int64(1), int64(2)}, ids)
+	require.Contains(t, srv.Calls(), "CloseImpalaOperation")
+
+	_, err = AttachOperation(ctx, conn, "not a handle")
+	require.ErrorIs(t, err, ErrInvalidHandle)
+
+	// closing the attached rows closed the query
+	_, err = AttachOperation(ctx, conn, handle)
+	require.ErrorIs(t, err, ErrOperationNotFound)
+	require.NoError(t, conn.PingContext(ctx), "the connection remains usable")
+}
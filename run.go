@@ -0,0 +1,38 @@
+package impala
+
+import (
+	"context"
+	"database/sql/driver"
+
+	"github.com/sclgo/impala-go/internal/isql"
+)
+
+// StatementOutcome is the outcome of StatementRunner.RunContext. Exactly one of Rows and Result is set.
+type StatementOutcome = isql.Outcome
+
+// StatementRunner is implemented by the driver connections of this driver. It is useful for generic SQL runners,
+// which don't know ahead of time if a statement returns rows, so they can't choose between Query and Exec.
+// RunContext executes the statement and returns rows if the statement has a result set.
+// Otherwise, RunContext waits for the statement to complete and returns its result.
+// Note that Impala reports a result set, containing a summary, for most DDL statements.
+//
+// database/sql doesn't expose a similar method so using StatementRunner requires
+// calling the driver directly, using sql.Conn.Raw:
+//
+//	err := conn.Raw(func(driverConn any) error {
+//		outcome, err := driverConn.(impala.StatementRunner).RunContext(ctx, query, nil)
+//		if err != nil {
+//			return err
+//		}
+//		if outcome.Rows != nil {
+//			defer outcome.Rows.Close()
+//			// call outcome.Rows.Next ...
+//		} else {
+//			// call outcome.Result.RowsAffected() ...
+//		}
+//	})
+type StatementRunner interface {
+	RunContext(ctx context.Context, query string, args []driver.NamedValue) (*StatementOutcome, error)
+}
+
+var _ StatementRunner = (*isql.Conn)(nil)
@@ -0,0 +1,85 @@
+package impala
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sclgo/impala-go/impalatest"
+	"github.com/stretchr/testify/require"
+)
+
+// TestScan documents which Go targets each Impala type can be scanned into with database/sql.
+// The README has the same matrix - keep them in sync.
+func TestScan(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	date := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	// expected values by target; missing targets fail to scan
+	tests := []struct {
+		typ      string
+		value    any
+		expected map[reflect.Type]any
+		// params are DSN parameters
+		params []string
+	}{
+		{"BOOLEAN", true, targets(nil, true, nil, "true", []byte("true")), nil},
+		{"TINYINT", 1, targets(int64(1), true, 1.0, "1", []byte("1")), nil},
+		{"SMALLINT", 1, targets(int64(1), true, 1.0, "1", []byte("1")), nil},
+		{"INT", 1, targets(int64(1), true, 1.0, "1", []byte("1")), nil},
+		{"BIGINT", 1, targets(int64(1), true, 1.0, "1", []byte("1")), nil},
+		{"DOUBLE", 1.5, targets(nil, nil, 1.5, "1.5", []byte("1.5")), nil},
+		{"DECIMAL", "12.50", targets(nil, nil, 12.5, "12.50", []byte("12.50")), nil},
+		{"STRING", "abc", targets(nil, nil, nil, "abc", []byte("abc")), nil},
+		{"TIMESTAMP", ts, targets(nil, nil, nil, "2024-01-02T03:04:05Z", []byte("2024-01-02T03:04:05Z"), ts), nil},
+		{"DATE", date, targets(nil, nil, nil, "2024-01-02", []byte("2024-01-02")), nil},
+		{"DATE", date, targets(nil, nil, nil, "2024-01-02T00:00:00Z", []byte("2024-01-02T00:00:00Z"), date), []string{"date-as=time"}},
+	}
+
+	srv := impalatest.Start(t)
+
+	allTargets := []reflect.Type{
+		reflect.TypeFor[int64](), reflect.TypeFor[bool](), reflect.TypeFor[float64](),
+		reflect.TypeFor[string](), reflect.TypeFor[[]byte](), reflect.TypeFor[time.Time](),
+	}
+	for _, tt := range tests {
+		query := "SELECT " + tt.typ
+		require.NoError(t, srv.AddResult(query, impalatest.Result{
+			Columns: []impalatest.Column{{Name: "c", Type: tt.typ}},
+			Rows:    [][]any{{tt.value}},
+		}))
+		db := srv.OpenDB(t, tt.params...)
+		for _, target := range allTargets {
+			t.Run(fmt.Sprintf("%s %v into %v", tt.typ, tt.params, target), func(t *testing.T) {
+				dest := reflect.New(target)
+				err := db.QueryRowContext(context.Background(), query).Scan(dest.Interface())
+				expected, ok := tt.expected[target]
+				if !ok {
+					require.Error(t, err)
+					return
+				}
+				require.NoError(t, err)
+				require.Equal(t, expected, dest.Elem().Interface())
+			})
+		}
+	}
+}
+
+// targets returns the expected values of scanning into int64, bool, float64, string, []byte, and
+// optionally time.Time. nil means that scanning fails.
+func targets(i any, b any, f any, s string, bs []byte, tm ...time.Time) map[reflect.Type]any {
+	res := map[reflect.Type]any{
+		reflect.TypeFor[string](): s,
+		reflect.TypeFor[[]byte](): bs,
+	}
+	for _, v := range []any{i, b, f} {
+		if v != nil {
+			res[reflect.TypeOf(v)] = v
+		}
+	}
+	for _, v := range tm {
+		res[reflect.TypeOf(v)] = v
+	}
+	return res
+}
@@ -0,0 +1,35 @@
+package impala
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sclgo/impala-go/internal/isql"
+)
+
+// QuerySchema returns the columns of the result of query, e.g. to prepare a downstream writer, without fetching
+// any rows. The query is started and closed right after the server reports the result columns, which stops it
+// from computing more of the result. query must be a query - other statements, e.g. INSERT, may take effect.
+// *sql.Conn implements ConnRawAccess.
+func QuerySchema(ctx context.Context, conn ConnRawAccess, query string) ([]ColumnDesc, error) {
+	var res []ColumnDesc
+	err := conn.Raw(func(driverConn any) error {
+		impalaConn, ok := driverConn.(*isql.Conn)
+		if !ok {
+			return errors.New("query schema can be retrieved only with Impala drivers")
+		}
+		cols, err := impalaConn.QuerySchema(ctx, query)
+		for _, col := range cols {
+			res = append(res, *col)
+		}
+		return err
+	})
+	return res, err
+}
+
+// QuerySchemaLimitZero is like QuerySchema but runs the query as a subquery with LIMIT 0, so the server doesn't
+// compute any rows. Impala rejects some queries as subqueries, e.g. if result columns have the same name.
+func QuerySchemaLimitZero(ctx context.Context, conn ConnRawAccess, query string) ([]ColumnDesc, error) {
+	// the line breaks keep a trailing line comment in query from commenting out the rest
+	return QuerySchema(ctx, conn, "SELECT * FROM (\n"+query+"\n) impala_go_schema LIMIT 0")
+}
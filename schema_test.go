@@ -0,0 +1,44 @@
+package impala
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sclgo/impala-go/impalatest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuerySchema(t *testing.T) {
+	srv := impalatest.Start(t)
+	result := impalatest.Result{
+		Columns: []impalatest.Column{{Name: "id", Type: "BIGINT"}, {Name: "name", Type: "STRING"}},
+		Rows:    [][]any{{1, "a"}},
+	}
+	require.NoError(t, srv.AddResult("SELECT id, name FROM t", result))
+	require.NoError(t, srv.AddResult("SELECT * FROM (\nSELECT id, name FROM t\n) impala_go_schema LIMIT 0", result))
+
+	db := srv.OpenDB(t)
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, conn.Close())
+	}()
+
+	cols, err := QuerySchema(ctx, conn, "SELECT id, name FROM t")
+	require.NoError(t, err)
+	require.Len(t, cols, 2)
+	require.Equal(t, "id", cols[0].Name)
+	require.Equal(t, "BIGINT", cols[0].DatabaseTypeName)
+	require.Equal(t, "name", cols[1].Name)
+	require.NotContains(t, srv.Calls(), "FetchResults")
+	require.Contains(t, srv.Calls(), "CloseImpalaOperation")
+
+	cols, err = QuerySchemaLimitZero(ctx, conn, "SELECT id, name FROM t")
+	require.NoError(t, err)
+	require.Len(t, cols, 2)
+
+	// the connection is idle again
+	_, err = conn.ExecContext(ctx, "SET MT_DOP=1")
+	require.NoError(t, err)
+}
@@ -0,0 +1,36 @@
+package impala
+
+import (
+	"errors"
+	"net"
+
+	"github.com/sclgo/impala-go/internal/isql"
+)
+
+// ServerAddr returns the address of the Impala coordinator that serves conn, e.g. to correlate client errors with
+// the logs of that coordinator when the host name resolves to several coordinators behind DNS or a load balancer.
+// With the binary transport, it is the remote address of the network connection e.g. 10.0.0.5:21050 -
+// the resolved IP, not the host name in Options. With TransportUnix, it is the socket path.
+// With TransportHTTP, the HTTP client may connect to a different address for each request, so it is
+// Host and Port from Options. *sql.Conn implements ConnRawAccess.
+func ServerAddr(conn ConnRawAccess) (string, error) {
+	var res string
+	err := conn.Raw(func(driverConn any) error {
+		impalaConn, ok := driverConn.(*isql.Conn)
+		if !ok {
+			return errors.New("server address can be reported only for Impala drivers")
+		}
+		res = impalaConn.ServerAddr()
+		return nil
+	})
+	return res, err
+}
+
+// remoteAddr returns the remote address of conn, or dialed if conn doesn't report it, e.g. a custom DialContext
+// may return a connection through a tunnel without a meaningful remote address
+func remoteAddr(conn net.Conn, dialed string) string {
+	if conn == nil || conn.RemoteAddr() == nil || conn.RemoteAddr().String() == "" {
+		return dialed
+	}
+	return conn.RemoteAddr().String()
+}
@@ -0,0 +1,43 @@
+package impala
+
+import (
+	"context"
+	"database/sql"
+	"net"
+	"testing"
+
+	"github.com/sclgo/impala-go/impalatest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerAddr(t *testing.T) {
+	srv := impalatest.Start(t)
+	_, port, err := net.SplitHostPort(srv.Addr())
+	require.NoError(t, err)
+
+	t.Run("resolved", func(t *testing.T) {
+		db, err := sql.Open("impala", "impala://localhost:"+port)
+		require.NoError(t, err)
+		defer func() {
+			require.NoError(t, db.Close())
+		}()
+		conn, err := db.Conn(context.Background())
+		require.NoError(t, err)
+		defer func() {
+			require.NoError(t, conn.Close())
+		}()
+		addr, err := ServerAddr(conn)
+		require.NoError(t, err)
+		require.Equal(t, srv.Addr(), addr, "the IP address instead of localhost")
+	})
+
+	t.Run("fallback to dialed address", func(t *testing.T) {
+		require.Equal(t, "impala.internal:21050", remoteAddr(nil, "impala.internal:21050"))
+		client, server := net.Pipe()
+		defer func() {
+			_ = client.Close()
+			_ = server.Close()
+		}()
+		require.Equal(t, "pipe", remoteAddr(client, "impala.internal:21050"))
+	})
+}